@@ -9,6 +9,8 @@ type Config struct {
 	Redis      RedisConfig      `mapstructure:"redis"`
 	JWT        JWTConfig        `mapstructure:"jwt"`
 	Pagenation PagenationConfig `mapstructure:"pagenation"`
+	Batch      BatchConfig      `mapstructure:"batch"`
+	Query      QueryConfig      `mapstructure:"query"`
 }
 
 type AppConfig struct {
@@ -35,17 +37,19 @@ type LogConfig struct {
 }
 
 type DatabaseConfig struct {
-	Driver          string `mapstructure:"driver"`
-	Host            string `mapstructure:"host"`
-	Port            int    `mapstructure:"port"`
-	Username        string `mapstructure:"username"`
-	Password        string `mapstructure:"password"`
-	Database        string `mapstructure:"database"`
-	Charset         string `mapstructure:"charset"`
-	MaxIdleConns    int    `mapstructure:"max_idle_conns"`
-	MaxOpenConns    int    `mapstructure:"max_open_conns"`
-	ConnMaxLifetime int    `mapstructure:"conn_max_lifetime"`
-	ConnMaxIdleTime int    `mapstructure:"conn_max_idle_time"`
+	Name            string           `mapstructure:"name"` // 数据源名称，未配置时属于默认数据源 default
+	Driver          string           `mapstructure:"driver"`
+	Host            string           `mapstructure:"host"`
+	Port            int              `mapstructure:"port"`
+	Username        string           `mapstructure:"username"`
+	Password        string           `mapstructure:"password"`
+	Database        string           `mapstructure:"database"`
+	Charset         string           `mapstructure:"charset"`
+	MaxIdleConns    int              `mapstructure:"max_idle_conns"`
+	MaxOpenConns    int              `mapstructure:"max_open_conns"`
+	ConnMaxLifetime int              `mapstructure:"conn_max_lifetime"`
+	ConnMaxIdleTime int              `mapstructure:"conn_max_idle_time"`
+	Replicas        []DatabaseConfig `mapstructure:"replicas"` // 只读副本，未配置的连接字段沿用主库配置，仅支持 gorm 连接
 }
 
 type RedisConfig struct {
@@ -66,3 +70,11 @@ type PagenationConfig struct {
 	DefaultPageSize int `mapstructure:"default_page_size"`
 	MaxPageSize     int `mapstructure:"max_page_size"`
 }
+
+type BatchConfig struct {
+	AsyncThreshold int `mapstructure:"async_threshold"` // 批量记录数超过该值时异步执行，0 表示不自动异步
+}
+
+type QueryConfig struct {
+	MaxExpandDepth int `mapstructure:"max_expand_depth"` // expand 关联展开的最大层数，0 使用默认值 2
+}
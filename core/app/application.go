@@ -10,6 +10,7 @@ import (
 	"github.com/kruily/gofastcrud/core/di"
 	"github.com/kruily/gofastcrud/core/server"
 	"github.com/kruily/gofastcrud/logger"
+	"github.com/kruily/gofastcrud/scheduler"
 	"github.com/kruily/gofastcrud/utils"
 )
 
@@ -38,6 +39,12 @@ func NewDefaultGoFastCrudApp(opts ...Option) *GoFastCrudApp {
 	if _, err := container.ResolveSingleton(module.ResponseService); err != nil {
 		container.BindSingletonWithName(module.ResponseService, &utils.DefaultResponseHandler{})
 	}
+	if _, err := container.ResolveSingleton(module.BatchJobService); err != nil {
+		container.BindSingletonWithName(module.BatchJobService, crud.NewMemoryBatchJobStore())
+	}
+	if err := registerOutboxRelay(container); err != nil {
+		log.Fatalf("Outbox relay error: %v", err)
+	}
 
 	return &GoFastCrudApp{
 		server:    server,
@@ -46,10 +53,34 @@ func NewDefaultGoFastCrudApp(opts ...Option) *GoFastCrudApp {
 	}
 }
 
+// registerOutboxRelay 同时设置了发件箱与调度器时，将发件箱投递任务注册到调度器
+func registerOutboxRelay(container *di.Container) error {
+	outbox, err := container.ResolveSingleton(module.OutboxService)
+	if err != nil {
+		return nil
+	}
+	s, err := container.ResolveSingleton(module.ScheduleService)
+	if err != nil {
+		return nil
+	}
+	relay, ok := outbox.(*crud.Outbox)
+	if !ok {
+		return nil
+	}
+	schedule, ok := s.(*scheduler.Scheduler)
+	if !ok {
+		return nil
+	}
+	return schedule.AddJob(relay.Spec(), relay)
+}
+
 // RegisterControllers 注册控制器
 func (a *GoFastCrudApp) RegisterControllers(fn func(*crud.ControllerFactory, *server.Server)) *GoFastCrudApp {
 	fn(a.factory, a.server)
-	a.factory.Migrate() // 自动迁移数据库
+	// 自动迁移数据库
+	if err := a.factory.Migrate(); err != nil {
+		log.Fatalf("Migrate error: %v", err)
+	}
 	return a
 }
 
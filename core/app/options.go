@@ -1,9 +1,11 @@
 package app
 
 import (
+	"github.com/kruily/gofastcrud/core/crud"
 	"github.com/kruily/gofastcrud/core/crud/module"
 	"github.com/kruily/gofastcrud/core/database"
 	"github.com/kruily/gofastcrud/core/di"
+	"github.com/kruily/gofastcrud/scheduler"
 )
 
 // Options 应用选项
@@ -43,3 +45,39 @@ func WithDatabase(db *database.Database) Option {
 		di.SINGLE().BindSingletonWithName(module.DatabaseService, db)
 	}
 }
+
+// WithEventBus 设置事件总线，仓储写操作发布实体领域事件，未设置时不发布
+func WithEventBus(bus module.IEventBus) Option {
+	return func(o *AppOption) {
+		di.SINGLE().BindSingletonWithName(module.EventBusService, bus)
+	}
+}
+
+// WithOutbox 设置事务发件箱，gorm 实体的异步领域事件与数据变更在同一事务中写入发件箱，由发件箱任务投递
+// 仓储需在事务中写入发件箱表，因此只接受 crud.NewOutbox 创建的发件箱；同时设置调度器时自动注册投递任务
+func WithOutbox(outbox *crud.Outbox) Option {
+	return func(o *AppOption) {
+		di.SINGLE().BindSingletonWithName(module.OutboxService, outbox)
+	}
+}
+
+// WithAuditWriter 设置审计记录存储，未设置时不记录审计日志
+func WithAuditWriter(writer module.IAuditWriter) Option {
+	return func(o *AppOption) {
+		di.SINGLE().BindSingletonWithName(module.AuditService, writer)
+	}
+}
+
+// WithBatchJobStore 设置异步批量任务存储，默认使用内存存储
+func WithBatchJobStore(store module.IBatchJobStore) Option {
+	return func(o *AppOption) {
+		di.SINGLE().BindSingletonWithName(module.BatchJobService, store)
+	}
+}
+
+// WithScheduler 设置定时任务调度器，设置了发件箱时按 crud.WithOutboxSpec 注册投递任务，调度器由调用方启动与停止
+func WithScheduler(s *scheduler.Scheduler) Option {
+	return func(o *AppOption) {
+		di.SINGLE().BindSingletonWithName(module.ScheduleService, s)
+	}
+}
@@ -0,0 +1,264 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/core/crud/types"
+	"github.com/kruily/gofastcrud/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AggregateResult 聚合接口响应
+type AggregateResult struct {
+	Func    string                   `json:"func" description:"聚合函数"`
+	Field   string                   `json:"field" description:"聚合字段"`
+	GroupBy []string                 `json:"group_by,omitempty" description:"分组字段"`
+	Value   *float64                 `json:"value,omitempty" description:"聚合值（未分组时）"`
+	Groups  []map[string]interface{} `json:"groups,omitempty" description:"分组聚合结果，每组包含分组字段与 value"`
+}
+
+// resolveAggregateFields 校验并解析聚合字段与分组字段
+func resolveAggregateFields(entityType reflect.Type, opts *options.AggregateOptions) (entityField, []entityField, error) {
+	if !options.IsAggregateFunc(opts.Func) {
+		return entityField{}, nil, errors.New(errors.ErrInvalidParam, "invalid aggregate func: "+opts.Func)
+	}
+	field, ok := lookupEntityField(entityType, opts.Field)
+	if !ok {
+		return entityField{}, nil, errors.New(errors.ErrInvalidParam, "invalid aggregate field: "+opts.Field)
+	}
+	groups := make([]entityField, 0, len(opts.GroupBy))
+	for _, name := range opts.GroupBy {
+		group, ok := lookupEntityField(entityType, name)
+		if !ok {
+			return entityField{}, nil, errors.New(errors.ErrInvalidParam, "invalid group_by field: "+name)
+		}
+		groups = append(groups, group)
+	}
+	if opts.Having != nil {
+		if _, ok := options.HavingOperators[opts.Having.Op]; !ok {
+			return entityField{}, nil, errors.New(errors.ErrInvalidParam, "invalid having operator: "+opts.Having.Op)
+		}
+	}
+	return field, groups, nil
+}
+
+// toFloat64 将数据库返回的数值转换为 float64
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case nil:
+		return 0, true
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case []byte:
+		f, err := strconv.ParseFloat(string(n), 64)
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	case primitive.Decimal128:
+		f, err := strconv.ParseFloat(n.String(), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// normalizeAggregateRow 统一分组结果中的值类型
+func normalizeAggregateRow(row map[string]interface{}) map[string]interface{} {
+	for key, value := range row {
+		// 部分驱动以指针形式返回扫描值
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				value = nil
+			} else {
+				value = v.Elem().Interface()
+			}
+		}
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		row[key] = value
+	}
+	if value, ok := toFloat64(row["value"]); ok {
+		row["value"] = value
+	}
+	return row
+}
+
+// Aggregate 聚合查询
+// 聚合字段与分组字段仅限带 filter tag 的字段，过滤条件、数据权限与列表接口一致
+func (c *BlankController[T]) Aggregate(ctx *gin.Context) (interface{}, error) {
+	fn := strings.ToLower(ctx.Query("func"))
+	field := ctx.Query("field")
+	if fn == "" || field == "" {
+		return nil, errors.New(errors.ErrInvalidParam, "func and field are required")
+	}
+
+	allowed := filterableFields(c.entity)
+	if !allowed[field] {
+		return nil, errors.New(errors.ErrInvalidParam, "field is not aggregatable: "+field)
+	}
+	var groupBy []string
+	if value := ctx.Query("group_by"); value != "" {
+		groupBy = strings.Split(value, ",")
+		for _, group := range groupBy {
+			if !allowed[group] {
+				return nil, errors.New(errors.ErrInvalidParam, "field is not groupable: "+group)
+			}
+		}
+	}
+	var having *options.Having
+	if value := ctx.Query("having"); value != "" {
+		h, err := options.ParseHaving(value)
+		if err != nil {
+			return nil, errors.Wrap(err, errors.ErrInvalidParam, "invalid having")
+		}
+		having = h
+	}
+
+	queryOpts, err := c.BuildQueryOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	listCtx, err := c.scoped(ctx, DataActList)
+	if err != nil {
+		return nil, err
+	}
+	aggOpts := options.NewAggregateOptions(fn, field,
+		options.WithGroupBy(groupBy...),
+		options.WithHaving(having),
+		options.WithQuery(queryOpts),
+	)
+	rows, err := c.Repository.GroupAggregate(listCtx, aggOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AggregateResult{Func: fn, Field: field, GroupBy: groupBy}
+	if len(groupBy) > 0 {
+		result.Groups = rows
+	} else if len(rows) > 0 {
+		value, _ := rows[0]["value"].(float64)
+		result.Value = &value
+	} else if having == nil {
+		value := 0.0
+		result.Value = &value
+	}
+	return c.Responser.Success(result), nil
+}
+
+// aggregateParams 聚合接口查询参数
+func (c *BlankController[T]) aggregateParams() []types.Parameter {
+	params := []types.Parameter{
+		{
+			Name:        "func",
+			In:          "query",
+			Description: "Aggregate function (sum, count, max, min, avg)",
+			Required:    true,
+			Schema:      types.Schema{Type: "string"},
+		},
+		{
+			Name:        "field",
+			In:          "query",
+			Description: "Field to aggregate, must be a filterable field",
+			Required:    true,
+			Schema:      types.Schema{Type: "string"},
+		},
+		{
+			Name:        "group_by",
+			In:          "query",
+			Description: "Fields to group by (comma-separated), must be filterable fields",
+			Schema:      types.Schema{Type: "string"},
+		},
+		{
+			Name:        "having",
+			In:          "query",
+			Description: "Filter on the aggregated value in the form op:value (gt, gte, lt, lte, eq, neq), example: having=gt:10",
+			Schema:      types.Schema{Type: "string"},
+		},
+		{
+			Name:        "search",
+			In:          "query",
+			Description: "Search keyword",
+			Schema:      types.Schema{Type: "string"},
+		},
+		{
+			Name:        "search_fields",
+			In:          "query",
+			Description: "Fields to search in (comma-separated)",
+			Schema:      types.Schema{Type: "string"},
+		},
+	}
+	params = append(params, searchModeParam(), filterExprParam())
+	return append(params, ModeParams(c)...)
+}
+
+// filterableFields 获取带 filter tag 的字段（json 名称）
+func filterableFields(entity any) map[string]bool {
+	fields := make(map[string]bool)
+	for _, field := range queryFields(entity) {
+		if field.FilterTag != "" {
+			fields[field.Field] = true
+		}
+	}
+	return fields
+}
+
+// AggregateRoute 添加只读聚合路由（GET），build 根据请求构建聚合管道，仅支持 MongoDB 实体
+// response 为输出类型的零值，如 []SalesReport{} 或 SalesReport{}：结果按其 bson 标签解码，OpenAPI 响应定义由其推导；
+// 为 nil 时按 []map[string]interface{} 返回，非切片类型只返回第一条结果。聚合遵循列表接口的数据权限、租户与软删除条件，返回的路由可继续设置参数、缓存等
+func (c *BlankController[T]) AggregateRoute(path string, response interface{}, build func(ctx *gin.Context) (*Pipeline, error)) *types.APIRoute {
+	if response == nil {
+		response = []map[string]interface{}{}
+	}
+	responseType := reflect.TypeOf(response)
+	handler := func(ctx *gin.Context) (interface{}, error) {
+		pipeline, err := build(ctx)
+		if err != nil {
+			return nil, err
+		}
+		listCtx, err := c.scoped(ctx, DataActList)
+		if err != nil {
+			return nil, err
+		}
+		sliceType := responseType
+		if sliceType.Kind() != reflect.Slice {
+			sliceType = reflect.SliceOf(responseType)
+		}
+		rows := reflect.New(sliceType)
+		if err := c.Repository.Aggregate(listCtx, pipeline, rows.Interface()); err != nil {
+			return nil, err
+		}
+		if responseType.Kind() == reflect.Slice {
+			if rows.Elem().IsNil() {
+				rows.Elem().Set(reflect.MakeSlice(sliceType, 0, 0))
+			}
+			return c.Responser.Success(rows.Elem().Interface()), nil
+		}
+		if rows.Elem().Len() == 0 {
+			return c.Responser.Success(reflect.Zero(responseType).Interface()), nil
+		}
+		return c.Responser.Success(rows.Elem().Index(0).Interface()), nil
+	}
+	entityName := strings.ToLower(c.entityName[:1]) + c.entityName[1:]
+	route := types.Get(path, handler).
+		WithTags([]string{c.entityName}).
+		WithSummary(fmt.Sprintf("Aggregate %s %s", entityName, strings.Trim(path, "/"))).
+		WithResponse(response)
+	c.AddRoute(route)
+	return route
+}
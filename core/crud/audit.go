@@ -0,0 +1,170 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kruily/gofastcrud/core/crud/module"
+	"github.com/kruily/gofastcrud/core/crud/types"
+	"github.com/kruily/gofastcrud/core/di"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IAuditable 启用审计的实体
+type IAuditable interface {
+	// AuditEnabled 是否记录审计日志
+	AuditEnabled() bool
+}
+
+// Audited 审计标记，嵌入实体即可启用审计：仓储的写操作记录操作人、变更字段与请求ID，控制器提供 GET /:id/history 接口
+// 审计记录写入通过 app.WithAuditWriter 注册的存储，未注册时不记录；MongoDB 实体嵌入时需声明 bson:",inline"
+type Audited struct{}
+
+// AuditEnabled 启用审计
+func (Audited) AuditEnabled() bool {
+	return true
+}
+
+// auditWriter 获取审计记录存储，未注册 AuditService 时返回 nil
+func auditWriter() module.IAuditWriter {
+	if writer, err := di.SINGLE().ResolveSingleton(module.AuditService); err == nil {
+		if writer, ok := writer.(module.IAuditWriter); ok {
+			return writer
+		}
+	}
+	return nil
+}
+
+// auditEnabled 实体是否启用审计
+func auditEnabled(entity any) bool {
+	auditable, ok := entity.(IAuditable)
+	return ok && auditable.AuditEnabled()
+}
+
+// auditMetaKey 上下文中审计信息的键
+type auditMetaKey struct{}
+
+// auditMeta 审计信息，用于脱离请求的后台任务
+type auditMeta struct {
+	actor     string
+	requestID string
+}
+
+// WithAuditActor 返回携带操作人的上下文，用于后台任务等没有认证信息的场景
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditMetaKey{}, auditMeta{actor: actor, requestID: auditRequestID(ctx)})
+}
+
+// withAuditMeta 将请求中的操作人与请求ID复制到后台任务的上下文
+func withAuditMeta(ctx context.Context, from context.Context) context.Context {
+	return context.WithValue(ctx, auditMetaKey{}, auditMeta{actor: auditActor(from), requestID: auditRequestID(from)})
+}
+
+// auditActor 当前操作人，来自认证中间件写入的 user_id
+func auditActor(ctx context.Context) string {
+	if meta, ok := ctx.Value(auditMetaKey{}).(auditMeta); ok {
+		return meta.actor
+	}
+	if userID := ctx.Value("user_id"); userID != nil {
+		return fmt.Sprint(userID)
+	}
+	return ""
+}
+
+// auditRequestID 当前请求ID，来自 middleware.RequestID 写入的 request_id
+func auditRequestID(ctx context.Context) string {
+	if meta, ok := ctx.Value(auditMetaKey{}).(auditMeta); ok {
+		return meta.requestID
+	}
+	requestID, _ := ctx.Value("request_id").(string)
+	return requestID
+}
+
+// auditEntityID 将实体ID格式化为审计记录中的字符串，与路由中的ID参数一致
+func auditEntityID(id any) string {
+	switch v := id.(type) {
+	case primitive.ObjectID:
+		return v.Hex()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(id)
+}
+
+// auditSnapshot 以 json 字段名记录实体的字段值
+func auditSnapshot(entity any) map[string]interface{} {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil
+	}
+	snapshot := make(map[string]interface{})
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil
+	}
+	return snapshot
+}
+
+// auditChanges 对比前后快照，返回值不同的字段
+func auditChanges(before, after map[string]interface{}) map[string]types.AuditChange {
+	changes := make(map[string]types.AuditChange)
+	for field, value := range before {
+		if next, ok := after[field]; !ok || !reflect.DeepEqual(value, next) {
+			changes[field] = types.AuditChange{Before: value, After: after[field]}
+		}
+	}
+	for field, value := range after {
+		if _, ok := before[field]; !ok && value != nil {
+			changes[field] = types.AuditChange{After: value}
+		}
+	}
+	return changes
+}
+
+// auditing 是否需要记录审计日志
+func (r *Repository[T]) auditing() bool {
+	return r.audited && auditWriter() != nil
+}
+
+// snapshot 查询记录当前的字段值，记录不存在时返回 false
+func (r *Repository[T]) snapshot(ctx context.Context, id any) (map[string]interface{}, bool) {
+	entity, err := r.crudRepo.FindById(ctx, id)
+	if err != nil {
+		return nil, false
+	}
+	return auditSnapshot(entity), true
+}
+
+// record 记录一条审计日志，事务中的记录在提交后写入
+func (r *Repository[T]) record(ctx context.Context, action string, id any, before, after map[string]interface{}) {
+	entry := &types.AuditLog{
+		ID:        uuid.NewString(),
+		Entity:    r.entityType.Name(),
+		EntityID:  auditEntityID(id),
+		Action:    action,
+		Actor:     auditActor(ctx),
+		RequestID: auditRequestID(ctx),
+		Changes:   auditChanges(before, after),
+		CreatedAt: time.Now(),
+	}
+	if r.pending != nil {
+		r.pending.audits = append(r.pending.audits, entry)
+		return
+	}
+	r.flush(ctx, entry)
+}
+
+// flush 写入审计日志，写入失败不影响已完成的写操作，仅记录日志
+func (r *Repository[T]) flush(ctx context.Context, entries ...*types.AuditLog) {
+	writer := auditWriter()
+	if writer == nil || len(entries) == 0 {
+		return
+	}
+	if err := writer.Write(context.WithoutCancel(ctx), entries...); err != nil {
+		log.Printf("Failed to write audit logs for %s: %v", r.entityType.Name(), err)
+	}
+}
@@ -0,0 +1,83 @@
+package crud
+
+import (
+	"context"
+
+	"github.com/kruily/gofastcrud/core/crud/module"
+	"github.com/kruily/gofastcrud/core/crud/types"
+	"github.com/qiniu/qmgo"
+	qmgooptions "github.com/qiniu/qmgo/options"
+	"go.mongodb.org/mongo-driver/bson"
+	"gorm.io/gorm"
+)
+
+// gormAuditWriter 数据库审计记录存储
+type gormAuditWriter struct {
+	db *gorm.DB
+}
+
+// NewGormAuditWriter 创建数据库审计记录存储，并自动迁移 audit_logs 表
+func NewGormAuditWriter(db *gorm.DB) (module.IAuditWriter, error) {
+	if err := db.AutoMigrate(&types.AuditLog{}); err != nil {
+		return nil, err
+	}
+	return &gormAuditWriter{db: db}, nil
+}
+
+// Write 写入审计记录
+func (w *gormAuditWriter) Write(ctx context.Context, logs ...*types.AuditLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	return w.db.WithContext(ctx).Create(logs).Error
+}
+
+// History 分页查询实体的审计记录
+func (w *gormAuditWriter) History(ctx context.Context, entity string, entityID string, page int, pageSize int) ([]*types.AuditLog, int64, error) {
+	var total int64
+	db := w.db.WithContext(ctx).Model(&types.AuditLog{}).Where("entity = ? AND entity_id = ?", entity, entityID)
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	logs := make([]*types.AuditLog, 0)
+	err := db.Order("created_at desc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs).Error
+	return logs, total, err
+}
+
+// mongoAuditWriter MongoDB 审计记录存储
+type mongoAuditWriter struct {
+	collection *qmgo.Collection
+}
+
+// NewMongoAuditWriter 创建 MongoDB 审计记录存储，并为 audit_logs 集合创建索引
+func NewMongoAuditWriter(db *qmgo.Database) (module.IAuditWriter, error) {
+	collection := db.Collection(types.AuditLog{}.TableName())
+	err := collection.CreateIndexes(context.Background(), []qmgooptions.IndexModel{
+		{Key: []string{"entity", "entity_id", "-created_at"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mongoAuditWriter{collection: collection}, nil
+}
+
+// Write 写入审计记录
+func (w *mongoAuditWriter) Write(ctx context.Context, logs ...*types.AuditLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	_, err := w.collection.InsertMany(ctx, logs)
+	return err
+}
+
+// History 分页查询实体的审计记录
+func (w *mongoAuditWriter) History(ctx context.Context, entity string, entityID string, page int, pageSize int) ([]*types.AuditLog, int64, error) {
+	filter := bson.M{"entity": entity, "entity_id": entityID}
+	total, err := w.collection.Find(ctx, filter).Count()
+	if err != nil {
+		return nil, 0, err
+	}
+	logs := make([]*types.AuditLog, 0)
+	err = w.collection.Find(ctx, filter).Sort("-created_at").Skip(int64((page - 1) * pageSize)).Limit(int64(pageSize)).All(&logs)
+	return logs, total, err
+}
@@ -0,0 +1,95 @@
+package crud
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kruily/gofastcrud/core/crud/module"
+	"github.com/kruily/gofastcrud/core/crud/types"
+	"github.com/kruily/gofastcrud/core/di"
+	"github.com/stretchr/testify/require"
+)
+
+// testAuditedPage 审计测试用页面
+type testAuditedPage struct {
+	*BaseEntity
+	Audited
+	Title  string `json:"title"`
+	Status string `json:"status"`
+}
+
+func (*testAuditedPage) TableName() string {
+	return "test_audited_pages"
+}
+
+func (a *testAuditedPage) Init() {
+	if a.BaseEntity == nil {
+		a.BaseEntity = &BaseEntity{}
+	}
+}
+
+func TestAudit(t *testing.T) {
+	db, pages := setupTestRepository(t, &testAuditedPage{})
+	writer, err := NewGormAuditWriter(db)
+	require.NoError(t, err)
+	require.NoError(t, di.SINGLE().BindSingletonWithName(module.AuditService, writer))
+	t.Cleanup(func() { di.SINGLE().Unbind(module.AuditService) })
+
+	repo := &Repository[*testAuditedPage]{
+		crudRepo:   pages,
+		entityType: reflect.TypeOf(testAuditedPage{}),
+		audited:    auditEnabled(&testAuditedPage{}),
+	}
+	require.True(t, repo.auditing())
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest("POST", "/pages", nil)
+	ctx.Set("user_id", float64(7))
+	ctx.Set("request_id", "req-1")
+
+	page := &testAuditedPage{BaseEntity: &BaseEntity{}, Title: "draft", Status: "new"}
+	require.NoError(t, repo.Create(ctx, page))
+	require.NoError(t, repo.Update(ctx, page, map[string]interface{}{"title": "final"}))
+
+	// 回滚的事务不记录审计日志
+	rollback := errors.New("rollback")
+	err = repo.Transaction(ctx, func(tx IRepository[*testAuditedPage]) error {
+		if err := tx.Update(ctx, page, map[string]interface{}{"status": "archived"}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	require.ErrorIs(t, err, rollback)
+	require.NoError(t, repo.Transaction(ctx, func(tx IRepository[*testAuditedPage]) error {
+		return tx.BatchUpdate(ctx, []*testAuditedPage{{BaseEntity: &BaseEntity{ID: page.ID}, Title: "final", Status: "published"}})
+	}))
+	require.NoError(t, repo.DeleteById(ctx, page.ID))
+	// 不存在的记录不记录删除
+	require.NoError(t, repo.DeleteById(ctx, uint64(99)))
+
+	logs, total, err := writer.History(ctx, "testAuditedPage", "1", 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), total)
+	actions := make([]string, 0, len(logs))
+	for _, log := range logs {
+		require.Equal(t, "7", log.Actor)
+		require.Equal(t, "req-1", log.RequestID)
+		actions = append(actions, log.Action)
+	}
+	require.Equal(t, []string{types.AuditActionDelete, types.AuditActionUpdate, types.AuditActionUpdate, types.AuditActionCreate}, actions)
+
+	require.Equal(t, types.AuditChange{Before: "new", After: "published"}, logs[1].Changes["status"])
+	require.NotContains(t, logs[1].Changes, "title")
+	require.Equal(t, types.AuditChange{Before: "draft", After: "final"}, logs[2].Changes["title"])
+	require.NotContains(t, logs[2].Changes, "status")
+	require.Equal(t, types.AuditChange{After: "draft"}, logs[3].Changes["title"])
+	require.Equal(t, types.AuditChange{Before: "published"}, logs[0].Changes["status"])
+
+	// 后台任务保留请求中的操作人
+	jobCtx := withAuditMeta(ctx.Request.Context(), ctx)
+	require.Equal(t, "7", auditActor(jobCtx))
+	require.Equal(t, "req-1", auditRequestID(jobCtx))
+}
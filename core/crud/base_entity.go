@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/kruily/gofastcrud/errors"
+	"gorm.io/gorm"
 )
 
 // ICrudEntity CRUD 实体接口
@@ -34,10 +35,11 @@ type ICrudEntity interface {
 
 // BaseEntity 基础实体
 type BaseEntity struct {
-	ID        uint64    `gorm:"primarykey" json:"id" example:"1" description:"唯一标识符"`
-	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at" example:"2024-03-20T10:00:00Z" description:"创建时间"`
-	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at" example:"2024-03-20T10:00:00Z" description:"更新时间"`
-	DeletedAt time.Time `gorm:"column:deleted_at;index" json:"-" example:"2024-03-20T10:00:00Z" description:"删除时间"` // 软删除
+	ID        uint64         `gorm:"primarykey" json:"id" example:"1" description:"唯一标识符"`
+	CreatedAt time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at" example:"2024-03-20T10:00:00Z" description:"创建时间"`
+	UpdatedAt time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at" example:"2024-03-20T10:00:00Z" description:"更新时间"`
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index" json:"-" example:"2024-03-20T10:00:00Z" description:"删除时间"` // 软删除
+	DeletedBy string         `gorm:"column:deleted_by;size:64" json:"-" description:"删除人"`
 }
 
 // GetID 获取ID
@@ -67,7 +69,7 @@ func (e *BaseEntity) GetUpdatedAt() time.Time {
 
 // GetDeletedAt 获取删除时间
 func (e *BaseEntity) GetDeletedAt() time.Time {
-	return e.DeletedAt
+	return e.DeletedAt.Time
 }
 
 // SetCreatedAt 设置创建时间
@@ -82,7 +84,17 @@ func (e *BaseEntity) SetUpdatedAt(t time.Time) {
 
 // SetDeletedAt 设置删除时间
 func (e *BaseEntity) SetDeletedAt(t time.Time) {
-	e.DeletedAt = t
+	e.DeletedAt = gorm.DeletedAt{Time: t, Valid: !t.IsZero()}
+}
+
+// GetDeletedBy 获取删除人
+func (e *BaseEntity) GetDeletedBy() string {
+	return e.DeletedBy
+}
+
+// SetDeletedBy 设置删除人
+func (e *BaseEntity) SetDeletedBy(deletedBy string) {
+	e.DeletedBy = deletedBy
 }
 
 func (b *BaseEntity) DBType() string {
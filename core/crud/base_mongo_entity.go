@@ -13,7 +13,8 @@ type BaseMongoEntity struct {
 	Id        primitive.ObjectID `bson:"_id,omitempty" json:"id" `
 	CreatedAt time.Time          `bson:"created_at" json:"created_at" example:"2024-03-20T10:00:00Z" description:"创建时间"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at" example:"2024-03-20T10:00:00Z" description:"更新时间"`
-	DeletedAt time.Time          `bson:"deleted_at" json:"-" example:"2024-03-20T10:00:00Z" description:"删除时间"`
+	DeletedAt *time.Time         `bson:"deleted_at,omitempty" json:"-" example:"2024-03-20T10:00:00Z" description:"删除时间"`
+	DeletedBy string             `bson:"deleted_by,omitempty" json:"-" description:"删除人"`
 }
 
 func (b *BaseMongoEntity) GetID() any {
@@ -45,11 +46,26 @@ func (b *BaseMongoEntity) SetUpdatedAt(updatedAt time.Time) {
 }
 
 func (b *BaseMongoEntity) GetDeletedAt() time.Time {
-	return b.DeletedAt
+	if b.DeletedAt == nil {
+		return time.Time{}
+	}
+	return *b.DeletedAt
 }
 
 func (b *BaseMongoEntity) SetDeletedAt(deletedAt time.Time) {
-	b.DeletedAt = deletedAt
+	if deletedAt.IsZero() {
+		b.DeletedAt = nil
+		return
+	}
+	b.DeletedAt = &deletedAt
+}
+
+func (b *BaseMongoEntity) GetDeletedBy() string {
+	return b.DeletedBy
+}
+
+func (b *BaseMongoEntity) SetDeletedBy(deletedBy string) {
+	b.DeletedBy = deletedBy
 }
 
 func (b *BaseMongoEntity) DBType() string {
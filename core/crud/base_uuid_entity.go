@@ -9,10 +9,11 @@ import (
 )
 
 type BaseUUIDEntity struct {
-	ID        uuid.UUID `gorm:"type:string;primarykey;" json:"id" example:"1" description:"唯一标识符" filter:"eq,neq,in,nin"`
-	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at" example:"2024-03-20T10:00:00Z" description:"创建时间" filter:"gt,gte,lt,lte,eq,neq,in,nin"`
-	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at" example:"2024-03-20T10:00:00Z" description:"更新时间" filter:"gt,gte,lt,lte,eq,neq,in,nin"`
-	DeleteAt  time.Time `gorm:"column:deleted_at;index" json:"-" example:"2024-03-20T10:00:00Z" description:"删除时间" filter:"gt,gte,lt,lte,eq,neq,in,nin"` // 软删除
+	ID        uuid.UUID      `gorm:"type:string;primarykey;" json:"id" example:"1" description:"唯一标识符" filter:"eq,neq,in,nin"`
+	CreatedAt time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at" example:"2024-03-20T10:00:00Z" description:"创建时间" filter:"gt,gte,lt,lte,eq,neq,in,nin"`
+	UpdatedAt time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at" example:"2024-03-20T10:00:00Z" description:"更新时间" filter:"gt,gte,lt,lte,eq,neq,in,nin"`
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index" json:"-" example:"2024-03-20T10:00:00Z" description:"删除时间"` // 软删除
+	DeletedBy string         `gorm:"column:deleted_by;size:64" json:"-" description:"删除人"`
 }
 
 // func ()
@@ -47,7 +48,7 @@ func (e *BaseUUIDEntity) GetUpdatedAt() time.Time {
 
 // GetDeletedAt 获取删除时间
 func (e *BaseUUIDEntity) GetDeletedAt() time.Time {
-	return e.DeleteAt
+	return e.DeletedAt.Time
 }
 
 // SetCreatedAt 设置创建时间
@@ -62,7 +63,17 @@ func (e *BaseUUIDEntity) SetUpdatedAt(t time.Time) {
 
 // SetDeletedAt 设置删除时间
 func (e *BaseUUIDEntity) SetDeletedAt(t time.Time) {
-	e.DeleteAt = t
+	e.DeletedAt = gorm.DeletedAt{Time: t, Valid: !t.IsZero()}
+}
+
+// GetDeletedBy 获取删除人
+func (e *BaseUUIDEntity) GetDeletedBy() string {
+	return e.DeletedBy
+}
+
+// SetDeletedBy 设置删除人
+func (e *BaseUUIDEntity) SetDeletedBy(deletedBy string) {
+	e.DeletedBy = deletedBy
 }
 
 func (e *BaseUUIDEntity) BeforeCreate(tx *gorm.DB) (err error) {
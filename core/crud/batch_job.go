@@ -0,0 +1,167 @@
+package crud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kruily/gofastcrud/config"
+	"github.com/kruily/gofastcrud/core/crud/module"
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/core/crud/types"
+	"github.com/kruily/gofastcrud/core/di"
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/kruily/gofastcrud/tenant"
+)
+
+// defaultBatchSize 批量任务默认每批处理数量
+const defaultBatchSize = 100
+
+// defaultBatchJobStore 未注册 BatchJobService 时使用的内存存储
+var defaultBatchJobStore = NewMemoryBatchJobStore()
+
+// batchJobStore 获取批量任务存储
+func batchJobStore() module.IBatchJobStore {
+	if store, err := di.SINGLE().ResolveSingleton(module.BatchJobService); err == nil {
+		if store, ok := store.(module.IBatchJobStore); ok {
+			return store
+		}
+	}
+	return defaultBatchJobStore
+}
+
+// batchOptions 解析批量操作选项
+// 请求携带 async=true 或记录数超过配置 batch.async_threshold 时异步执行
+func batchOptions(ctx *gin.Context, total int) *options.BatchOptions {
+	opts := &options.BatchOptions{BatchSize: defaultBatchSize}
+	if size, err := strconv.Atoi(ctx.Query("batch_size")); err == nil && size > 0 {
+		opts.BatchSize = size
+	}
+	opts.Async, _ = strconv.ParseBool(ctx.Query("async"))
+	if cfg := config.CONFIG_MANAGER.GetConfig(); cfg != nil && cfg.Batch.AsyncThreshold > 0 && total > cfg.Batch.AsyncThreshold {
+		opts.Async = true
+	}
+	return opts
+}
+
+// startBatchJob 创建批量任务并在后台执行，返回 202 与任务信息
+// process 处理 [start, end) 区间的记录，id 返回第 i 条记录的ID
+func (c *BlankController[T]) startBatchJob(ctx *gin.Context, operation string, total int, opts *options.BatchOptions,
+	process func(ctx context.Context, start, end int) error, id func(i int) any) (interface{}, error) {
+	now := time.Now()
+	tenantID, _ := tenant.FromContext(ctx)
+	job := &types.BatchJob{
+		ID:        uuid.NewString(),
+		Entity:    c.entityName,
+		TenantID:  tenantID,
+		Actor:     auditActor(ctx),
+		Operation: operation,
+		Status:    types.BatchJobPending,
+		Total:     total,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	store := batchJobStore()
+	if err := store.Save(ctx, job); err != nil {
+		return nil, err
+	}
+
+	// 任务在请求结束后继续执行，保留上下文中的值但不随请求取消
+	jobCtx := withAuditMeta(context.WithoutCancel(ctx.Request.Context()), ctx)
+	snapshot := copyBatchJob(job)
+	go runBatchJob(jobCtx, store, job, opts.BatchSize, process, id)
+
+	ctx.Status(http.StatusAccepted)
+	return c.Responser.Success(snapshot), nil
+}
+
+// runBatchJob 分批执行任务，批次失败时逐条重试以定位失败记录
+func runBatchJob(ctx context.Context, store module.IBatchJobStore, job *types.BatchJob, batchSize int,
+	process func(ctx context.Context, start, end int) error, id func(i int) any) {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	ids := make([]any, 0, job.Total)
+	save := func() {
+		job.UpdatedAt = time.Now()
+		store.Save(ctx, job)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			job.Errors = append(job.Errors, types.BatchItemError{Index: job.Processed, Error: fmt.Sprint(r)})
+			job.Failed = job.Total - job.Succeeded
+			job.Processed = job.Total
+		}
+		job.Result = ids
+		switch {
+		case job.Failed == 0:
+			job.Status = types.BatchJobSucceeded
+		case job.Succeeded == 0:
+			job.Status = types.BatchJobFailed
+		default:
+			job.Status = types.BatchJobPartial
+		}
+		finishedAt := time.Now()
+		job.FinishedAt = &finishedAt
+		save()
+	}()
+
+	job.Status = types.BatchJobRunning
+	save()
+	for start := 0; start < job.Total; start += batchSize {
+		end := min(start+batchSize, job.Total)
+		if err := process(ctx, start, end); err == nil {
+			for i := start; i < end; i++ {
+				ids = append(ids, id(i))
+			}
+			job.Succeeded += end - start
+		} else {
+			for i := start; i < end; i++ {
+				if err := process(ctx, i, i+1); err != nil {
+					job.Failed++
+					job.Errors = append(job.Errors, types.BatchItemError{Index: i, Error: err.Error()})
+					continue
+				}
+				ids = append(ids, id(i))
+				job.Succeeded++
+			}
+		}
+		job.Processed = end
+		save()
+	}
+}
+
+// BatchJob 查询批量任务进度与结果，只有创建任务的租户与操作人可以查询
+func (c *BlankController[T]) BatchJob(ctx *gin.Context) (interface{}, error) {
+	job, err := batchJobStore().Get(ctx, ctx.Param("job_id"))
+	if err != nil {
+		return nil, err
+	}
+	tenantID, _ := tenant.FromContext(ctx)
+	if job.Entity != c.entityName || job.TenantID != tenantID || job.Actor != auditActor(ctx) {
+		return nil, errors.New(errors.ErrNotFound, "batch job not found")
+	}
+	return c.Responser.Success(job), nil
+}
+
+// batchParams 批量操作参数
+func batchParams() []types.Parameter {
+	return []types.Parameter{
+		{
+			Name:        "async",
+			In:          "query",
+			Description: "Run the batch in background and return 202 with a job, see GET /batch/jobs/:job_id",
+			Schema:      types.Schema{Type: "boolean", Default: "false"},
+		},
+		{
+			Name:        "batch_size",
+			In:          "query",
+			Description: "Number of records processed per transaction",
+			Schema:      types.Schema{Type: "integer", Default: defaultBatchSize},
+		},
+	}
+}
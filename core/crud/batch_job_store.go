@@ -0,0 +1,133 @@
+package crud
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kruily/gofastcrud/core/crud/module"
+	"github.com/kruily/gofastcrud/core/crud/types"
+	"github.com/kruily/gofastcrud/errors"
+	"gorm.io/gorm"
+)
+
+// memoryBatchJobStore 内存批量任务存储，进程重启后任务丢失
+// 已结束的任务保留 ttl 时间且最多保留 limit 个，超出时淘汰最早结束的任务，执行中的任务不会被淘汰
+type memoryBatchJobStore struct {
+	mu       sync.RWMutex
+	jobs     map[string]types.BatchJob
+	finished []string // 已结束任务的ID，按结束顺序排列
+	ttl      time.Duration
+	limit    int
+}
+
+// MemoryBatchJobOption 内存批量任务存储选项
+type MemoryBatchJobOption func(*memoryBatchJobStore)
+
+// WithBatchJobTTL 设置已结束任务的保留时间，默认 24 小时
+func WithBatchJobTTL(ttl time.Duration) MemoryBatchJobOption {
+	return func(s *memoryBatchJobStore) {
+		s.ttl = ttl
+	}
+}
+
+// WithBatchJobLimit 设置已结束任务的最大保留数量，默认 1000
+func WithBatchJobLimit(limit int) MemoryBatchJobOption {
+	return func(s *memoryBatchJobStore) {
+		s.limit = limit
+	}
+}
+
+// NewMemoryBatchJobStore 创建内存批量任务存储
+func NewMemoryBatchJobStore(opts ...MemoryBatchJobOption) module.IBatchJobStore {
+	store := &memoryBatchJobStore{
+		jobs:  make(map[string]types.BatchJob),
+		ttl:   24 * time.Hour,
+		limit: 1000,
+	}
+	for _, opt := range opts {
+		opt(store)
+	}
+	return store
+}
+
+// Save 保存任务副本，避免执行中的任务与读取方共享数据；任务结束时淘汰过期与超出数量的已结束任务
+func (s *memoryBatchJobStore) Save(ctx context.Context, job *types.BatchJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, exists := s.jobs[job.ID]
+	s.jobs[job.ID] = copyBatchJob(job)
+	if job.FinishedAt != nil && (!exists || previous.FinishedAt == nil) {
+		s.finished = append(s.finished, job.ID)
+		s.evict()
+	}
+	return nil
+}
+
+// evict 淘汰超过保留时间的已结束任务，并在数量超出上限时淘汰最早结束的任务
+func (s *memoryBatchJobStore) evict() {
+	deadline := time.Now().Add(-s.ttl)
+	kept := s.finished[:0]
+	for _, id := range s.finished {
+		if job, ok := s.jobs[id]; ok && job.FinishedAt != nil && job.FinishedAt.After(deadline) {
+			kept = append(kept, id)
+			continue
+		}
+		delete(s.jobs, id)
+	}
+	for s.limit > 0 && len(kept) > s.limit {
+		delete(s.jobs, kept[0])
+		kept = kept[1:]
+	}
+	s.finished = kept
+}
+
+// Get 获取任务，已结束且超过保留时间的任务视为不存在
+func (s *memoryBatchJobStore) Get(ctx context.Context, id string) (*types.BatchJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, ok := s.jobs[id]
+	if !ok || (job.FinishedAt != nil && time.Since(*job.FinishedAt) > s.ttl) {
+		return nil, errors.New(errors.ErrNotFound, "batch job not found")
+	}
+	job = copyBatchJob(&job)
+	return &job, nil
+}
+
+// copyBatchJob 复制任务
+func copyBatchJob(job *types.BatchJob) types.BatchJob {
+	copied := *job
+	copied.Errors = append([]types.BatchItemError(nil), job.Errors...)
+	return copied
+}
+
+// gormBatchJobStore 数据库批量任务存储
+type gormBatchJobStore struct {
+	db *gorm.DB
+}
+
+// NewGormBatchJobStore 创建数据库批量任务存储，并自动迁移 batch_jobs 表
+func NewGormBatchJobStore(db *gorm.DB) (module.IBatchJobStore, error) {
+	if err := db.AutoMigrate(&types.BatchJob{}); err != nil {
+		return nil, err
+	}
+	return &gormBatchJobStore{db: db}, nil
+}
+
+// Save 保存任务
+func (s *gormBatchJobStore) Save(ctx context.Context, job *types.BatchJob) error {
+	return s.db.WithContext(ctx).Save(job).Error
+}
+
+// Get 获取任务
+func (s *gormBatchJobStore) Get(ctx context.Context, id string) (*types.BatchJob, error) {
+	job := &types.BatchJob{}
+	result := s.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(job)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New(errors.ErrNotFound, "batch job not found")
+	}
+	return job, nil
+}
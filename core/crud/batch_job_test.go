@@ -0,0 +1,101 @@
+package crud
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/core/crud/types"
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/kruily/gofastcrud/tenant"
+	"github.com/kruily/gofastcrud/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunBatchJob(t *testing.T) {
+	db, _ := setupGormRepository(t)
+	store, err := NewGormBatchJobStore(db)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// 第 3 条记录失败，所在批次逐条重试后其余记录成功
+	job := &types.BatchJob{ID: "job-1", Entity: "testArticle", Operation: types.BatchOperationCreate, Total: 5}
+	require.NoError(t, store.Save(ctx, job))
+	runBatchJob(ctx, store, job, 2, func(ctx context.Context, start, end int) error {
+		if start <= 3 && 3 < end {
+			return fmt.Errorf("item %d is invalid", 3)
+		}
+		return nil
+	}, func(i int) any { return i })
+
+	saved, err := store.Get(ctx, "job-1")
+	require.NoError(t, err)
+	require.Equal(t, types.BatchJobPartial, saved.Status)
+	require.Equal(t, 5, saved.Processed)
+	require.Equal(t, 4, saved.Succeeded)
+	require.Equal(t, []types.BatchItemError{{Index: 3, Error: "item 3 is invalid"}}, saved.Errors)
+	require.Len(t, saved.Result, 4)
+	require.NotNil(t, saved.FinishedAt)
+
+	_, err = NewMemoryBatchJobStore().Get(ctx, "job-1")
+	require.True(t, errors.Is(err, errors.ErrNotFound))
+}
+
+func TestMemoryBatchJobStoreEviction(t *testing.T) {
+	store := NewMemoryBatchJobStore(WithBatchJobTTL(time.Hour), WithBatchJobLimit(2))
+	ctx := context.Background()
+	finish := func(id string, at time.Time) {
+		require.NoError(t, store.Save(ctx, &types.BatchJob{ID: id, Status: types.BatchJobSucceeded, FinishedAt: &at}))
+	}
+	exists := func(id string) bool {
+		_, err := store.Get(ctx, id)
+		return err == nil
+	}
+
+	// 执行中的任务不会被淘汰
+	require.NoError(t, store.Save(ctx, &types.BatchJob{ID: "running", Status: types.BatchJobRunning}))
+	// 超过保留时间的任务不可读取，并在下次任务结束时清理
+	finish("expired", time.Now().Add(-2*time.Hour))
+	require.False(t, exists("expired"))
+	finish("a", time.Now())
+	finish("b", time.Now())
+	require.Len(t, store.(*memoryBatchJobStore).jobs, 3)
+
+	// 超出数量上限时淘汰最早结束的任务
+	finish("c", time.Now())
+	require.False(t, exists("a"))
+	require.True(t, exists("b"))
+	require.True(t, exists("c"))
+	require.True(t, exists("running"))
+}
+
+func TestBatchJobOwner(t *testing.T) {
+	controller := &BlankController[*testArticle]{Responser: &utils.DefaultResponseHandler{}, entityName: "testArticle"}
+	request := func(tenantID string, userID float64) *gin.Context {
+		ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx.Request = httptest.NewRequest("GET", "/articles/batch/jobs", nil)
+		ctx.Set(tenant.ContextKey, tenantID)
+		ctx.Set("user_id", userID)
+		return ctx
+	}
+
+	owner := request("acme", 1)
+	result, err := controller.startBatchJob(owner, types.BatchOperationDelete, 1, &options.BatchOptions{BatchSize: 1},
+		func(ctx context.Context, start, end int) error { return nil }, func(i int) any { return i })
+	require.NoError(t, err)
+	job := result.(utils.Response).Data.(types.BatchJob)
+
+	find := func(ctx *gin.Context) error {
+		ctx.Params = gin.Params{{Key: "job_id", Value: job.ID}}
+		_, err := controller.BatchJob(ctx)
+		return err
+	}
+	require.NoError(t, find(owner))
+	// 其他租户或其他操作人查询时任务不存在
+	require.True(t, errors.Is(find(request("globex", 1)), errors.ErrNotFound))
+	require.True(t, errors.Is(find(request("acme", 2)), errors.ErrNotFound))
+}
@@ -1,17 +1,21 @@
 package crud
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/kruily/gofastcrud/config"
 	"github.com/kruily/gofastcrud/core/crud/module"
 	"github.com/kruily/gofastcrud/core/crud/options"
 	"github.com/kruily/gofastcrud/core/crud/types"
 	"github.com/kruily/gofastcrud/core/database"
 	"github.com/kruily/gofastcrud/core/di"
+	"github.com/kruily/gofastcrud/errors"
 )
 
 // ICrudController 控制器接口
@@ -42,6 +46,7 @@ type BlankController[T ICrudEntity] struct {
 	middlewares map[string][]gin.HandlerFunc
 	routes      []*types.APIRoute
 	group       *gin.RouterGroup
+	permissions []IDataPermission // 数据权限
 }
 
 // NewCrudController 创建控制器
@@ -104,6 +109,54 @@ func (c *BlankController[T]) UseMiddleware(method string, middlewares ...gin.Han
 	c.middlewares[method] = append(c.middlewares[method], middlewares...)
 }
 
+// UseDataPermission 添加数据权限，列表、详情、更新与删除只能访问规则允许的记录，多个数据权限之间为且关系
+func (c *BlankController[T]) UseDataPermission(permissions ...IDataPermission) {
+	c.permissions = append(c.permissions, permissions...)
+}
+
+// dataScope 计算 act 操作的数据权限条件，未配置数据权限或规则不限制时返回 nil
+func (c *BlankController[T]) dataScope(ctx *gin.Context, act string) (*options.FilterExpr, error) {
+	scopes := make([]options.FilterExpr, 0, len(c.permissions))
+	for _, permission := range c.permissions {
+		rules, err := permission.DataRules(ctx, act)
+		if err != nil {
+			return nil, err
+		}
+		expr, err := compileDataRules(ctx, c.entityType(), c.entity.DBType(), rules)
+		if err != nil {
+			return nil, err
+		}
+		if expr != nil {
+			scopes = append(scopes, *expr)
+		}
+	}
+	switch len(scopes) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &scopes[0], nil
+	}
+	return &options.FilterExpr{And: scopes}, nil
+}
+
+// scoped 返回携带 act 操作数据权限条件的上下文，仓储读写只作用于允许的记录
+func (c *BlankController[T]) scoped(ctx *gin.Context, act string) (context.Context, error) {
+	expr, err := c.dataScope(ctx, act)
+	if err != nil {
+		return nil, err
+	}
+	return withDataScope(ctx, c.entityType(), expr), nil
+}
+
+// entityType 实体类型
+func (c *BlankController[T]) entityType() reflect.Type {
+	entityType := reflect.TypeOf(c.entity)
+	if entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+	return entityType
+}
+
 // GetMiddlewares 获取中间件
 func (c *BlankController[T]) GetMiddlewares() map[string][]gin.HandlerFunc {
 	return c.middlewares
@@ -164,6 +217,34 @@ func (c *BlankController[T]) GetResponser() module.ICrudResponse {
 	return c.Responser
 }
 
+// idParam 路由中的ID参数名，与路由定义保持一致
+func (c *BlankController[T]) idParam() string {
+	return strings.ToLower(c.entityName[:1]) + c.entityName[1:] + "_id"
+}
+
+// parseID 解析路由中的ID，依次尝试 UUID、整数，否则保持字符串
+func (c *BlankController[T]) parseID(ctx *gin.Context) (any, error) {
+	id := ctx.Param(c.idParam())
+	if id == "" {
+		return nil, errors.New(errors.ErrNotFound, "missing id parameter")
+	}
+	if idUUID, err := uuid.Parse(id); err == nil {
+		return idUUID, nil
+	}
+	if idInt, err := strconv.ParseUint(id, 10, 64); err == nil {
+		return idInt, nil
+	}
+	return id, nil
+}
+
+// operatorID 当前操作人，来自认证中间件写入的 user_id
+func operatorID(ctx *gin.Context) string {
+	if userID, ok := ctx.Get("user_id"); ok && userID != nil {
+		return fmt.Sprint(userID)
+	}
+	return ""
+}
+
 // queryParams 获取查询参数
 func (c *BlankController[T]) queryParams() []types.Parameter {
 	// 获取所有可查询字段
@@ -188,6 +269,18 @@ func (c *BlankController[T]) queryParams() []types.Parameter {
 			Description: "Number of items per page",
 			Schema:      types.Schema{Type: "integer", Default: "10"},
 		},
+		{
+			Name:        "cursor",
+			In:          "query",
+			Description: "Opaque cursor for keyset pagination, pass an empty value to request the first page (page/page_size are ignored in cursor mode)",
+			Schema:      types.Schema{Type: "string"},
+		},
+		{
+			Name:        "limit",
+			In:          "query",
+			Description: "Number of items per page in cursor mode",
+			Schema:      types.Schema{Type: "integer", Default: "10"},
+		},
 		{
 			Name:        "order_by",
 			In:          "query",
@@ -206,12 +299,6 @@ func (c *BlankController[T]) queryParams() []types.Parameter {
 			Description: "Fields to search in (comma-separated)",
 			Schema:      types.Schema{Type: "string", Default: strings.Join(searchFields, ",")},
 		},
-		{
-			Name:        "preload",
-			In:          "query",
-			Description: "Relations to preload (comma-separated)",
-			Schema:      types.Schema{Type: "string"},
-		},
 		{
 			Name:        "fields",
 			In:          "query",
@@ -221,13 +308,14 @@ func (c *BlankController[T]) queryParams() []types.Parameter {
 	}
 
 	// 为每个字段添加过滤参数
+	queryParams = append(queryParams, expandParams()...)
+	queryParams = append(queryParams, searchModeParam(), filterExprParam())
 	queryParams = append(queryParams, ModeParams(c)...)
 	return queryParams
 }
 
-// BuildQueryOptions 构建查询选项
-func (c *BlankController[T]) BuildQueryOptions(ctx *gin.Context) *options.QueryOptions {
-	// 获取基础分页参数
+// pagination 解析分页参数，页面大小不超过配置的最大值
+func pagination(ctx *gin.Context) (int, int) {
 	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", strconv.Itoa(config.CONFIG_MANAGER.GetConfig().Pagenation.DefaultPageSize)))
 
@@ -235,6 +323,13 @@ func (c *BlankController[T]) BuildQueryOptions(ctx *gin.Context) *options.QueryO
 	if pageSize > config.CONFIG_MANAGER.GetConfig().Pagenation.MaxPageSize {
 		pageSize = config.CONFIG_MANAGER.GetConfig().Pagenation.MaxPageSize
 	}
+	return page, pageSize
+}
+
+// BuildQueryOptions 构建查询选项，过滤参数不合法时返回 ErrInvalidParam
+func (c *BlankController[T]) BuildQueryOptions(ctx *gin.Context) (*options.QueryOptions, error) {
+	// 获取基础分页参数
+	page, pageSize := pagination(ctx)
 
 	// 构建查询选项
 	opts := options.NewQueryOptions(
@@ -243,18 +338,44 @@ func (c *BlankController[T]) BuildQueryOptions(ctx *gin.Context) *options.QueryO
 		options.WithOrderBy(ctx.DefaultQuery("order_by", "id desc")),
 	)
 
+	// 处理游标分页
+	if cursor, ok := ctx.GetQuery("cursor"); ok {
+		limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(config.CONFIG_MANAGER.GetConfig().Pagenation.DefaultPageSize)))
+		if limit <= 0 {
+			limit = config.CONFIG_MANAGER.GetConfig().Pagenation.DefaultPageSize
+		}
+		if limit > config.CONFIG_MANAGER.GetConfig().Pagenation.MaxPageSize {
+			limit = config.CONFIG_MANAGER.GetConfig().Pagenation.MaxPageSize
+		}
+		options.WithCursor(cursor)(opts)
+		options.WithLimit(limit)(opts)
+	}
+
 	// 处理搜索
-	if search := ctx.Query("search"); search != "" {
-		opts.Search = search
-		opts.SearchFields = strings.Split(ctx.DefaultQuery("search_fields", "id"), ",")
+	entityType := reflect.TypeOf(c.entity)
+	if err := searchOptions(ctx, entityType, c.entity.DBType(), opts); err != nil {
+		return nil, err
 	}
 
 	// 处理过滤条件
-	c.buildFilterOptions(ctx, opts)
+	conditions, err := compileFilters(entityType, c.entity.DBType(), ctx.Request.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+	options.WithConditions(conditions...)(opts)
 
-	// 处理预加载关系
-	if preload := ctx.Query("preload"); preload != "" {
-		opts.Preload = strings.Split(preload, ",")
+	// 处理嵌套过滤表达式
+	if filter := ctx.Query("filter"); filter != "" {
+		expr, err := parseFilterExpr(entityType, c.entity.DBType(), filter)
+		if err != nil {
+			return nil, err
+		}
+		options.WithFilterExpr(expr)(opts)
+	}
+
+	// 处理关联字段过滤与排序
+	if err := relationOptions(entityType, c.entity.DBType(), opts); err != nil {
+		return nil, err
 	}
 
 	// 处理字段选择
@@ -262,65 +383,22 @@ func (c *BlankController[T]) BuildQueryOptions(ctx *gin.Context) *options.QueryO
 		opts.Select = strings.Split(fields, ",")
 	}
 
-	return opts
-}
-
-// buildFilterOptions 构建过滤选项
-func (c *BlankController[T]) buildFilterOptions(ctx *gin.Context, opts *options.QueryOptions) {
-	querys := ctx.Request.URL.Query()
-	for key, values := range querys {
-		// 跳过特殊参数
-		if isSpecialParam(key) {
-			continue
-		}
-		// 处理范围查询
-		if strings.HasSuffix(key, "_gt") || strings.HasSuffix(key, "_lt") ||
-			strings.HasSuffix(key, "_gte") || strings.HasSuffix(key, "_lte") {
-			field := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(
-				strings.TrimSuffix(key, "_gt"), "_lt"), "_gte"), "_lte")
-
-			if strings.HasSuffix(key, "_gt") {
-				opts.Where[field+" > ?"] = values[0]
-			} else if strings.HasSuffix(key, "_lt") {
-				opts.Where[field+" < ?"] = values[0]
-			} else if strings.HasSuffix(key, "_gte") {
-				opts.Where[field+" >= ?"] = values[0]
-			} else if strings.HasSuffix(key, "_lte") {
-				opts.Where[field+" <= ?"] = values[0]
-			}
-			continue
-		}
-
-		// 处理IN查询
-		if strings.HasSuffix(key, "_in") {
-			field := strings.TrimSuffix(key, "_in")
-			opts.Where[field+" IN ?"] = strings.Split(values[0], ",")
-			continue
+	// 处理关联展开，preload 为 expand 的旧名称，同样只能展开声明了 expand 标签的关联
+	expand := ctx.DefaultQuery("expand", ctx.Query("preload"))
+	if relationFields := ctx.QueryMap("fields"); expand != "" || len(relationFields) > 0 {
+		expands, keys, err := parseExpand(entityType, c.entity.DBType(), strings.Split(expand, ","), relationFields, maxExpandDepth())
+		if err != nil {
+			return nil, err
 		}
-
-		// 处理NULL查询
-		if strings.HasSuffix(key, "_null") {
-			field := strings.TrimSuffix(key, "_null")
-			if values[0] == "true" {
-				opts.Where[field+" IS NULL"] = nil
-			} else {
-				opts.Where[field+" IS NOT NULL"] = nil
+		options.WithExpand(expands...)(opts)
+		for _, key := range keys {
+			if len(opts.Select) > 0 && !containsColumn(opts.Select, key) {
+				opts.Select = append(opts.Select, key)
 			}
-			continue
-		}
-
-		// 处理模糊查询
-		if strings.HasSuffix(key, "_like") {
-			field := strings.TrimSuffix(key, "_like")
-			opts.Where[field+" LIKE ?"] = "%" + values[0] + "%"
-			continue
-		}
-
-		// 处理普通相等查询
-		if !isSpecialParam(key) {
-			opts.Filter[key] = values[0]
 		}
 	}
+
+	return opts, nil
 }
 
 // isSpecialParam 检查是否为特殊参数
@@ -328,7 +406,12 @@ func isSpecialParam(key string) bool {
 	specialParams := []string{
 		"page", "page_size", "order_by",
 		"search", "search_fields", "preload",
-		"fields",
+		"fields", "cursor", "limit",
+		"func", "field", "group_by", "having",
+		"filter", "search_mode", "expand",
+	}
+	if strings.HasPrefix(key, "fields[") {
+		return true
 	}
 	for _, param := range specialParams {
 		if key == param {
@@ -2,14 +2,12 @@ package crud
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/kruily/gofastcrud/core/crud/types"
 	"github.com/kruily/gofastcrud/core/database"
-	"github.com/kruily/gofastcrud/errors"
 )
 
 type OnlyReadController[T ICrudEntity] struct {
@@ -28,43 +26,53 @@ func NewOnlyReadController[T ICrudEntity](db *database.Database, entity T) *Only
 
 // GetById 根据ID获取实体
 func (c *OnlyReadController[T]) GetById(ctx *gin.Context) (interface{}, error) {
-	// TODO 仍旧获取不到正确的ID
-	id := ctx.Param(strings.ToLower(c.entityName) + "_id")
-	if id == "" {
-		return nil, errors.New(errors.ErrNotFound, "missing id parameter")
+	idTID, err := c.parseID(ctx)
+	if err != nil {
+		return nil, err
 	}
-	var idTID any
 
-	// 处理 UUID 类型
-	if idUUID, err := uuid.Parse(id); err == nil {
-		idTID = idUUID // 直接赋值
-	} else if idInt, err := strconv.ParseUint(id, 10, 64); err == nil {
-		idTID = idInt // 直接赋值
-	} else {
-		idTID = id // 直接赋值
+	readCtx, err := c.scoped(ctx, DataActRead)
+	if err != nil {
+		return nil, err
 	}
-
-	entity, err := c.Repository.FindById(ctx, idTID)
+	entity, err := c.Repository.FindById(readCtx, idTID)
 	if err != nil {
 		return nil, err
 	}
 
+	setETag(ctx, entity)
 	return c.Responser.Success(entity), nil
 }
 
 // List 获取实体列表
 func (c *OnlyReadController[T]) List(ctx *gin.Context) (interface{}, error) {
 	// 构建查询选项
-	opts := c.BuildQueryOptions(ctx)
+	opts, err := c.BuildQueryOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	listCtx, err := c.scoped(ctx, DataActList)
+	if err != nil {
+		return nil, err
+	}
+
+	// 游标分页
+	if opts.UseCursor {
+		page, err := c.Repository.FindByCursor(listCtx, c.entity, opts)
+		if err != nil {
+			return nil, err
+		}
+		return c.Responser.CursorPagenation(page.Items, page.NextCursor, page.PrevCursor, opts.Limit), nil
+	}
 
 	// 执行查询
-	items, err := c.Repository.Find(ctx, c.entity, opts)
+	items, err := c.Repository.Find(listCtx, c.entity, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	// 获取总数
-	total, err := c.Repository.Count(ctx, c.entity)
+	total, err := c.Repository.Count(listCtx, c.entity)
 	if err != nil {
 		return nil, err
 	}
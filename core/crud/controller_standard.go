@@ -1,12 +1,14 @@
 package crud
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"strconv"
+	"reflect"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"github.com/kruily/gofastcrud/core/crud/options"
 	"github.com/kruily/gofastcrud/core/crud/types"
 	"github.com/kruily/gofastcrud/core/database"
 	"github.com/kruily/gofastcrud/errors"
@@ -53,43 +55,53 @@ func (c *CrudController[T]) Create(ctx *gin.Context) (interface{}, error) {
 
 // GetById 根据ID获取实体
 func (c *CrudController[T]) GetById(ctx *gin.Context) (interface{}, error) {
-	// TODO 仍旧获取不到正确的ID
-	id := ctx.Param(strings.ToLower(c.entityName) + "_id")
-	if id == "" {
-		return nil, errors.New(errors.ErrNotFound, "missing id parameter")
+	idTID, err := c.parseID(ctx)
+	if err != nil {
+		return nil, err
 	}
-	var idTID any
 
-	// 处理 UUID 类型
-	if idUUID, err := uuid.Parse(id); err == nil {
-		idTID = idUUID // 直接赋值
-	} else if idInt, err := strconv.ParseUint(id, 10, 64); err == nil {
-		idTID = idInt // 直接赋值
-	} else {
-		idTID = id
+	readCtx, err := c.scoped(ctx, DataActRead)
+	if err != nil {
+		return nil, err
 	}
-
-	entity, err := c.Repository.FindById(ctx, idTID)
+	entity, err := c.Repository.FindById(readCtx, idTID)
 	if err != nil {
 		return nil, err
 	}
 
+	setETag(ctx, entity)
 	return c.Responser.Success(entity), nil
 }
 
 // List 获取实体列表
 func (c *CrudController[T]) List(ctx *gin.Context) (interface{}, error) {
 	// 构建查询选项
-	opts := c.BuildQueryOptions(ctx)
+	opts, err := c.BuildQueryOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	listCtx, err := c.scoped(ctx, DataActList)
+	if err != nil {
+		return nil, err
+	}
+
+	// 游标分页
+	if opts.UseCursor {
+		page, err := c.Repository.FindByCursor(listCtx, c.entity, opts)
+		if err != nil {
+			return nil, err
+		}
+		return c.Responser.CursorPagenation(page.Items, page.NextCursor, page.PrevCursor, opts.Limit), nil
+	}
 
 	// 执行查询
-	items, err := c.Repository.Find(ctx, c.entity, opts)
+	items, err := c.Repository.Find(listCtx, c.entity, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	// 获取总数
-	total, err := c.Repository.Count(ctx, c.entity)
+	total, err := c.Repository.Count(listCtx, c.entity)
 	if err != nil {
 		return nil, err
 	}
@@ -99,9 +111,9 @@ func (c *CrudController[T]) List(ctx *gin.Context) (interface{}, error) {
 
 // Update 更新实体
 func (c *CrudController[T]) Update(ctx *gin.Context) (interface{}, error) {
-	id := ctx.Param(strings.ToLower(c.entityName) + "_id")
-	if id == "" {
-		return nil, errors.New(errors.ErrNotFound, "missing id parameter")
+	idTID, err := c.parseID(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// 将请求体绑定到map
@@ -110,61 +122,227 @@ func (c *CrudController[T]) Update(ctx *gin.Context) (interface{}, error) {
 		return nil, err
 	}
 
+	updateCtx, err := c.scoped(ctx, DataActUpdate)
+	if err != nil {
+		return nil, err
+	}
+	// 读主库，避免副本延迟导致版本校验失败
+	entity, err := c.Repository.FindById(database.WithPrimary(updateCtx), idTID)
+	if err != nil {
+		return nil, errors.New(errors.ErrNotFound, "No record of this id was found")
+	}
+
+	// 乐观锁版本校验
+	if err := checkVersion(ctx, entity, updateFields); err != nil {
+		return nil, err
+	}
+
 	// 验证字段
 	if err := validator.ValidateMap(updateFields, c.entity); err != nil {
 		return nil, err
 	}
 
-	var idTID any
-
-	// 处理 UUID 类型
-	if idUUID, err := uuid.Parse(id); err == nil {
-		idTID = idUUID // 直接赋值
-	} else if idInt, err := strconv.ParseUint(id, 10, 64); err == nil {
-		idTID = idInt // 直接赋值
-	}else {
-		idTID = id // 直接赋值
+	// 更新指定字段，json 名称转换为存储字段名
+	fields, err := storageFields(reflect.TypeOf(c.entity), c.entity.DBType(), updateFields)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Repository.Update(updateCtx, entity, fields); err != nil {
+		return nil, err
 	}
 
+	return c.reload(ctx, idTID)
+}
+
+// Patch 局部更新实体
+// 支持 application/merge-patch+json（RFC 7396，application/json 按此处理）与 application/json-patch+json（RFC 6902）
+func (c *CrudController[T]) Patch(ctx *gin.Context) (interface{}, error) {
+	idTID, err := c.parseID(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	entity, err := c.Repository.FindById(ctx, idTID)
+	updateCtx, err := c.scoped(ctx, DataActUpdate)
+	if err != nil {
+		return nil, err
+	}
+	// 读主库，避免副本延迟导致版本校验失败
+	entity, err := c.Repository.FindById(database.WithPrimary(updateCtx), idTID)
 	if err != nil {
 		return nil, errors.New(errors.ErrNotFound, "No record of this id was found")
 	}
+	if err := checkVersion(ctx, entity, nil); err != nil {
+		return nil, err
+	}
 
-	// 更新指定字段
-	if err := c.Repository.Update(ctx, entity, updateFields); err != nil {
+	// 实体转为 json 文档后应用补丁
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	original := make(map[string]interface{})
+	if err := json.Unmarshal(data, &original); err != nil {
 		return nil, err
 	}
+	document := deepCopyJSON(original)
+
+	var patched interface{}
+	switch ctx.ContentType() {
+	case JSONPatchContentType:
+		var ops []PatchOperation
+		if err := ctx.ShouldBindJSON(&ops); err != nil {
+			return nil, errors.New(errors.ErrInvalidParam, err.Error())
+		}
+		if patched, err = applyJSONPatch(document, ops); err != nil {
+			return nil, err
+		}
+	case MergePatchContentType, "application/json":
+		var patch interface{}
+		if err := ctx.ShouldBindJSON(&patch); err != nil {
+			return nil, errors.New(errors.ErrInvalidParam, err.Error())
+		}
+		patched = mergePatch(document, patch)
+	default:
+		return nil, errors.New(errors.ErrInvalidParam, "unsupported content type: "+ctx.ContentType())
+	}
+	patchedDoc, ok := patched.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(errors.ErrInvalidParam, "patched document must be an object")
+	}
+
+	// 补丁后的文档还原为实体并整体校验
+	patchedEntity := NewModel[T]()
+	data, err = json.Marshal(patchedDoc)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, patchedEntity); err != nil {
+		return nil, errors.New(errors.ErrInvalidParam, err.Error())
+	}
+	if err := validator.Validate(patchedEntity); err != nil {
+		return nil, err
+	}
+
+	fields, err := patchedFields(reflect.TypeOf(c.entity), c.entity.DBType(), original, patchedDoc, patchedEntity)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) > 0 {
+		if err := c.Repository.Update(updateCtx, entity, fields); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.reload(ctx, idTID)
+}
 
+// reload 从主库重新查询更新后的实体并设置 ETag
+func (c *CrudController[T]) reload(ctx *gin.Context, id any) (interface{}, error) {
+	readCtx, err := c.scoped(ctx, DataActRead)
+	if err != nil {
+		return nil, err
+	}
+	entity, err := c.Repository.FindById(database.WithPrimary(readCtx), id)
+	if err != nil {
+		return nil, err
+	}
+	setETag(ctx, entity)
 	return c.Responser.Success(entity), nil
 }
 
 // Delete 删除实体
 func (c *CrudController[T]) Delete(ctx *gin.Context) (interface{}, error) {
-	id := ctx.Param(strings.ToLower(c.entityName) + "_id")
-	if id == "" {
-		return nil, errors.New(errors.ErrNotFound, "missing id parameter")
+	idTID, err := c.parseID(ctx)
+	if err != nil {
+		return nil, err
 	}
-	var idTID any
-	// 处理 UUID 类型
-	if idUUID, err := uuid.Parse(id); err == nil {
-		idTID = idUUID // 直接赋值
-	} else if idInt, err := strconv.ParseUint(id, 10, 64); err == nil {
-		idTID = idInt // 直接赋值
-	}else {
-		idTID = id // 直接赋值
+
+	deleteCtx, err := c.scoped(ctx, DataActDelete)
+	if err != nil {
+		return nil, err
 	}
 
+	// force=true 时物理删除
+	if ctx.Query("force") == "true" {
+		if err := c.Repository.ForceDelete(deleteCtx, idTID); err != nil {
+			return nil, err
+		}
+		return c.Responser.Success(nil), nil
+	}
 
-	// opts := options.NewDeleteOptions()
-	if err := c.Repository.DeleteById(ctx, idTID); err != nil {
+	opts := options.NewDeleteOptions(options.WithDeletedBy(operatorID(ctx)))
+	if err := c.Repository.DeleteById(deleteCtx, idTID, opts); err != nil {
 		return nil, err
 	}
 
 	return c.Responser.Success(nil), nil
 }
 
+// Restore 恢复软删除的实体
+func (c *CrudController[T]) Restore(ctx *gin.Context) (interface{}, error) {
+	idTID, err := c.parseID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteCtx, err := c.scoped(ctx, DataActDelete)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Repository.Restore(deleteCtx, idTID); err != nil {
+		return nil, err
+	}
+
+	return c.reload(ctx, idTID)
+}
+
+// Trash 获取已软删除的实体列表
+func (c *CrudController[T]) Trash(ctx *gin.Context) (interface{}, error) {
+	opts, err := c.BuildQueryOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	listCtx, err := c.scoped(ctx, DataActList)
+	if err != nil {
+		return nil, err
+	}
+	items, total, err := c.Repository.FindTrashed(listCtx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Responser.Pagenation(items, total, opts.Page, opts.PageSize), nil
+}
+
+// History 获取实体的变更历史，按时间倒序分页
+func (c *CrudController[T]) History(ctx *gin.Context) (interface{}, error) {
+	idTID, err := c.parseID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	readCtx, err := c.scoped(ctx, DataActRead)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.Repository.FindById(readCtx, idTID); err != nil {
+		return nil, err
+	}
+	writer := auditWriter()
+	if writer == nil {
+		return nil, errors.New(errors.ErrInternal, "audit writer is not configured")
+	}
+
+	page, pageSize := pagination(ctx)
+	logs, total, err := writer.History(ctx, c.entityName, auditEntityID(idTID), page, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Responser.Pagenation(logs, total, page, pageSize), nil
+}
+
 // BatchCreate 批量创建实体
 func (c *CrudController[T]) BatchCreate(ctx *gin.Context) (interface{}, error) {
 	var entities []T
@@ -179,9 +357,19 @@ func (c *CrudController[T]) BatchCreate(ctx *gin.Context) (interface{}, error) {
 		}
 	}
 
+	// 记录较多或请求 async=true 时转为后台任务
+	opts := batchOptions(ctx, len(entities))
+	if opts.Async {
+		return c.startBatchJob(ctx, types.BatchOperationCreate, len(entities), opts, func(jobCtx context.Context, start, end int) error {
+			return c.Repository.Transaction(jobCtx, func(tx IRepository[T]) error {
+				return tx.BatchCreate(jobCtx, entities[start:end], opts)
+			})
+		}, func(i int) any { return entities[i].GetID() })
+	}
+
 	// 使用事务进行批量创建
 	err := c.Repository.Transaction(ctx, func(tx IRepository[T]) error {
-		return tx.BatchCreate(ctx, entities)
+		return tx.BatchCreate(ctx, entities, opts)
 	})
 
 	if err != nil {
@@ -191,7 +379,7 @@ func (c *CrudController[T]) BatchCreate(ctx *gin.Context) (interface{}, error) {
 	return c.Responser.Success(entities), nil
 }
 
-// BatchUpdate 批量更新实体
+// BatchUpdate 批量更新实体，mode=upsert 时批量插入或更新
 func (c *CrudController[T]) BatchUpdate(ctx *gin.Context) (interface{}, error) {
 	var entities []T
 	if err := ctx.ShouldBindJSON(&entities); err != nil {
@@ -205,9 +393,45 @@ func (c *CrudController[T]) BatchUpdate(ctx *gin.Context) (interface{}, error) {
 		}
 	}
 
+	scope, err := c.dataScope(ctx, DataActUpdate)
+	if err != nil {
+		return nil, err
+	}
+	updateCtx := withDataScope(ctx, c.entityType(), scope)
+
+	// mode=upsert 时按冲突字段插入或更新
+	opts := batchOptions(ctx, len(entities))
+	if ctx.Query("mode") == "upsert" {
+		conflictColumns, updateColumns := splitQueryList(ctx.Query("conflict")), splitQueryList(ctx.Query("update_fields"))
+		if opts.Async {
+			return c.startBatchJob(ctx, types.BatchOperationUpsert, len(entities), opts, func(jobCtx context.Context, start, end int) error {
+				jobCtx = withDataScope(jobCtx, c.entityType(), scope)
+				return c.Repository.Transaction(jobCtx, func(tx IRepository[T]) error {
+					return tx.BatchUpsert(jobCtx, entities[start:end], conflictColumns, updateColumns, opts)
+				})
+			}, func(i int) any { return entities[i].GetID() })
+		}
+		err := c.Repository.Transaction(updateCtx, func(tx IRepository[T]) error {
+			return tx.BatchUpsert(updateCtx, entities, conflictColumns, updateColumns, opts)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return c.Responser.Success(entities), nil
+	}
+
+	if opts.Async {
+		return c.startBatchJob(ctx, types.BatchOperationUpdate, len(entities), opts, func(jobCtx context.Context, start, end int) error {
+			jobCtx = withDataScope(jobCtx, c.entityType(), scope)
+			return c.Repository.Transaction(jobCtx, func(tx IRepository[T]) error {
+				return tx.BatchUpdate(jobCtx, entities[start:end])
+			})
+		}, func(i int) any { return entities[i].GetID() })
+	}
+
 	// 使用事务进行批量更新
-	err := c.Repository.Transaction(ctx, func(tx IRepository[T]) error {
-		return tx.BatchUpdate(ctx, entities)
+	err = c.Repository.Transaction(updateCtx, func(tx IRepository[T]) error {
+		return tx.BatchUpdate(updateCtx, entities)
 	})
 
 	if err != nil {
@@ -228,9 +452,24 @@ func (c *CrudController[T]) BatchDelete(ctx *gin.Context) (interface{}, error) {
 		return nil, errors.New(errors.ErrInvalidParam, "no ids provided")
 	}
 
+	scope, err := c.dataScope(ctx, DataActDelete)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.NewDeleteOptions(options.WithDeletedBy(operatorID(ctx)))
+	if batchOpts := batchOptions(ctx, len(ids)); batchOpts.Async {
+		return c.startBatchJob(ctx, types.BatchOperationDelete, len(ids), batchOpts, func(jobCtx context.Context, start, end int) error {
+			jobCtx = withDataScope(jobCtx, c.entityType(), scope)
+			return c.Repository.Transaction(jobCtx, func(tx IRepository[T]) error {
+				return tx.BatchDelete(jobCtx, ids[start:end], opts)
+			})
+		}, func(i int) any { return ids[i] })
+	}
+
 	// 使用事务进行批量删除
-	err := c.Repository.Transaction(ctx, func(tx IRepository[T]) error {
-		return tx.BatchDelete(ctx, ids)
+	deleteCtx := withDataScope(ctx, c.entityType(), scope)
+	err = c.Repository.Transaction(deleteCtx, func(tx IRepository[T]) error {
+		return tx.BatchDelete(deleteCtx, ids, opts)
 	})
 
 	if err != nil {
@@ -247,7 +486,7 @@ func (c *CrudController[T]) standardRoutes(cache bool, cacheTTL int) []*types.AP
 	if _, ok := c.entity.GetID().(uint); ok {
 		idType = "integer"
 	}
-	return []*types.APIRoute{
+	routes := []*types.APIRoute{
 		{
 			Path:        "/:" + entityName + "_id",
 			Method:      "GET",
@@ -270,6 +509,17 @@ func (c *CrudController[T]) standardRoutes(cache bool, cacheTTL int) []*types.AP
 			Parameters:  c.queryParams(),
 			Cache:       types.Cache{Enable: cache, Key: fmt.Sprintf("%s:%s", c.entityName, "list"), TTL: cacheTTL},
 		},
+		{
+			Path:        "/aggregate",
+			Method:      "GET",
+			Tags:        []string{c.entityName},
+			Summary:     fmt.Sprintf("Aggregate %s", entityName),
+			Description: fmt.Sprintf("Aggregate %s with optional group by, having and filters", entityName),
+			Handler:     c.Aggregate,
+			Response:    AggregateResult{},
+			Parameters:  c.aggregateParams(),
+			Cache:       types.Cache{Enable: cache, Key: fmt.Sprintf("%s:%s", c.entityName, "aggregate"), TTL: cacheTTL},
+		},
 		{
 			Path:        "",
 			Method:      "POST",
@@ -291,7 +541,35 @@ func (c *CrudController[T]) standardRoutes(cache bool, cacheTTL int) []*types.AP
 			Handler:     c.Update,
 			Request:     c.entity,
 			Response:    c.entity,
-			Cache:       types.Cache{Enable: cache, Key: fmt.Sprintf("%s:%s", c.entityName, "update"), TTL: cacheTTL},
+			Parameters: []types.Parameter{
+				{
+					Name:        "If-Match",
+					In:          "header",
+					Description: "ETag returned by GetById, the update is rejected with 412 if the record has changed",
+					Schema:      types.Schema{Type: "string"},
+				},
+			},
+			Cache: types.Cache{Enable: cache, Key: fmt.Sprintf("%s:%s", c.entityName, "update"), TTL: cacheTTL},
+		},
+		{
+			Path:        "/:" + entityName + "_id",
+			PathType:    idType,
+			Method:      "PATCH",
+			Tags:        []string{c.entityName},
+			Summary:     fmt.Sprintf("Patch %s", entityName),
+			Description: fmt.Sprintf("Partially update an existing %s with merge patch (RFC 7396) or JSON patch (RFC 6902)", entityName),
+			Handler:     c.Patch,
+			Request:     c.entity,
+			Response:    c.entity,
+			Parameters: []types.Parameter{
+				{
+					Name:        "If-Match",
+					In:          "header",
+					Description: "ETag returned by GetById, the patch is rejected with 412 if the record has changed",
+					Schema:      types.Schema{Type: "string"},
+				},
+			},
+			Cache: types.Cache{Enable: cache, Key: fmt.Sprintf("%s:%s", c.entityName, "patch"), TTL: cacheTTL},
 		},
 		{
 			Path:        "/:" + entityName + "_id",
@@ -301,7 +579,15 @@ func (c *CrudController[T]) standardRoutes(cache bool, cacheTTL int) []*types.AP
 			Summary:     fmt.Sprintf("Delete %s", entityName),
 			Description: fmt.Sprintf("Delete an existing %s", entityName),
 			Handler:     c.Delete,
-			Cache:       types.Cache{Enable: cache, Key: fmt.Sprintf("%s:%s", c.entityName, "delete"), TTL: cacheTTL},
+			Parameters: []types.Parameter{
+				{
+					Name:        "force",
+					In:          "query",
+					Description: "Permanently delete the record instead of soft deleting it",
+					Schema:      types.Schema{Type: "boolean", Default: "false"},
+				},
+			},
+			Cache: types.Cache{Enable: cache, Key: fmt.Sprintf("%s:%s", c.entityName, "delete"), TTL: cacheTTL},
 		},
 		{
 			Path:        "/batch",
@@ -310,6 +596,7 @@ func (c *CrudController[T]) standardRoutes(cache bool, cacheTTL int) []*types.AP
 			Summary:     fmt.Sprintf("Batch Create %s", entityName),
 			Description: fmt.Sprintf("Create multiple %s records", entityName),
 			Handler:     c.BatchCreate,
+			Parameters:  batchParams(),
 			Request:     []T{},
 			Response:    c.Responser.Success("批量创建成功"),
 			Cache:       types.Cache{Enable: cache, Key: fmt.Sprintf("%s:%s", c.entityName, "batchCreate"), TTL: cacheTTL},
@@ -321,7 +608,7 @@ func (c *CrudController[T]) standardRoutes(cache bool, cacheTTL int) []*types.AP
 			Summary:     fmt.Sprintf("Batch Update %s", c.entityName),
 			Description: fmt.Sprintf("Update multiple %s records", c.entityName),
 			Handler:     c.BatchUpdate,
-			Parameters:  ModeParams(c),
+			Parameters:  append(append(ModeParams(c), upsertParams()...), batchParams()...),
 			Request:     []T{},
 			Response:    c.Responser.Success("批量更新成功"),
 			Cache:       types.Cache{Enable: cache, Key: fmt.Sprintf("%s:%s", c.entityName, "batchUpdate"), TTL: cacheTTL},
@@ -333,9 +620,94 @@ func (c *CrudController[T]) standardRoutes(cache bool, cacheTTL int) []*types.AP
 			Summary:     fmt.Sprintf("Batch Delete %s", entityName),
 			Description: fmt.Sprintf("Delete multiple %s records", entityName),
 			Handler:     c.BatchDelete,
-			Parameters:  ModeParams(c),
+			Parameters:  append(ModeParams(c), batchParams()...),
 			Response:    c.Responser.Success("批量删除成功"),
 			Cache:       types.Cache{Enable: cache, Key: fmt.Sprintf("%s:%s", c.entityName, "batchDelete"), TTL: cacheTTL},
 		},
+		{
+			Path:        "/batch/jobs/:job_id",
+			Method:      "GET",
+			Tags:        []string{c.entityName},
+			Summary:     fmt.Sprintf("Get %s batch job", entityName),
+			Description: "Get progress, per-item failures and result of an asynchronous batch job",
+			Handler:     c.BatchJob,
+			Response:    types.BatchJob{},
+		},
+	}
+
+	// 支持软删除的实体提供回收站与恢复路由
+	if softDeleteMetaOf(reflect.TypeOf(c.entity), c.entity.DBType()).Enabled {
+		routes = append(routes,
+			&types.APIRoute{
+				Path:        "/trash",
+				Method:      "GET",
+				Tags:        []string{c.entityName},
+				Summary:     fmt.Sprintf("List deleted %s", entityName),
+				Description: fmt.Sprintf("Get a list of soft deleted %s with pagination and filters", entityName),
+				Handler:     c.Trash,
+				Response:    []T{},
+				Parameters:  c.queryParams(),
+			},
+			&types.APIRoute{
+				Path:        "/:" + entityName + "_id/restore",
+				PathType:    idType,
+				Method:      "POST",
+				Tags:        []string{c.entityName},
+				Summary:     fmt.Sprintf("Restore %s", entityName),
+				Description: fmt.Sprintf("Restore a soft deleted %s", entityName),
+				Handler:     c.Restore,
+				Response:    c.entity,
+			},
+		)
+	}
+
+	// 启用审计的实体提供变更历史路由
+	if auditEnabled(c.entity) {
+		routes = append(routes, &types.APIRoute{
+			Path:        "/:" + entityName + "_id/history",
+			PathType:    idType,
+			Method:      "GET",
+			Tags:        []string{c.entityName},
+			Summary:     fmt.Sprintf("Get %s history", entityName),
+			Description: fmt.Sprintf("Get the audit trail of a %s with actor, action and changed fields, newest first", entityName),
+			Handler:     c.History,
+			Response:    []types.AuditLog{},
+		})
+	}
+	return routes
+}
+
+// upsertParams 批量 upsert 参数
+func upsertParams() []types.Parameter {
+	return []types.Parameter{
+		{
+			Name:        "mode",
+			In:          "query",
+			Description: "Set to upsert to insert records or update them on conflict",
+			Schema:      types.Schema{Type: "string"},
+		},
+		{
+			Name:        "conflict",
+			In:          "query",
+			Description: "Comma separated conflict fields for upsert, defaults to the primary key",
+			Schema:      types.Schema{Type: "string"},
+		},
+		{
+			Name:        "update_fields",
+			In:          "query",
+			Description: "Comma separated fields updated on conflict, defaults to all fields",
+			Schema:      types.Schema{Type: "string"},
+		},
+	}
+}
+
+// splitQueryList 解析逗号分隔的查询参数
+func splitQueryList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
@@ -0,0 +1,147 @@
+package crud
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
+)
+
+// CursorPage 游标分页结果
+type CursorPage[T ICrudEntity] struct {
+	Items      []T
+	NextCursor string
+	PrevCursor string
+}
+
+// cursorKey 解析后的游标排序键
+type cursorKey struct {
+	Name  string       // 存储字段名
+	Desc  bool         // 是否降序
+	field entityField  // 字段元信息
+	value interface{}  // 游标中的字段值
+	typ   reflect.Type // 字段类型
+}
+
+// cursorKeys 根据排序选项生成游标排序键，并追加ID作为唯一兜底排序
+func cursorKeys(entityType reflect.Type, dbType string, orderBy []string) ([]cursorKey, error) {
+	orders, err := options.ParseOrderBy(orderBy)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidParam, "invalid order_by")
+	}
+	idField, ok := lookupEntityField(entityType, "id")
+	if !ok {
+		return nil, errors.New(errors.ErrInternal, "entity has no id field")
+	}
+
+	keys := make([]cursorKey, 0, len(orders)+1)
+	hasID := false
+	for _, order := range orders {
+		f, ok := lookupEntityField(entityType, order.Field)
+		if !ok {
+			return nil, errors.New(errors.ErrInvalidParam, "invalid order field: "+order.Field)
+		}
+		name := f.StorageName(dbType)
+		if name == idField.StorageName(dbType) {
+			hasID = true
+		}
+		keys = append(keys, cursorKey{Name: name, Desc: order.Desc, field: f, typ: f.Type})
+	}
+	if !hasID {
+		desc := true
+		if len(keys) > 0 {
+			desc = keys[len(keys)-1].Desc
+		}
+		keys = append(keys, cursorKey{Name: idField.StorageName(dbType), Desc: desc, field: idField, typ: idField.Type})
+	}
+	return keys, nil
+}
+
+// decodeCursor 解码游标并将值还原为字段类型
+func decodeCursor(token string, keys []cursorKey) (*options.Cursor, []cursorKey, error) {
+	cursor, err := options.DecodeCursor(token)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errors.ErrInvalidParam, "invalid cursor")
+	}
+	if len(cursor.Keys) != len(keys) {
+		return nil, nil, errors.New(errors.ErrInvalidParam, "cursor does not match order_by")
+	}
+	decoded := make([]cursorKey, len(keys))
+	for i, key := range keys {
+		if cursor.Keys[i] != key.Name {
+			return nil, nil, errors.New(errors.ErrInvalidParam, "cursor does not match order_by")
+		}
+		ptr := reflect.New(key.typ)
+		if err := json.Unmarshal(cursor.Values[i], ptr.Interface()); err != nil {
+			return nil, nil, errors.Wrap(err, errors.ErrInvalidParam, "invalid cursor")
+		}
+		key.value = ptr.Elem().Interface()
+		decoded[i] = key
+	}
+	return cursor, decoded, nil
+}
+
+// encodeCursor 根据实体生成游标
+func encodeCursor(entity any, keys []cursorKey, backward bool) (string, error) {
+	cursor := &options.Cursor{
+		Keys:     make([]string, len(keys)),
+		Values:   make([]json.RawMessage, len(keys)),
+		Backward: backward,
+	}
+	v := reflect.ValueOf(entity)
+	for i, key := range keys {
+		fv, ok := fieldValue(v, key.field.Index)
+		var raw []byte
+		var err error
+		if ok {
+			raw, err = json.Marshal(fv.Interface())
+		} else {
+			raw, err = json.Marshal(nil)
+		}
+		if err != nil {
+			return "", err
+		}
+		cursor.Keys[i] = key.Name
+		cursor.Values[i] = raw
+	}
+	return options.EncodeCursor(cursor)
+}
+
+// buildCursorPage 根据查询结果构建游标分页
+// items 为按查询方向获取的 limit+1 条记录
+func buildCursorPage[T ICrudEntity](items []T, keys []cursorKey, limit int, cursor *options.Cursor) (*CursorPage[T], error) {
+	backward := cursor != nil && cursor.Backward
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	// 向前翻页时结果为逆序，需反转
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	page := &CursorPage[T]{Items: items}
+	if len(items) == 0 {
+		return page, nil
+	}
+	var err error
+	if (!backward && hasMore) || backward {
+		if page.NextCursor, err = encodeCursor(items[len(items)-1], keys, false); err != nil {
+			return nil, err
+		}
+	}
+	if (backward && hasMore) || (!backward && cursor != nil) {
+		if page.PrevCursor, err = encodeCursor(items[0], keys, true); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// keysetAscending 判断键在当前翻页方向上是否为升序比较
+func keysetAscending(key cursorKey, backward bool) bool {
+	return key.Desc == backward
+}
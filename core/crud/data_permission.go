@@ -0,0 +1,314 @@
+package crud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 数据权限操作
+const (
+	DataActList   = "list"
+	DataActRead   = "read"
+	DataActUpdate = "update"
+	DataActDelete = "delete"
+)
+
+// IDataPermission 数据权限，返回当前请求在 act 操作下的行过滤规则
+// 规则之间为或关系：* 表示不限制，没有规则表示无权访问任何记录
+// 规则格式为 field op value，多个条件以 and 连接，op 支持 =、!=、>、>=、<、<=、in、not in，
+// value 为 $name 时取自 gin 上下文中的同名值（如认证中间件写入的 user_id），否则为字面量，in 的字面量以逗号分隔
+type IDataPermission interface {
+	DataRules(ctx *gin.Context, act string) ([]string, error)
+}
+
+// DataPolicyFunc Go 函数形式的数据权限
+type DataPolicyFunc func(ctx *gin.Context, act string) ([]string, error)
+
+// DataRules 返回数据权限规则
+func (f DataPolicyFunc) DataRules(ctx *gin.Context, act string) ([]string, error) {
+	return f(ctx, act)
+}
+
+// IDataRuleProvider 数据权限规则来源，fast_casbin.CasbinMaker 实现此接口
+type IDataRuleProvider interface {
+	DataRules(sub, obj, act string) ([]string, error)
+}
+
+// CasbinDataPolicy 使用 Casbin 数据权限策略（p2 = sub, obj, act, rule），主体为认证中间件写入的 username
+func CasbinDataPolicy(provider IDataRuleProvider, obj string) IDataPermission {
+	return DataPolicyFunc(func(ctx *gin.Context, act string) ([]string, error) {
+		username := ctx.GetString("username")
+		if username == "" {
+			return nil, errors.New(errors.ErrUnauthorized, "user not found in context")
+		}
+		return provider.DataRules(username, obj, act)
+	})
+}
+
+// dataRulePattern 单个条件：field op value
+var dataRulePattern = regexp.MustCompile(`(?i)^\s*([A-Za-z_][A-Za-z0-9_]*)\s*(!=|>=|<=|=|>|<|not\s+in|in)\s*(.+?)\s*$`)
+
+// dataRuleAnd 条件分隔符
+var dataRuleAnd = regexp.MustCompile(`(?i)\s+and\s+`)
+
+// dataRuleOperators 规则操作符与过滤操作符映射
+var dataRuleOperators = map[string]string{
+	"=":  options.OpEq,
+	"!=": options.OpNeq,
+	">":  options.OpGt,
+	">=": options.OpGte,
+	"<":  options.OpLt,
+	"<=": options.OpLte,
+	"in": options.OpIn,
+}
+
+// compileDataRules 将数据权限规则编译为过滤表达式，返回 nil 表示不限制
+// 引用了上下文中不存在的值的规则被忽略，所有规则均被忽略时无权访问
+func compileDataRules(ctx *gin.Context, entityType reflect.Type, dbType string, rules []string) (*options.FilterExpr, error) {
+	grants := make([]options.FilterExpr, 0, len(rules))
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "*" {
+			return nil, nil
+		}
+		conditions := make([]options.FilterExpr, 0)
+		granted := true
+		for _, part := range dataRuleAnd.Split(rule, -1) {
+			condition, ok, err := compileDataCondition(ctx, entityType, dbType, part)
+			if err != nil {
+				return nil, errors.Wrap(err, errors.ErrInternal, "invalid data permission rule: "+rule)
+			}
+			if !ok {
+				granted = false
+				break
+			}
+			conditions = append(conditions, options.FilterExpr{Condition: &condition})
+		}
+		if !granted {
+			continue
+		}
+		if len(conditions) == 1 {
+			grants = append(grants, conditions[0])
+		} else {
+			grants = append(grants, options.FilterExpr{And: conditions})
+		}
+	}
+	switch len(grants) {
+	case 0:
+		return nil, errors.New(errors.ErrForbidden, "permission denied")
+	case 1:
+		return &grants[0], nil
+	}
+	return &options.FilterExpr{Or: grants}, nil
+}
+
+// compileDataCondition 编译单个条件，ok 为 false 表示引用的上下文值不存在
+func compileDataCondition(ctx *gin.Context, entityType reflect.Type, dbType string, raw string) (options.Condition, bool, error) {
+	matches := dataRulePattern.FindStringSubmatch(raw)
+	if matches == nil {
+		return options.Condition{}, false, fmt.Errorf("malformed condition %q", raw)
+	}
+	field, ok := lookupEntityField(entityType, matches[1])
+	if !ok || isRelationType(field.Type) {
+		return options.Condition{}, false, fmt.Errorf("unknown field %s", matches[1])
+	}
+	op := strings.ToLower(strings.Join(strings.Fields(matches[2]), " "))
+	filterOp, ok := dataRuleOperators[op]
+	if op == "not in" {
+		filterOp, ok = options.OpNin, true
+	}
+	if !ok {
+		return options.Condition{}, false, fmt.Errorf("unsupported operator %s", op)
+	}
+
+	var value interface{} = matches[3]
+	if name, isVar := strings.CutPrefix(matches[3], "$"); isVar {
+		v, exists := ctx.Get(name)
+		if !exists || v == nil {
+			return options.Condition{}, false, nil
+		}
+		value = dataRuleValue(v)
+	}
+	converted, err := filterNodeConditionValue(field, filterOp, value)
+	if err != nil {
+		return options.Condition{}, false, err
+	}
+	return options.Condition{Field: field.StorageName(dbType), Op: filterOp, Value: converted}, true, nil
+}
+
+// dataRuleValue 将上下文值转换为字符串或字符串列表，JSON 解析出的整数不使用科学计数法
+func dataRuleValue(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		values := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, dataRuleValue(rv.Index(i).Interface()))
+		}
+		return values
+	}
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// dataScopeKey 上下文中数据权限条件的键，按实体类型区分
+type dataScopeKey struct{ entityType reflect.Type }
+
+// withDataScope 返回携带实体数据权限条件的上下文，仓储读写时追加该条件，expr 为 nil 时返回原上下文
+func withDataScope(ctx context.Context, entityType reflect.Type, expr *options.FilterExpr) context.Context {
+	if expr == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, dataScopeKey{entityType}, expr)
+}
+
+// dataScopeOf 获取上下文中实体的数据权限条件
+func dataScopeOf(ctx context.Context, entityType reflect.Type) *options.FilterExpr {
+	if ctx == nil {
+		return nil
+	}
+	expr, _ := ctx.Value(dataScopeKey{entityType}).(*options.FilterExpr)
+	return expr
+}
+
+// mongoDataScope 为过滤条件追加数据权限条件
+func mongoDataScope(ctx context.Context, entityType reflect.Type, filter interface{}) interface{} {
+	if expr := dataScopeOf(ctx, entityType); expr != nil {
+		return bson.M{"$and": bson.A{filter, mongoFilterExpr(*expr)}}
+	}
+	return filter
+}
+
+// dataScopeMatches 判断文档（以存储字段名为键）是否满足数据权限条件，用于校验更新后的值仍在可编辑范围内
+func dataScopeMatches(expr options.FilterExpr, doc map[string]interface{}) bool {
+	switch {
+	case expr.Condition != nil:
+		return dataConditionMatches(*expr.Condition, doc[expr.Condition.Field])
+	case expr.Not != nil:
+		return !dataScopeMatches(*expr.Not, doc)
+	case len(expr.And) > 0:
+		for _, child := range expr.And {
+			if !dataScopeMatches(child, doc) {
+				return false
+			}
+		}
+		return true
+	case len(expr.Or) > 0:
+		for _, child := range expr.Or {
+			if dataScopeMatches(child, doc) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dataConditionMatches 判断字段值是否满足数据权限规则的单个条件
+func dataConditionMatches(c options.Condition, value interface{}) bool {
+	switch c.Op {
+	case options.OpIn, options.OpNin:
+		items, _ := c.Value.([]interface{})
+		found := false
+		for _, item := range items {
+			if cmp, ok := compareScopeValues(value, item); ok && cmp == 0 {
+				found = true
+				break
+			}
+		}
+		return found == (c.Op == options.OpIn)
+	case options.OpNeq:
+		cmp, ok := compareScopeValues(value, c.Value)
+		return !ok || cmp != 0
+	}
+	cmp, ok := compareScopeValues(value, c.Value)
+	if !ok {
+		return false
+	}
+	switch c.Op {
+	case options.OpEq:
+		return cmp == 0
+	case options.OpGt:
+		return cmp > 0
+	case options.OpGte:
+		return cmp >= 0
+	case options.OpLt:
+		return cmp < 0
+	case options.OpLte:
+		return cmp <= 0
+	}
+	return false
+}
+
+// compareScopeValues 比较字段值与规则值，数值按大小、时间按先后、其余按字符串比较；任一值为空时只判断相等
+func compareScopeValues(a, b interface{}) (int, bool) {
+	a, b = scopeValue(a), scopeValue(b)
+	if a == nil || b == nil {
+		if a == nil && b == nil {
+			return 0, true
+		}
+		return 1, false
+	}
+	if sa, ok := a.(string); ok {
+		if sb, ok := b.(string); ok {
+			return strings.Compare(sa, sb), true
+		}
+	}
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := b.(time.Time); ok {
+			return ta.Compare(tb), true
+		}
+	}
+	if fa, ok := toFloat64(a); ok {
+		if fb, ok := toFloat64(b); ok {
+			switch {
+			case fa < fb:
+				return -1, true
+			case fa > fb:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b)), true
+}
+
+// scopeValue 统一字段值的表示：解引用指针，ObjectID 转为十六进制字符串，bson 时间转为 time.Time
+func scopeValue(v interface{}) interface{} {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		v = rv.Elem().Interface()
+	}
+	switch value := v.(type) {
+	case primitive.ObjectID:
+		return value.Hex()
+	case primitive.DateTime:
+		return value.Time()
+	case uint:
+		return float64(value)
+	case uint8:
+		return float64(value)
+	case uint16:
+		return float64(value)
+	case uint32:
+		return float64(value)
+	case int8:
+		return float64(value)
+	case int16:
+		return float64(value)
+	}
+	return v
+}
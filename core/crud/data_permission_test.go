@@ -0,0 +1,178 @@
+package crud
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kruily/gofastcrud/config"
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/kruily/gofastcrud/utils"
+	"github.com/qiniu/qmgo"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// testDocument 数据权限测试用文档
+type testDocument struct {
+	*BaseEntity
+	Title        string `json:"title"`
+	OwnerID      uint64 `json:"owner_id" filter:"eq"`
+	DepartmentID uint64 `json:"department_id"`
+	Status       string `json:"status"`
+}
+
+func (*testDocument) TableName() string {
+	return "test_documents"
+}
+
+func (d *testDocument) Init() {
+	if d.BaseEntity == nil {
+		d.BaseEntity = &BaseEntity{}
+	}
+}
+
+func TestDataPermission(t *testing.T) {
+	_, repo := setupTestRepository(t, &testDocument{})
+	for _, doc := range []*testDocument{
+		{Title: "a", OwnerID: 1, DepartmentID: 10, Status: "draft"},
+		{Title: "b", OwnerID: 2, DepartmentID: 10, Status: "published"},
+		{Title: "c", OwnerID: 2, DepartmentID: 20, Status: "draft"},
+		{Title: "d", OwnerID: 3, DepartmentID: 30, Status: "published"},
+	} {
+		doc.BaseEntity = &BaseEntity{}
+		require.NoError(t, repo.Create(context.Background(), doc))
+	}
+
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Request = httptest.NewRequest("GET", "/documents", nil)
+	ginCtx.Set("user_id", float64(1))
+	ginCtx.Set("departments", []uint64{20, 30})
+
+	titles := func(rules ...string) []string {
+		expr, err := compileDataRules(ginCtx, repo.entityType, DB_TYPE_GORM, rules)
+		require.NoError(t, err)
+		items, err := repo.Find(withDataScope(ginCtx, repo.entityType, expr), &testDocument{}, options.NewQueryOptions(options.WithOrderBy("id asc")))
+		require.NoError(t, err)
+		result := make([]string, 0, len(items))
+		for _, item := range items {
+			result = append(result, item.Title)
+		}
+		return result
+	}
+
+	require.Equal(t, []string{"a"}, titles("owner_id = $user_id"))
+	require.Equal(t, []string{"a", "c", "d"}, titles("owner_id = $user_id", "department_id in $departments"))
+	require.Equal(t, []string{"d"}, titles("department_id in $departments and status = published"))
+	require.Equal(t, []string{"b", "c"}, titles("owner_id not in 1,3"))
+	require.Equal(t, []string{"a", "b", "c", "d"}, titles("owner_id = $user_id", "*"))
+	// 引用不存在的上下文值的规则被忽略
+	require.Equal(t, []string{"a"}, titles("owner_id = $manager_id", "owner_id = $user_id"))
+
+	_, err := compileDataRules(ginCtx, repo.entityType, DB_TYPE_GORM, []string{"owner_id = $manager_id"})
+	require.True(t, errors.Is(err, errors.ErrForbidden))
+	_, err = compileDataRules(ginCtx, repo.entityType, DB_TYPE_GORM, nil)
+	require.True(t, errors.Is(err, errors.ErrForbidden))
+	_, err = compileDataRules(ginCtx, repo.entityType, DB_TYPE_GORM, []string{"secret = 1"})
+	require.True(t, errors.Is(err, errors.ErrInternal))
+
+	// 不允许的记录既不可读取也不可修改
+	expr, err := compileDataRules(ginCtx, repo.entityType, DB_TYPE_GORM, []string{"owner_id = $user_id"})
+	require.NoError(t, err)
+	scoped := withDataScope(ginCtx, repo.entityType, expr)
+	_, err = repo.FindById(scoped, uint64(2))
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	err = repo.BatchUpdate(scoped, []*testDocument{{BaseEntity: &BaseEntity{ID: 2}, Title: "x", OwnerID: 1}})
+	require.True(t, errors.Is(err, errors.ErrForbidden))
+
+	// 更新不能将记录移出可编辑范围
+	err = repo.Update(scoped, &testDocument{BaseEntity: &BaseEntity{ID: 1}}, map[string]interface{}{"owner_id": 2})
+	require.True(t, errors.Is(err, errors.ErrForbidden))
+	err = repo.BatchUpdate(scoped, []*testDocument{{BaseEntity: &BaseEntity{ID: 1}, Title: "a", OwnerID: 2}})
+	require.True(t, errors.Is(err, errors.ErrForbidden))
+	own, err := repo.FindById(context.Background(), uint64(1))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), own.OwnerID)
+	require.Equal(t, "a", own.Title)
+	require.NoError(t, repo.Update(scoped, &testDocument{BaseEntity: &BaseEntity{ID: 1}}, map[string]interface{}{"title": "a2"}))
+
+	// MongoDB 在写入前按规则校验新值，文档以 bson 名称为键
+	mongoExpr, err := compileDataRules(ginCtx, repo.entityType, DB_TYPE_MONGODB, []string{"owner_id = $user_id", "department_id in $departments and status = published"})
+	require.NoError(t, err)
+	require.True(t, dataScopeMatches(*mongoExpr, map[string]interface{}{"ownerid": int64(1), "departmentid": int64(10)}))
+	require.True(t, dataScopeMatches(*mongoExpr, map[string]interface{}{"ownerid": int64(2), "departmentid": int64(30), "status": "published"}))
+	require.False(t, dataScopeMatches(*mongoExpr, map[string]interface{}{"ownerid": int64(2), "departmentid": int64(30), "status": "draft"}))
+	require.False(t, dataScopeMatches(*mongoExpr, map[string]interface{}{"departmentid": int64(10)}))
+
+	// 聚合接口只统计允许的记录，控制器分页参数需要配置
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("pagenation:\n  default_page_size: 10\n  max_page_size: 100\n"), 0o644))
+	t.Setenv("CONFIG_PATH", configPath)
+	require.NoError(t, config.CONFIG_MANAGER.LoadConfig())
+	controller := &BlankController[*testDocument]{
+		Repository: &Repository[*testDocument]{crudRepo: repo, entityType: repo.entityType},
+		Responser:  &utils.DefaultResponseHandler{},
+		entity:     &testDocument{},
+		entityName: "testDocument",
+		permissions: []IDataPermission{DataPolicyFunc(func(ctx *gin.Context, act string) ([]string, error) {
+			return []string{"owner_id = $user_id"}, nil
+		})},
+	}
+	ginCtx.Request = httptest.NewRequest("GET", "/documents/aggregate?func=sum&field=owner_id", nil)
+	result, err := controller.Aggregate(ginCtx)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, *result.(utils.Response).Data.(*AggregateResult).Value)
+
+	// 只读控制器同样只返回允许的记录
+	readOnly := &OnlyReadController[*testDocument]{BlankController: controller}
+	ginCtx.Request = httptest.NewRequest("GET", "/documents", nil)
+	result, err = readOnly.List(ginCtx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.(utils.Response).Data.(utils.PagenationResponse).Total)
+	ginCtx.Params = gin.Params{{Key: "testDocument_id", Value: "2"}}
+	_, err = readOnly.GetById(ginCtx)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	require.NoError(t, repo.BatchDelete(scoped, []any{uint64(1), uint64(2)}))
+	count, err := repo.Count(context.Background(), &testDocument{})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+}
+
+func TestMongoUpsertDataScope(t *testing.T) {
+	uri := os.Getenv(testMongoTransactionURI)
+	if uri == "" {
+		t.Skipf("%s is not set, skipping mongodb tests", testMongoTransactionURI)
+	}
+	ctx := context.Background()
+	client, err := qmgo.NewClient(ctx, &qmgo.Config{Uri: uri})
+	require.NoError(t, err)
+	defer client.Close(ctx)
+	db := client.Database("gofastcrud_test")
+	require.NoError(t, db.Collection((&testMongoMember{}).TableName()).DropCollection(ctx))
+
+	repo := newMongoRepository(client, db, &testMongoMember{})
+	require.NoError(t, repo.BatchCreate(ctx, []*testMongoMember{{UserName: "tom", Age: 30}, {UserName: "amy", Age: 10}}))
+
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Request = httptest.NewRequest("GET", "/members", nil)
+	expr, err := compileDataRules(ginCtx, repo.entityType, DB_TYPE_MONGODB, []string{"age >= 18"})
+	require.NoError(t, err)
+	scoped := withDataScope(ctx, repo.entityType, expr)
+
+	// 冲突文档在范围外时拒绝覆盖
+	err = repo.Upsert(scoped, &testMongoMember{UserName: "amy", Age: 40}, []string{"userName"}, nil)
+	require.True(t, errors.Is(err, errors.ErrForbidden))
+	amy, err := repo.FindOne(ctx, "userName = ?", "amy")
+	require.NoError(t, err)
+	require.Equal(t, 10, amy.Age)
+
+	require.NoError(t, repo.Upsert(scoped, &testMongoMember{UserName: "tom", Age: 31}, []string{"userName"}, nil))
+	tom, err := repo.FindOne(ctx, "userName = ?", "tom")
+	require.NoError(t, err)
+	require.Equal(t, 31, tom.Age)
+}
@@ -0,0 +1,25 @@
+package crud
+
+import (
+	"fmt"
+
+	"github.com/kruily/gofastcrud/core/database"
+)
+
+// IDatasource 指定实体使用的数据源，返回配置中的 name，优先于 ControllerFactory.Datasource
+type IDatasource interface {
+	Datasource() string
+}
+
+// entityDatabase 返回实体使用的数据源，实体未指定时使用 db；数据源未配置时返回错误
+func entityDatabase(db *database.Database, entity any) (*database.Database, error) {
+	source, ok := entity.(IDatasource)
+	if !ok || db == nil {
+		return db, nil
+	}
+	resolved, err := db.Datasource(source.Datasource())
+	if err != nil {
+		return nil, fmt.Errorf("datasource of entity %T: %w", entity, err)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,112 @@
+package crud
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kruily/gofastcrud/config"
+	"github.com/kruily/gofastcrud/core/database"
+	"github.com/stretchr/testify/require"
+)
+
+// testReport 数据源测试用报表，存储在 analytics 数据源
+type testReport struct {
+	*BaseEntity
+	Title string `json:"title"`
+}
+
+func (*testReport) TableName() string {
+	return "test_reports"
+}
+
+func (r *testReport) Init() {
+	if r.BaseEntity == nil {
+		r.BaseEntity = &BaseEntity{}
+	}
+}
+
+func (*testReport) Datasource() string {
+	return "analytics"
+}
+
+// testMemo 数据源测试用备忘，存储在默认数据源
+type testMemo struct {
+	*BaseEntity
+	Content string `json:"content"`
+}
+
+func (*testMemo) TableName() string {
+	return "test_memos"
+}
+
+func (m *testMemo) Init() {
+	if m.BaseEntity == nil {
+		m.BaseEntity = &BaseEntity{}
+	}
+}
+
+// testOrphanMemo 引用未配置数据源的实体
+type testOrphanMemo struct {
+	testMemo
+}
+
+func (*testOrphanMemo) Datasource() string {
+	return "missing"
+}
+
+func TestDatasource(t *testing.T) {
+	dir := t.TempDir()
+	db := database.New([]config.DatabaseConfig{
+		{Driver: "sqlite", Database: filepath.Join(dir, "main.db")},
+		{Name: "analytics", Driver: "sqlite", Database: filepath.Join(dir, "analytics.db")},
+		{Name: "archive", Driver: "sqlite", Database: filepath.Join(dir, "archive.db")},
+	})
+	defer db.Close()
+	require.Equal(t, []string{database.DefaultDatasource, "analytics", "archive"}, db.Datasources())
+	analytics, err := db.Datasource("analytics")
+	require.NoError(t, err)
+	archive, err := db.Datasource("archive")
+	require.NoError(t, err)
+	_, err = db.Datasource("missing")
+	require.Error(t, err)
+
+	// 按数据源迁移：实体声明的数据源优先于工厂指定的数据源
+	factory := &ControllerFactory{db: db}
+	report, memo, archived := &testReport{}, &testMemo{}, &testMemo{}
+	report.Init()
+	memo.Init()
+	archived.Init()
+	factory.addModel(memo)
+	factory.Datasource("archive").addModel(report)
+	factory.Datasource("archive").addModel(archived)
+	require.NoError(t, factory.Migrate())
+	require.True(t, db.DB().Migrator().HasTable("test_memos"))
+	require.False(t, db.DB().Migrator().HasTable("test_reports"))
+	require.True(t, analytics.DB().Migrator().HasTable("test_reports"))
+	require.True(t, archive.DB().Migrator().HasTable("test_memos"))
+
+	// 仓储写入实体声明的数据源
+	ctx := context.Background()
+	require.NoError(t, NewRepository(db, report).Create(ctx, &testReport{BaseEntity: &BaseEntity{}, Title: "weekly"}))
+	var count int64
+	require.NoError(t, analytics.DB().Table("test_reports").Count(&count).Error)
+	require.Equal(t, int64(1), count)
+
+	require.NoError(t, NewRepository(archive, memo).Create(ctx, &testMemo{BaseEntity: &BaseEntity{}, Content: "old"}))
+	require.NoError(t, archive.DB().Table("test_memos").Count(&count).Error)
+	require.Equal(t, int64(1), count)
+	require.NoError(t, db.DB().Table("test_memos").Count(&count).Error)
+	require.Equal(t, int64(0), count)
+
+	// 未配置的数据源在注册时记录错误，由 Migrate 返回
+	broken := &ControllerFactory{db: db}
+	orphan := &testOrphanMemo{}
+	orphan.Init()
+	broken.addModel(orphan)
+	broken.Datasource("unknown").addModel(memo)
+	err = broken.Migrate()
+	require.ErrorContains(t, err, "missing")
+	require.ErrorContains(t, err, "unknown")
+	require.NotPanics(t, func() { NewRepository(db, orphan) })
+}
@@ -0,0 +1,141 @@
+package crud
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// entityField 实体字段元信息
+type entityField struct {
+	Name   string            // Go 字段名
+	JSON   string            // json 名称
+	Column string            // gorm 列名
+	BSON   string            // bson 名称
+	Type   reflect.Type      // 字段类型
+	Index  []int             // 反射索引路径（包含嵌入结构体）
+	Tag    reflect.StructTag // 字段标签
+}
+
+// StorageName 根据数据库类型返回字段存储名称
+func (f entityField) StorageName(dbType string) string {
+	if dbType == DB_TYPE_MONGODB {
+		return f.BSON
+	}
+	return f.Column
+}
+
+var (
+	entityFieldsCache sync.Map
+	namingStrategy    = schema.NamingStrategy{}
+	timeType          = reflect.TypeOf(time.Time{})
+)
+
+// entityFields 获取实体的所有字段元信息（展开嵌入结构体）
+func entityFields(entityType reflect.Type) []entityField {
+	if entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+	if cached, ok := entityFieldsCache.Load(entityType); ok {
+		return cached.([]entityField)
+	}
+	fields := collectEntityFields(entityType, nil)
+	entityFieldsCache.Store(entityType, fields)
+	return fields
+}
+
+// collectEntityFields 递归收集字段
+func collectEntityFields(t reflect.Type, parent []int) []entityField {
+	fields := make([]entityField, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		index := append(append([]int{}, parent...), i)
+		fieldType := field.Type
+		if field.Anonymous {
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct && fieldType != timeType {
+				fields = append(fields, collectEntityFields(fieldType, index)...)
+				continue
+			}
+		}
+		gormTag := field.Tag.Get("gorm")
+		if gormTag == "-" {
+			continue
+		}
+		ef := entityField{
+			Name:   field.Name,
+			JSON:   field.Name,
+			Column: namingStrategy.ColumnName("", field.Name),
+			BSON:   strings.ToLower(field.Name),
+			Type:   field.Type,
+			Index:  index,
+			Tag:    field.Tag,
+		}
+		if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" {
+			ef.JSON = name
+		}
+		if name := strings.Split(field.Tag.Get("bson"), ",")[0]; name != "" && name != "-" {
+			ef.BSON = name
+		}
+		if column := gormTagSetting(field.Tag, "column"); column != "" {
+			ef.Column = column
+		}
+		fields = append(fields, ef)
+	}
+	return fields
+}
+
+// gormTagSetting 读取 gorm 标签中的设置项，如 column、foreignKey
+func gormTagSetting(tag reflect.StructTag, key string) string {
+	for _, setting := range strings.Split(tag.Get("gorm"), ";") {
+		kv := strings.SplitN(setting, ":", 2)
+		if len(kv) == 2 && strings.EqualFold(strings.TrimSpace(kv[0]), key) {
+			return strings.TrimSpace(kv[1])
+		}
+	}
+	return ""
+}
+
+// lookupEntityField 根据 json 名称、列名、bson 名称或 Go 字段名查找字段
+func lookupEntityField(entityType reflect.Type, name string) (entityField, bool) {
+	fields := entityFields(entityType)
+	for _, f := range fields {
+		if f.JSON == name {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if f.Column == name || f.BSON == name || f.Name == name {
+			return f, true
+		}
+	}
+	return entityField{}, false
+}
+
+// fieldValue 按索引路径取值，遇到空指针返回 false
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	for _, i := range index {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
@@ -0,0 +1,202 @@
+package crud
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/kruily/gofastcrud/core/crud/module"
+	"github.com/kruily/gofastcrud/core/di"
+)
+
+// 领域事件动作，事件名称为 <实体名>.<动作>，实体名为首字母小写的类型名，如 book.created
+const (
+	EventCreated       = "created"
+	EventUpdated       = "updated"
+	EventUpserted      = "upserted"
+	EventDeleted       = "deleted"
+	EventForceDeleted  = "force_deleted"
+	EventRestored      = "restored"
+	EventBatchCreated  = "batch_created"
+	EventBatchUpdated  = "batch_updated"
+	EventBatchUpserted = "batch_upserted"
+	EventBatchDeleted  = "batch_deleted"
+)
+
+// EventMode 领域事件发布方式
+type EventMode string
+
+const (
+	// EventModeAsync 异步发布（默认），事务中的写操作在提交后发布，处理器错误不影响写操作
+	EventModeAsync EventMode = "async"
+	// EventModeSync 写入后同步发布，处理器返回错误时操作返回该错误，事务中的操作随之回滚
+	EventModeSync EventMode = "sync"
+	// EventModeOff 不发布领域事件
+	EventModeOff EventMode = "off"
+)
+
+// IEventEntity 自定义领域事件发布方式的实体，未实现时异步发布
+type IEventEntity interface {
+	EventMode() EventMode
+}
+
+// EntityEvent 实体领域事件，通过 app.WithEventBus 注册的事件总线发布
+type EntityEvent struct {
+	Name       string                 `json:"name"`              // 事件名称，如 book.created
+	Entity     string                 `json:"entity"`            // 实体名称
+	Action     string                 `json:"action"`            // 动作
+	ID         any                    `json:"id,omitempty"`      // 单条操作的实体ID
+	IDs        []any                  `json:"ids,omitempty"`     // 批量操作的实体ID
+	Data       any                    `json:"data,omitempty"`    // 写入后的实体或实体列表，删除时为删除前的实体
+	Changes    map[string]interface{} `json:"changes,omitempty"` // 按字段更新时的更新字段
+	Actor      string                 `json:"actor,omitempty"`
+	RequestID  string                 `json:"request_id,omitempty"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// EventName 事件名称
+func (e *EntityEvent) EventName() string {
+	return e.Name
+}
+
+// EventName 返回实体的领域事件名称，用于订阅
+func EventName(entity any, action string) string {
+	entityType := reflect.TypeOf(entity)
+	if entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+	return eventEntityName(entityType.Name()) + "." + action
+}
+
+// eventEntityName 事件中的实体名称
+func eventEntityName(typeName string) string {
+	if typeName == "" {
+		return typeName
+	}
+	return strings.ToLower(typeName[:1]) + typeName[1:]
+}
+
+// eventBus 获取事件总线，未注册 EventBusService 时返回 nil
+func eventBus() module.IEventBus {
+	if bus, err := di.SINGLE().ResolveSingleton(module.EventBusService); err == nil {
+		if bus, ok := bus.(module.IEventBus); ok {
+			return bus
+		}
+	}
+	return nil
+}
+
+// eventModeOf 实体的领域事件发布方式
+func eventModeOf(entity any) EventMode {
+	if e, ok := entity.(IEventEntity); ok && e.EventMode() != "" {
+		return e.EventMode()
+	}
+	return EventModeAsync
+}
+
+// publishAsync 异步发布事件，处理器使用脱离请求取消的上下文
+func publishAsync(ctx context.Context, events ...*EntityEvent) {
+	bus := eventBus()
+	if bus == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	for _, event := range events {
+		bus.PublishAsync(ctx, event)
+	}
+}
+
+// publishing 是否需要发布领域事件
+func (r *Repository[T]) publishing() bool {
+	if r.events == EventModeOff {
+		return false
+	}
+	return eventBus() != nil || r.outboxing()
+}
+
+// outboxing 异步事件是否写入发件箱，仅支持与发件箱使用同一 gorm 实例的仓储
+func (r *Repository[T]) outboxing() bool {
+	if r.events != EventModeAsync {
+		return false
+	}
+	outbox := eventOutbox()
+	if outbox == nil {
+		return false
+	}
+	store, ok := r.crudRepo.(outboxStore)
+	return ok && outbox.covers(store.outboxDB())
+}
+
+// atomic 写操作是否需要在事务中执行，使写入发件箱的事件与数据变更一同提交
+func (r *Repository[T]) atomic() bool {
+	return r.pending == nil && r.outboxing()
+}
+
+// transact 在事务中使用仓储副本执行写操作
+func (r *Repository[T]) transact(ctx context.Context, fn func(tx *Repository[T]) error) error {
+	return r.Transaction(ctx, func(tx IRepository[T]) error {
+		return fn(tx.(*Repository[T]))
+	})
+}
+
+// emit 发布单条记录的领域事件
+func (r *Repository[T]) emit(ctx context.Context, action string, id any, data any, changes map[string]interface{}) error {
+	if !r.publishing() {
+		return nil
+	}
+	event := r.newEvent(ctx, action)
+	event.ID, event.Data, event.Changes = id, data, changes
+	return r.publish(ctx, event)
+}
+
+// emitBatch 发布批量操作的领域事件
+func (r *Repository[T]) emitBatch(ctx context.Context, action string, ids []any, data any) error {
+	if !r.publishing() {
+		return nil
+	}
+	event := r.newEvent(ctx, action)
+	event.IDs, event.Data = ids, data
+	return r.publish(ctx, event)
+}
+
+// newEvent 创建领域事件
+func (r *Repository[T]) newEvent(ctx context.Context, action string) *EntityEvent {
+	entity := eventEntityName(r.entityType.Name())
+	return &EntityEvent{
+		Name:       entity + "." + action,
+		Entity:     entity,
+		Action:     action,
+		Actor:      auditActor(ctx),
+		RequestID:  auditRequestID(ctx),
+		OccurredAt: time.Now(),
+	}
+}
+
+// publish 按发布方式发布事件，启用发件箱时异步事件写入发件箱，否则在事务中暂存到提交后
+func (r *Repository[T]) publish(ctx context.Context, event *EntityEvent) error {
+	if r.events == EventModeSync {
+		if bus := eventBus(); bus != nil {
+			return bus.Publish(ctx, event)
+		}
+		return nil
+	}
+	if r.outboxing() {
+		return eventOutbox().store(ctx, r.crudRepo.(outboxStore), event)
+	}
+	if r.pending != nil {
+		r.pending.events = append(r.pending.events, event)
+		return nil
+	}
+	publishAsync(ctx, event)
+	return nil
+}
+
+// entityIDs 实体ID列表
+func entityIDs[T ICrudEntity](entities []T) []any {
+	ids := make([]any, 0, len(entities))
+	for _, entity := range entities {
+		ids = append(ids, entity.GetID())
+	}
+	return ids
+}
@@ -0,0 +1,118 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/kruily/gofastcrud/core/crud/module"
+	"github.com/kruily/gofastcrud/core/di"
+	"github.com/kruily/gofastcrud/eventbus"
+	"github.com/stretchr/testify/require"
+)
+
+// testTicket 领域事件测试用工单，异步发布
+type testTicket struct {
+	*BaseEntity
+	Title string `json:"title"`
+}
+
+func (*testTicket) TableName() string {
+	return "test_tickets"
+}
+
+func (t *testTicket) Init() {
+	if t.BaseEntity == nil {
+		t.BaseEntity = &BaseEntity{}
+	}
+}
+
+// testSyncTicket 同步发布领域事件的工单
+type testSyncTicket struct {
+	testTicket
+}
+
+func (*testSyncTicket) EventMode() EventMode {
+	return EventModeSync
+}
+
+func TestEntityEvents(t *testing.T) {
+	db, tickets := setupTestRepository(t, &testTicket{})
+	bus := eventbus.New()
+	require.NoError(t, di.SINGLE().BindSingletonWithName(module.EventBusService, bus))
+	t.Cleanup(func() { di.SINGLE().Unbind(module.EventBusService) })
+
+	var mu sync.Mutex
+	received := make([]*EntityEvent, 0)
+	collect := func(ctx context.Context, event eventbus.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, event.(*EntityEvent))
+		return nil
+	}
+	for _, action := range []string{EventCreated, EventUpdated, EventDeleted, EventBatchCreated} {
+		bus.Subscribe(EventName(&testTicket{}, action), collect)
+	}
+
+	repo := &Repository[*testTicket]{
+		crudRepo:   tickets,
+		entityType: reflect.TypeOf(testTicket{}),
+		events:     eventModeOf(&testTicket{}),
+	}
+	ctx := context.Background()
+	ticket := &testTicket{BaseEntity: &BaseEntity{}, Title: "bug"}
+	require.NoError(t, repo.Create(ctx, ticket))
+	require.NoError(t, repo.Update(ctx, ticket, map[string]interface{}{"title": "fixed"}))
+
+	// 回滚的事务不发布异步事件
+	rollback := errors.New("rollback")
+	err := repo.Transaction(ctx, func(tx IRepository[*testTicket]) error {
+		if err := tx.BatchCreate(ctx, []*testTicket{{BaseEntity: &BaseEntity{}, Title: "lost"}}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	require.ErrorIs(t, err, rollback)
+	require.NoError(t, repo.Transaction(ctx, func(tx IRepository[*testTicket]) error {
+		return tx.BatchCreate(ctx, []*testTicket{{BaseEntity: &BaseEntity{}, Title: "kept"}})
+	}))
+	require.NoError(t, repo.DeleteById(ctx, ticket.ID))
+	bus.Wait()
+
+	names := make([]string, 0, len(received))
+	for _, event := range received {
+		names = append(names, event.Name)
+	}
+	require.ElementsMatch(t, []string{"testTicket.created", "testTicket.updated", "testTicket.batch_created", "testTicket.deleted"}, names)
+	for _, event := range received {
+		switch event.Action {
+		case EventUpdated:
+			require.Equal(t, map[string]interface{}{"title": "fixed"}, event.Changes)
+		case EventBatchCreated:
+			require.Len(t, event.IDs, 1)
+			require.Equal(t, "kept", event.Data.([]*testTicket)[0].Title)
+		case EventDeleted:
+			require.Equal(t, "fixed", event.Data.(*testTicket).Title)
+		}
+	}
+
+	// 同步事件处理器返回错误时事务回滚
+	syncRepo := &Repository[*testSyncTicket]{
+		crudRepo:   newGormRepository(db, &testSyncTicket{}),
+		entityType: reflect.TypeOf(testSyncTicket{}),
+		events:     eventModeOf(&testSyncTicket{}),
+	}
+	rejected := errors.New("rejected")
+	bus.Subscribe(EventName(&testSyncTicket{}, EventCreated), func(ctx context.Context, event eventbus.Event) error {
+		return rejected
+	})
+	err = syncRepo.Transaction(ctx, func(tx IRepository[*testSyncTicket]) error {
+		return tx.Create(ctx, &testSyncTicket{testTicket{BaseEntity: &BaseEntity{}, Title: "vetoed"}})
+	})
+	require.ErrorIs(t, err, rejected)
+	exists, err := repo.Exists(ctx, "title = ?", "vetoed")
+	require.NoError(t, err)
+	require.False(t, exists)
+}
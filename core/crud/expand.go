@@ -0,0 +1,211 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kruily/gofastcrud/config"
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/core/crud/types"
+	"github.com/kruily/gofastcrud/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// expandTag 允许通过 expand 参数展开的关联字段标签，如 `expand:"true"`
+const expandTag = "expand"
+
+// defaultMaxExpandDepth expand 默认最大层数
+const defaultMaxExpandDepth = 2
+
+// maxExpandDepth 读取配置的 expand 最大层数
+func maxExpandDepth() int {
+	if cfg := config.CONFIG_MANAGER.GetConfig(); cfg != nil && cfg.Query.MaxExpandDepth > 0 {
+		return cfg.Query.MaxExpandDepth
+	}
+	return defaultMaxExpandDepth
+}
+
+// expandNode 解析中的展开节点
+type expandNode struct {
+	path     string         // json 路径，如 category.books
+	relation entityRelation // 关联信息
+	expand   options.Expand // 生成的展开选项
+}
+
+// parseExpand 校验 expand 路径与 fields[路径] 字段选择，生成由浅到深排列的展开选项
+// 路径的每一段须为声明了 expand 标签的关联字段（json 名称或 Go 字段名），层数不能超过 maxDepth
+// 返回值 keys 为主实体上展开所需的连接列，主实体指定了选择字段时需一并查询
+func parseExpand(entityType reflect.Type, dbType string, paths []string, fields map[string]string, maxDepth int) ([]options.Expand, []string, error) {
+	if entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+	nodes := make(map[string]*expandNode)
+	ordered := make([]*expandNode, 0)
+	for _, path := range paths {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		segments := strings.Split(path, ".")
+		if len(segments) > maxDepth {
+			return nil, nil, errors.New(errors.ErrInvalidParam, fmt.Sprintf("expand %s exceeds the max depth %d", path, maxDepth))
+		}
+		parentType := entityType
+		var parent *expandNode
+		for _, segment := range segments {
+			f, ok := expandField(parentType, segment)
+			if !ok {
+				return nil, nil, errors.New(errors.ErrInvalidParam, fmt.Sprintf("relation %s cannot be expanded", segment))
+			}
+			jsonPath, storagePath := f.JSON, f.Name
+			if dbType == DB_TYPE_MONGODB {
+				storagePath = f.BSON
+			}
+			if parent != nil {
+				jsonPath = parent.path + "." + jsonPath
+				storagePath = parent.expand.Path + "." + storagePath
+			}
+			node, exists := nodes[jsonPath]
+			if !exists {
+				relation, err := newEntityRelation(parentType, f)
+				if err != nil {
+					return nil, nil, err
+				}
+				node = &expandNode{path: jsonPath, relation: relation, expand: options.Expand{
+					Path:          storagePath,
+					Table:         relation.Table,
+					LocalColumn:   relation.LocalKey.StorageName(dbType),
+					ForeignColumn: relation.ForeignKey.StorageName(dbType),
+					Many:          relation.Many,
+				}}
+				if meta := softDeleteMetaOf(relation.Type, dbType); meta.Enabled {
+					node.expand.DeletedAt = meta.DeletedAt
+				}
+				nodes[jsonPath] = node
+				ordered = append(ordered, node)
+			}
+			parentType, parent = node.relation.Type, node
+		}
+	}
+
+	for path, names := range fields {
+		node, ok := nodes[path]
+		if !ok {
+			return nil, nil, errors.New(errors.ErrInvalidParam, fmt.Sprintf("fields[%s] requires expand=%s", path, path))
+		}
+		for _, name := range strings.Split(names, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			f, ok := lookupEntityField(node.relation.Type, name)
+			if !ok || isRelationType(f.Type) {
+				return nil, nil, errors.New(errors.ErrInvalidParam, fmt.Sprintf("invalid field %s in fields[%s]", name, path))
+			}
+			node.expand.Select = appendColumn(node.expand.Select, f.StorageName(dbType))
+		}
+	}
+
+	// 指定了选择字段时补充主键与连接列，否则关联数据无法回填
+	keys := make([]string, 0)
+	expands := make([]options.Expand, 0, len(ordered))
+	for _, node := range ordered {
+		if len(node.expand.Select) > 0 {
+			if id, ok := fieldByGoName(node.relation.Type, "ID"); ok {
+				node.expand.Select = appendColumn(node.expand.Select, id.StorageName(dbType))
+			}
+			node.expand.Select = appendColumn(node.expand.Select, node.expand.ForeignColumn)
+			for _, child := range ordered {
+				if strings.HasPrefix(child.path, node.path+".") && !strings.Contains(child.path[len(node.path)+1:], ".") {
+					node.expand.Select = appendColumn(node.expand.Select, child.expand.LocalColumn)
+				}
+			}
+		}
+		if !strings.Contains(node.path, ".") {
+			keys = appendColumn(keys, node.expand.LocalColumn)
+		}
+		expands = append(expands, node.expand)
+	}
+	return expands, keys, nil
+}
+
+// expandField 查找声明了 expand 标签的关联字段
+func expandField(entityType reflect.Type, name string) (entityField, bool) {
+	for _, f := range entityFields(entityType) {
+		if (f.JSON == name || f.Name == name) && f.Tag.Get(expandTag) == "true" {
+			return f, true
+		}
+	}
+	return entityField{}, false
+}
+
+// appendColumn 追加不重复的列
+func appendColumn(columns []string, column string) []string {
+	if containsColumn(columns, column) {
+		return columns
+	}
+	return append(columns, column)
+}
+
+// expandParams expand 与 fields[路径] 查询参数文档
+func expandParams() []types.Parameter {
+	return []types.Parameter{
+		{
+			Name:        "expand",
+			In:          "query",
+			Description: "Relations to expand (comma-separated json paths, e.g. category,category.books), only relations tagged with expand are allowed",
+			Schema:      types.Schema{Type: "string"},
+		},
+		{
+			Name:        "fields[relation]",
+			In:          "query",
+			Description: "Fields to select on an expanded relation, e.g. fields[category]=name",
+			Schema:      types.Schema{Type: "string"},
+		},
+	}
+}
+
+// mongoExpandStages 将 parent 下一层的关联展开转换为 $lookup 阶段，下级关联嵌套在 $lookup 管道中
+func mongoExpandStages(expands []options.Expand, parent string) bson.A {
+	stages := bson.A{}
+	for _, expand := range expands {
+		name := expand.Path
+		if parent != "" {
+			var ok bool
+			if name, ok = strings.CutPrefix(expand.Path, parent+"."); !ok {
+				continue
+			}
+		}
+		if strings.Contains(name, ".") {
+			continue
+		}
+		match := bson.M{"$expr": bson.M{"$eq": bson.A{"$" + expand.ForeignColumn, "$$local"}}}
+		if expand.DeletedAt != "" {
+			match[expand.DeletedAt] = nil
+		}
+		pipeline := bson.A{bson.M{"$match": match}}
+		children := mongoExpandStages(expands, expand.Path)
+		pipeline = append(pipeline, children...)
+		if len(expand.Select) > 0 {
+			projection := bson.M{}
+			for _, column := range expand.Select {
+				projection[column] = 1
+			}
+			for _, child := range expands {
+				if childName, ok := strings.CutPrefix(child.Path, expand.Path+"."); ok && !strings.Contains(childName, ".") {
+					projection[childName] = 1
+				}
+			}
+			pipeline = append(pipeline, bson.M{"$project": projection})
+		}
+		stages = append(stages, bson.M{"$lookup": bson.M{
+			"from":     expand.Table,
+			"let":      bson.M{"local": "$" + expand.LocalColumn},
+			"pipeline": pipeline,
+			"as":       name,
+		}})
+		if !expand.Many {
+			stages = append(stages, bson.M{"$unwind": bson.M{"path": "$" + name, "preserveNullAndEmptyArrays": true}})
+		}
+	}
+	return stages
+}
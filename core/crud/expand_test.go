@@ -0,0 +1,72 @@
+package crud
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseExpand(t *testing.T) {
+	bookType := reflect.TypeOf(testBook{})
+
+	expands, keys, err := parseExpand(bookType, DB_TYPE_GORM, []string{"category.books", "Category"}, map[string]string{"category": "name"}, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"category_id"}, keys)
+	require.Len(t, expands, 2)
+	require.Equal(t, "Category", expands[0].Path)
+	require.Equal(t, []string{"name", "id"}, expands[0].Select) // 补充主键，供 books 回填
+	require.Equal(t, "Category.Books", expands[1].Path)
+	require.True(t, expands[1].Many)
+	require.Empty(t, expands[1].Select)
+
+	for _, tc := range []struct {
+		paths  []string
+		fields map[string]string
+	}{
+		{paths: []string{"category.books.category"}},                              // 超过最大层数
+		{paths: []string{"title"}},                                                // 非关联字段
+		{paths: []string{"category"}, fields: map[string]string{"books": "id"}},   // 未展开的路径
+		{paths: []string{"category"}, fields: map[string]string{"category": "x"}}, // 未知字段
+	} {
+		_, _, err := parseExpand(bookType, DB_TYPE_GORM, tc.paths, tc.fields, 2)
+		require.True(t, errors.Is(err, errors.ErrInvalidParam), tc.paths)
+	}
+}
+
+func TestGormExpand(t *testing.T) {
+	books := setupRelationRepository(t)
+	expands, keys, err := parseExpand(books.entityType, DB_TYPE_GORM, []string{"category.books"}, map[string]string{"category": "name"}, 2)
+	require.NoError(t, err)
+
+	items, err := books.Find(context.Background(), &testBook{}, options.NewQueryOptions(
+		options.WithOrderBy("id asc"),
+		options.WithSelect(append([]string{"id", "title"}, keys...)),
+		options.WithExpand(expands...),
+	))
+	require.NoError(t, err)
+	require.Len(t, items, 4)
+	require.NotNil(t, items[0].Category)
+	require.Equal(t, "Fiction", items[0].Category.Name)
+	require.True(t, items[0].Category.CreatedAt.IsZero()) // 只查询了选择的列
+	require.Len(t, items[0].Category.Books, 2)
+	require.Nil(t, items[3].Category) // 已软删除的分类不展开
+}
+
+func TestMongoExpandStages(t *testing.T) {
+	expands, _, err := parseExpand(reflect.TypeOf(testBook{}), DB_TYPE_MONGODB, []string{"category.books"}, map[string]string{"category": "name"}, 2)
+	require.NoError(t, err)
+
+	stages := mongoExpandStages(expands, "")
+	require.Len(t, stages, 2) // category 的 $lookup 与 $unwind
+	lookup := stages[0].(bson.M)["$lookup"].(bson.M)
+	require.Equal(t, "test_categories", lookup["from"])
+	pipeline := lookup["pipeline"].(bson.A)
+	require.Len(t, pipeline, 3) // $match、嵌套 books 的 $lookup、$project
+	require.Equal(t, "books", pipeline[1].(bson.M)["$lookup"].(bson.M)["as"])
+	require.Equal(t, 1, pipeline[2].(bson.M)["$project"].(bson.M)["books"])
+}
@@ -1,18 +1,30 @@
 package crud
 
 import (
+	"context"
+	stderrors "errors"
+	"fmt"
 	"reflect"
 
 	"github.com/kruily/gofastcrud/core/crud/module"
 	"github.com/kruily/gofastcrud/core/crud/types"
 	"github.com/kruily/gofastcrud/core/database"
 	"github.com/kruily/gofastcrud/core/di"
+	"gorm.io/gorm"
 )
 
 // ControllerFactory 控制器工厂
 type ControllerFactory struct {
-	db     *database.Database // TODO 还需要想办法，将具体的数据库实例注入到控制器，使用mongog的实体使用mongodb，使用mysql的实体使用mysql
-	models []interface{}
+	db     *database.Database // 注册控制器使用的数据源，实体实现 IDatasource 时以实体为准
+	root   *ControllerFactory // Datasource 创建的工厂指向原工厂，注册的实体统一由原工厂迁移
+	models []factoryModel
+	errs   []error // 注册过程中的数据源错误，由 Migrate 返回
+}
+
+// factoryModel 已注册的实体及其数据源
+type factoryModel struct {
+	entity ICrudEntity
+	db     *database.Database
 }
 
 // NewControllerFactory 创建控制器工厂
@@ -22,12 +34,49 @@ func NewControllerFactory() *ControllerFactory {
 	return &ControllerFactory{db: db}
 }
 
+// Datasource 返回使用命名数据源注册控制器的工厂
+// 数据源未配置时记录错误并沿用当前数据源，错误由 Migrate 返回
+// 使用场景：factory.Datasource("analytics").Register(server, &Report{})
+func (f *ControllerFactory) Datasource(name string) *ControllerFactory {
+	db, err := f.db.Datasource(name)
+	if err != nil {
+		f.fail(err)
+		db = f.db
+	}
+	return &ControllerFactory{db: db, root: f.registry()}
+}
+
+// fail 记录注册过程中的错误
+func (f *ControllerFactory) fail(err error) {
+	root := f.registry()
+	root.errs = append(root.errs, err)
+}
+
+// registry 记录注册实体的工厂
+func (f *ControllerFactory) registry() *ControllerFactory {
+	if f.root != nil {
+		return f.root
+	}
+	return f
+}
+
+// addModel 记录注册的实体，用于按数据源迁移
+func (f *ControllerFactory) addModel(model ICrudEntity) {
+	db, err := entityDatabase(f.db, model)
+	if err != nil {
+		f.fail(err)
+		return
+	}
+	root := f.registry()
+	root.models = append(root.models, factoryModel{entity: model, db: db})
+}
+
 // RegisterGroup 注册后台路由组
 // 简单注册，默认注册标准控制器
 // server 是注册服务器
 // model 是实体
 func (f *ControllerFactory) Register(server types.RegisterServer, model ICrudEntity) ICrudController[ICrudEntity] {
-	f.models = append(f.models, model)
+	f.addModel(model)
 	controller := NewCrudController(f.db, model)
 	server.RegisterCrudController(model.TableName(), controller, reflect.TypeOf(model))
 	return controller
@@ -40,7 +89,7 @@ func (f *ControllerFactory) Register(server types.RegisterServer, model ICrudEnt
 // constructor 是控制器构造函数
 func (f *ControllerFactory) RegisterCustom(server types.RegisterServer, constructor func(*database.Database) ICrudController[ICrudEntity]) ICrudController[ICrudEntity] {
 	controller := constructor(f.db)
-	f.models = append(f.models, controller.GetEntity())
+	f.addModel(controller.GetEntity())
 	server.RegisterCrudController(controller.GetEntity().TableName(), controller, reflect.TypeOf(controller.GetEntity()))
 	return controller
 }
@@ -51,7 +100,7 @@ func (f *ControllerFactory) RegisterCustom(server types.RegisterServer, construc
 // group 是路由组
 // model 是实体
 func (f *ControllerFactory) RegisterWithFather(server types.RegisterServer, father ICrudController[ICrudEntity], model ICrudEntity) ICrudController[ICrudEntity] {
-	f.models = append(f.models, model)
+	f.addModel(model)
 	controller := NewCrudController(f.db, model)
 	server.RegisterCrudControllerWithFather(father, model.TableName(), controller, reflect.TypeOf(model))
 	return controller
@@ -59,7 +108,7 @@ func (f *ControllerFactory) RegisterWithFather(server types.RegisterServer, fath
 
 func (f *ControllerFactory) RegisterWithFatherCustom(server types.RegisterServer, father ICrudController[ICrudEntity], constructor func(*database.Database) ICrudController[ICrudEntity]) ICrudController[ICrudEntity] {
 	controller := constructor(f.db)
-	f.models = append(f.models, controller.GetEntity())
+	f.addModel(controller.GetEntity())
 	server.RegisterCrudControllerWithFather(father, controller.GetEntity().TableName(), controller, reflect.TypeOf(controller.GetEntity()))
 	return controller
 }
@@ -70,7 +119,7 @@ func (f *ControllerFactory) RegisterWithFatherCustom(server types.RegisterServer
 // models 是实体
 func (f *ControllerFactory) RegisterBatch(server types.RegisterServer, models ...ICrudEntity) {
 	for _, model := range models {
-		f.models = append(f.models, model)
+		f.addModel(model)
 		server.RegisterCrudController(model.TableName(), NewCrudController(f.db, model), reflect.TypeOf(model))
 	}
 }
@@ -82,7 +131,7 @@ func (f *ControllerFactory) RegisterBatch(server types.RegisterServer, models ..
 func (f *ControllerFactory) RegisterBatchCustom(server types.RegisterServer, controllerConstructor ...func(*database.Database) ICrudController[ICrudEntity]) {
 	for _, constructor := range controllerConstructor {
 		controller := constructor(f.db)
-		f.models = append(f.models, controller.GetEntity())
+		f.addModel(controller.GetEntity())
 		server.RegisterCrudController(controller.GetEntity().TableName(), controller, reflect.TypeOf(controller.GetEntity()))
 	}
 }
@@ -93,7 +142,7 @@ func (f *ControllerFactory) RegisterBatchCustom(server types.RegisterServer, con
 // models 是实体映射
 func (f *ControllerFactory) RegisterBatchMap(server types.RegisterServer, models map[string]ICrudEntity) {
 	for key, model := range models {
-		f.models = append(f.models, model)
+		f.addModel(model)
 		server.RegisterCrudController(key, NewCrudController(f.db, model), reflect.TypeOf(model))
 	}
 }
@@ -105,14 +154,47 @@ func (f *ControllerFactory) RegisterBatchMap(server types.RegisterServer, models
 func (f *ControllerFactory) RegisterBatchCustomMap(server types.RegisterServer, mapControllerConstructor map[string]func(*database.Database) ICrudController[ICrudEntity]) {
 	for key, constructor := range mapControllerConstructor {
 		controller := constructor(f.db)
-		f.models = append(f.models, controller.GetEntity())
+		f.addModel(controller.GetEntity())
 		server.RegisterCrudController(key, controller, reflect.TypeOf(controller.GetEntity()))
 	}
 }
 
-// gorm 自动迁移
-func (f *ControllerFactory) Migrate() {
-	if f.db.DB() != nil {
-		f.db.DB().AutoMigrate(f.models...)
+// Migrate 按实体的数据源执行 gorm 自动迁移，并为声明了 search 字段的实体创建全文索引
+// 返回注册时的数据源错误以及迁移与全文索引创建中的全部错误；sqlite 全文索引需启用 FTS5（sqlite_fts5 构建标签），否则全文检索查询会失败
+func (f *ControllerFactory) Migrate() error {
+	root := f.registry()
+	groups := make(map[*gorm.DB][]interface{})
+	names := make(map[*gorm.DB]string)
+	order := make([]*gorm.DB, 0)
+	for _, model := range root.models {
+		db := model.db.DB()
+		if model.entity.DBType() == DB_TYPE_MONGODB || db == nil {
+			continue
+		}
+		if _, ok := groups[db]; !ok {
+			order = append(order, db)
+			names[db] = model.db.Name()
+		}
+		groups[db] = append(groups[db], model.entity)
+	}
+	errs := append([]error(nil), root.errs...)
+	for _, db := range order {
+		if err := db.AutoMigrate(groups[db]...); err != nil {
+			errs = append(errs, fmt.Errorf("migrate datasource %s: %w", names[db], err))
+		}
+	}
+	for _, model := range root.models {
+		entity := model.entity
+		var err error
+		switch {
+		case entity.DBType() == DB_TYPE_MONGODB && model.db.MDB() != nil:
+			err = migrateMongoTextIndex(context.Background(), model.db.MDB(), entity)
+		case entity.DBType() != DB_TYPE_MONGODB && model.db.DB() != nil:
+			err = migrateSearchIndex(model.db.DB(), entity)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("create search index for %s: %w", entity.TableName(), err))
+		}
 	}
+	return stderrors.Join(errs...)
 }
@@ -0,0 +1,168 @@
+package crud
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	uuidType     = reflect.TypeOf(uuid.UUID{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// filterTimeLayouts 时间过滤值支持的格式
+var filterTimeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+// compileFilters 按实体字段的 filter 标签将查询参数编译为过滤条件
+// 参数形如 field=value（eq）或 field_op=value，field 为 json 名称；未声明的字段或操作符返回 ErrInvalidParam
+func compileFilters(entityType reflect.Type, dbType string, query url.Values) ([]options.Condition, error) {
+	conditions := make([]options.Condition, 0)
+	for key, values := range query {
+		if isSpecialParam(key) || len(values) == 0 {
+			continue
+		}
+		field, relation, op, err := resolveFilterKey(entityType, key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := filterValue(field, op, values[0])
+		if err != nil {
+			return nil, errors.New(errors.ErrInvalidParam, fmt.Sprintf("invalid value for %s: %v", key, err))
+		}
+		conditions = append(conditions, options.Condition{Table: relation, Field: field.StorageName(dbType), Op: op, Value: value})
+	}
+	return conditions, nil
+}
+
+// resolveFilterKey 解析查询参数对应的字段、关联别名与操作符，并校验是否在 filter 标签中声明
+func resolveFilterKey(entityType reflect.Type, key string) (entityField, string, string, error) {
+	for _, op := range options.FilterOperators {
+		name, found := strings.CutSuffix(key, "_"+op)
+		if !found {
+			continue
+		}
+		if field, relation, err := filterField(entityType, name); err == nil {
+			if !filterAllowed(field, op) {
+				return entityField{}, "", "", errors.New(errors.ErrInvalidParam, fmt.Sprintf("operator %s is not allowed on field %s", op, name))
+			}
+			return field, relation, op, nil
+		}
+	}
+	field, relation, err := filterField(entityType, key)
+	if err != nil {
+		return entityField{}, "", "", err
+	}
+	if !filterAllowed(field, options.OpEq) {
+		return entityField{}, "", "", errors.New(errors.ErrInvalidParam, fmt.Sprintf("operator eq is not allowed on field %s", key))
+	}
+	return field, relation, options.OpEq, nil
+}
+
+// filterField 根据 json 名称查找声明了 filter 标签的字段，relation.field 形式查找关联实体字段
+// 返回字段与关联别名，实体自身字段的关联别名为空
+func filterField(entityType reflect.Type, name string) (entityField, string, error) {
+	if strings.Contains(name, ".") {
+		relation, field, err := resolveRelationPath(entityType, name)
+		if err != nil {
+			return entityField{}, "", err
+		}
+		if field.Tag.Get("filter") == "" {
+			return entityField{}, "", errors.New(errors.ErrInvalidParam, fmt.Sprintf("field %s is not filterable", name))
+		}
+		return field, relation.Alias, nil
+	}
+	for _, f := range entityFields(entityType) {
+		if f.JSON == name && f.Tag.Get("filter") != "" {
+			return f, "", nil
+		}
+	}
+	return entityField{}, "", errors.New(errors.ErrInvalidParam, fmt.Sprintf("field %s is not filterable", name))
+}
+
+// filterOperators 解析 filter 标签声明的操作符，all 表示全部
+func filterOperators(tag string) []string {
+	ops := make([]string, 0)
+	for _, op := range strings.Split(tag, ",") {
+		if op = strings.TrimSpace(op); op == "all" {
+			return options.FilterOperators
+		} else if op != "" {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// filterAllowed 判断字段是否声明了操作符
+func filterAllowed(field entityField, op string) bool {
+	for _, allowed := range filterOperators(field.Tag.Get("filter")) {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+// filterValue 按操作符与字段类型转换参数值
+func filterValue(field entityField, op string, raw string) (interface{}, error) {
+	switch op {
+	case options.OpLike, options.OpNlike:
+		return raw, nil
+	case options.OpNull:
+		return strconv.ParseBool(raw)
+	case options.OpIn, options.OpNin, options.OpBetween:
+		parts := strings.Split(raw, ",")
+		if op == options.OpBetween && len(parts) != 2 {
+			return nil, fmt.Errorf("between requires two comma-separated values")
+		}
+		values := make([]interface{}, 0, len(parts))
+		for _, part := range parts {
+			value, err := coerceFilterValue(field.Type, strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	return coerceFilterValue(field.Type, raw)
+}
+
+// coerceFilterValue 将字符串转换为字段类型的值
+func coerceFilterValue(t reflect.Type, raw string) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case timeType:
+		for _, layout := range filterTimeLayouts {
+			if value, err := time.Parse(layout, raw); err == nil {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid time %q", raw)
+	case uuidType:
+		return uuid.Parse(raw)
+	case objectIDType:
+		return primitive.ObjectIDFromHex(raw)
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(raw, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(raw, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(raw, 64)
+	case reflect.Bool:
+		return strconv.ParseBool(raw)
+	}
+	return raw, nil
+}
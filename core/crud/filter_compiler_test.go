@@ -0,0 +1,94 @@
+package crud
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileFilters(t *testing.T) {
+	entityType := reflect.TypeOf(testArticle{})
+
+	conditions, err := compileFilters(entityType, DB_TYPE_GORM, url.Values{"score_between": {"1,2"}, "page": {"1"}})
+	require.NoError(t, err)
+	require.Equal(t, []options.Condition{{Field: "score", Op: options.OpBetween, Value: []interface{}{1.0, 2.0}}}, conditions)
+
+	for _, query := range []url.Values{
+		{"deleted_at_null": {"true"}}, // 未声明 filter 的字段
+		{"title_gt": {"a"}},           // 未声明的操作符
+		{"score": {"1"}},              // 未声明 eq
+		{"score_gt": {"abc"}},         // 值类型错误
+		{"score_between": {"1"}},      // between 需要两个值
+	} {
+		_, err := compileFilters(entityType, DB_TYPE_GORM, query)
+		require.True(t, errors.Is(err, errors.ErrInvalidParam), query.Encode())
+	}
+}
+
+func TestGormConditions(t *testing.T) {
+	_, repo := setupGormRepository(t)
+	seedArticles(t, repo, 6)
+	ctx := context.Background()
+
+	find := func(query url.Values) []*testArticle {
+		conditions, err := compileFilters(repo.entityType, DB_TYPE_GORM, query)
+		require.NoError(t, err)
+		items, err := repo.Find(ctx, &testArticle{}, options.NewQueryOptions(options.WithConditions(conditions...)))
+		require.NoError(t, err)
+		return items
+	}
+
+	require.Len(t, find(url.Values{"title": {"article-01"}}), 1)
+	require.Len(t, find(url.Values{"title_neq": {"article-01"}}), 5)
+	require.Len(t, find(url.Values{"title_nin": {"article-01,article-02"}}), 4)
+	require.Len(t, find(url.Values{"title_nlike": {"-0"}}), 0)
+	require.Len(t, find(url.Values{"score_between": {"1,2"}}), 4)
+	require.Len(t, find(url.Values{"score_gte": {"2"}, "title_like": {"05"}}), 1)
+
+	// LIKE 的通配符与转义字符按字面匹配
+	require.NoError(t, repo.Create(ctx, &testArticle{BaseEntity: &BaseEntity{}, Title: `50%_off\now`}))
+	require.Len(t, find(url.Values{"title_like": {"%"}}), 1)
+	require.Len(t, find(url.Values{"title_like": {"_"}}), 1)
+	require.Len(t, find(url.Values{"title_like": {`\`}}), 1)
+	require.Len(t, find(url.Values{"title_nlike": {"%_"}}), 6)
+	items, err := repo.Find(ctx, &testArticle{}, options.NewQueryOptions(options.WithSearch("_"), options.WithSearchFields([]string{"title"})))
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+}
+
+func TestGormFilterExpr(t *testing.T) {
+	_, repo := setupGormRepository(t)
+	seedArticles(t, repo, 6)
+	ctx := context.Background()
+
+	find := func(raw string, opts ...func(*options.QueryOptions)) []*testArticle {
+		expr, err := parseFilterExpr(repo.entityType, DB_TYPE_GORM, raw)
+		require.NoError(t, err)
+		items, err := repo.Find(ctx, &testArticle{}, options.NewQueryOptions(append(opts, options.WithFilterExpr(expr))...))
+		require.NoError(t, err)
+		return items
+	}
+
+	require.Len(t, find(`{"or":[{"field":"title","value":"article-01"},{"field":"score","op":"gte","value":2}]}`), 3)
+	require.Len(t, find(`{"not":{"field":"score","op":"between","value":[1,2]}}`), 2)
+	require.Len(t, find(`{"and":[{"field":"title","op":"in","value":["article-01","article-02"]},{"not":{"field":"score","op":"lt","value":2}}]}`), 1)
+	// 搜索条件整体作为一组 OR，再与过滤表达式 AND
+	require.Len(t, find(`{"field":"score","op":"gte","value":2}`, options.WithSearch("article"), options.WithSearchFields([]string{"title", "id"})), 2)
+
+	for _, raw := range []string{
+		`{"field":"deleted_at","op":"null","value":true}`, // 未声明 filter 的字段
+		`{"field":"title","op":"gt","value":"a"}`,         // 未声明的操作符
+		`{"field":"title","value":"a","or":[]}`,           // 多种节点
+		`{"or":[]}`,                                       // 空分组
+		`{"field":"title","value":"a","unknown":1}`,       // 未知键
+		`{"not":{"not":{"not":{"not":{"not":{"not":{"not":{"not":{"field":"title","value":"a"}}}}}}}}}`, // 超过最大深度
+	} {
+		_, err := parseFilterExpr(repo.entityType, DB_TYPE_GORM, raw)
+		require.True(t, errors.Is(err, errors.ErrInvalidParam), raw)
+	}
+}
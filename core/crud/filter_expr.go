@@ -0,0 +1,170 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/core/crud/types"
+	"github.com/kruily/gofastcrud/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// maxFilterDepth 过滤表达式最大嵌套层数
+const maxFilterDepth = 8
+
+// filterNode filter 参数的 json 节点
+// 分组：{"and": [...]}、{"or": [...]}、{"not": {...}}；条件：{"field": "title", "op": "like", "value": "go"}
+type filterNode struct {
+	And   []filterNode `json:"and"`
+	Or    []filterNode `json:"or"`
+	Not   *filterNode  `json:"not"`
+	Field string       `json:"field"`
+	Op    string       `json:"op"`
+	Value interface{}  `json:"value"`
+}
+
+// parseFilterExpr 解析 filter 参数，字段与操作符须在 filter 标签中声明
+func parseFilterExpr(entityType reflect.Type, dbType string, raw string) (*options.FilterExpr, error) {
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	var node filterNode
+	if err := decoder.Decode(&node); err != nil {
+		return nil, errors.New(errors.ErrInvalidParam, "invalid filter: "+err.Error())
+	}
+	expr, err := compileFilterNode(entityType, dbType, node, 1)
+	if err != nil {
+		return nil, err
+	}
+	return &expr, nil
+}
+
+// compileFilterNode 递归编译节点
+func compileFilterNode(entityType reflect.Type, dbType string, node filterNode, depth int) (options.FilterExpr, error) {
+	if depth > maxFilterDepth {
+		return options.FilterExpr{}, errors.New(errors.ErrInvalidParam, fmt.Sprintf("filter is nested deeper than %d levels", maxFilterDepth))
+	}
+	kinds := 0
+	for _, set := range []bool{node.And != nil, node.Or != nil, node.Not != nil, node.Field != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return options.FilterExpr{}, errors.New(errors.ErrInvalidParam, "filter node must contain exactly one of and, or, not, field")
+	}
+
+	compileChildren := func(children []filterNode) ([]options.FilterExpr, error) {
+		if len(children) == 0 {
+			return nil, errors.New(errors.ErrInvalidParam, "filter group must not be empty")
+		}
+		exprs := make([]options.FilterExpr, 0, len(children))
+		for _, child := range children {
+			expr, err := compileFilterNode(entityType, dbType, child, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			exprs = append(exprs, expr)
+		}
+		return exprs, nil
+	}
+
+	switch {
+	case node.And != nil:
+		children, err := compileChildren(node.And)
+		return options.FilterExpr{And: children}, err
+	case node.Or != nil:
+		children, err := compileChildren(node.Or)
+		return options.FilterExpr{Or: children}, err
+	case node.Not != nil:
+		child, err := compileFilterNode(entityType, dbType, *node.Not, depth+1)
+		return options.FilterExpr{Not: &child}, err
+	}
+
+	field, relation, err := filterField(entityType, node.Field)
+	if err != nil {
+		return options.FilterExpr{}, err
+	}
+	op := node.Op
+	if op == "" {
+		op = options.OpEq
+	}
+	if !filterAllowed(field, op) {
+		return options.FilterExpr{}, errors.New(errors.ErrInvalidParam, fmt.Sprintf("operator %s is not allowed on field %s", op, node.Field))
+	}
+	value, err := filterNodeConditionValue(field, op, node.Value)
+	if err != nil {
+		return options.FilterExpr{}, errors.New(errors.ErrInvalidParam, fmt.Sprintf("invalid value for %s: %v", node.Field, err))
+	}
+	return options.FilterExpr{Condition: &options.Condition{Table: relation, Field: field.StorageName(dbType), Op: op, Value: value}}, nil
+}
+
+// filterNodeConditionValue 转换条件值，in/nin/between 可使用 json 数组或逗号分隔的字符串
+func filterNodeConditionValue(field entityField, op string, value interface{}) (interface{}, error) {
+	items, isList := value.([]interface{})
+	if !isList || (op != options.OpIn && op != options.OpNin && op != options.OpBetween) {
+		return filterValue(field, op, filterNodeValue(value))
+	}
+	if op == options.OpBetween && len(items) != 2 {
+		return nil, fmt.Errorf("between requires two values")
+	}
+	values := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		v, err := coerceFilterValue(field.Type, filterNodeValue(item))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// filterNodeValue 将 json 标量转换为与查询参数一致的字符串形式
+func filterNodeValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
+
+// mongoFilterExpr 将过滤表达式转换为 bson 条件
+func mongoFilterExpr(e options.FilterExpr) bson.M {
+	children := func(exprs []options.FilterExpr) bson.A {
+		items := make(bson.A, 0, len(exprs))
+		for _, expr := range exprs {
+			items = append(items, mongoFilterExpr(expr))
+		}
+		return items
+	}
+	switch {
+	case e.Condition != nil:
+		return mongoCondition(*e.Condition)
+	case e.Not != nil:
+		return bson.M{"$nor": bson.A{mongoFilterExpr(*e.Not)}}
+	case len(e.And) > 0:
+		return bson.M{"$and": children(e.And)}
+	case len(e.Or) > 0:
+		return bson.M{"$or": children(e.Or)}
+	}
+	return bson.M{}
+}
+
+// filterExprParam filter 参数说明
+func filterExprParam() types.Parameter {
+	return types.Parameter{
+		Name: "filter",
+		In:   "query",
+		Description: `Nested filter expression in JSON, combined with other filters by AND. ` +
+			`Groups: {"and": [...]}, {"or": [...]}, {"not": {...}}; conditions: {"field": "<json name>", "op": "<operator declared in filter tag>", "value": ...}. ` +
+			`Example: {"or": [{"field": "title", "op": "like", "value": "go"}, {"field": "score", "op": "gt", "value": 5}]}`,
+		Schema: types.Schema{Type: "string"},
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/kruily/gofastcrud/core/crud/options"
 	"github.com/kruily/gofastcrud/core/crud/types"
 	"github.com/kruily/gofastcrud/errors"
 )
@@ -23,7 +24,7 @@ type QueryField struct {
 // return []types.Parameter 参数列表
 func ModeParams(c ICrudController[ICrudEntity]) []types.Parameter {
 	// 获取所有可查询字段
-	queryFields := queryFields(c.GetEntity())
+	queryFields := append(queryFields(c.GetEntity()), relationQueryFields(c.GetEntity())...)
 	params := []types.Parameter{}
 	params, err := generateModeQueryParams(params, queryFields)
 	if err != nil {
@@ -95,18 +96,18 @@ var tagDescriptions = map[string]string{
 
 // 生成模式查询参数
 func generateModeQueryParams(queryParams []types.Parameter, queryFields []QueryField) ([]types.Parameter, error) {
-	allFilterTags := []string{"gt", "gte", "lt", "lte", "eq", "neq", "in", "nin", "like", "nlike", "between", "null"}
 	// 为每个字段添加过滤参数
 	for _, field := range queryFields {
-		tags := strings.Split(field.FilterTag, ",")
+		// 解析tag，all 展开为所有tag
+		tags := filterOperators(field.FilterTag)
 		// 如果没有指定tag，跳过
-		if len(tags) == 0 || tags[0] == "" {
+		if len(tags) == 0 {
 			continue
 		}
 		// 检查tag是否合法
 		for _, tag := range tags {
 			found := false
-			for _, v := range allFilterTags {
+			for _, v := range options.FilterOperators {
 				if v == tag {
 					found = true
 					break
@@ -116,10 +117,6 @@ func generateModeQueryParams(queryParams []types.Parameter, queryFields []QueryF
 				return nil, errors.New(errors.ErrInternal, fmt.Sprintf("filter tag %s is not valid", tag))
 			}
 		}
-		// 如果 tag 为 all，添加所有tag
-		if len(tags) == 1 && tags[0] == "all" {
-			tags = allFilterTags
-		}
 
 		// 创建查询参数 openapi 描述
 		for _, tag := range tags {
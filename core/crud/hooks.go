@@ -0,0 +1,193 @@
+package crud
+
+import (
+	"context"
+)
+
+// 实体生命周期钩子，实体实现对应接口即可，由 Repository 在 gorm 与 MongoDB 上统一调用，批量操作逐条调用
+// 钩子接收请求上下文，返回错误时中止操作并原样返回，可返回 AppError 控制响应状态码；
+// After 钩子在写入后执行，返回错误时只有在事务中执行的操作会回滚
+// 钩子以 On 开头，与 gorm、qmgo 的钩子方法不重名，不会被它们调用
+
+// IBeforeCreateHook 创建前钩子，upsert 同样调用
+type IBeforeCreateHook interface {
+	OnBeforeCreate(ctx context.Context) error
+}
+
+// IAfterCreateHook 创建后钩子，upsert 同样调用
+type IAfterCreateHook interface {
+	OnAfterCreate(ctx context.Context) error
+}
+
+// IBeforeUpdateHook 更新前钩子，changes 为待更新字段，可在钩子中修改；整体更新时为 nil
+type IBeforeUpdateHook interface {
+	OnBeforeUpdate(ctx context.Context, changes map[string]interface{}) error
+}
+
+// IAfterUpdateHook 更新后钩子，changes 为已更新字段，整体更新时为 nil
+type IAfterUpdateHook interface {
+	OnAfterUpdate(ctx context.Context, changes map[string]interface{}) error
+}
+
+// IBeforeDeleteHook 删除前钩子，按 ID 删除时先查询记录，已软删除的记录物理删除时不调用
+type IBeforeDeleteHook interface {
+	OnBeforeDelete(ctx context.Context) error
+}
+
+// IAfterDeleteHook 删除后钩子
+type IAfterDeleteHook interface {
+	OnAfterDelete(ctx context.Context) error
+}
+
+// IAfterFindHook 查询后钩子，对每条查询结果调用
+type IAfterFindHook interface {
+	OnAfterFind(ctx context.Context) error
+}
+
+// hasDeleteHooks 实体是否实现删除钩子，按 ID 删除时需先查询记录
+func hasDeleteHooks(entity any) bool {
+	_, before := entity.(IBeforeDeleteHook)
+	_, after := entity.(IAfterDeleteHook)
+	return before || after
+}
+
+// beforeCreate 调用创建前钩子
+func beforeCreate[T ICrudEntity](ctx context.Context, entities ...T) error {
+	for _, entity := range entities {
+		if hook, ok := any(entity).(IBeforeCreateHook); ok {
+			if err := hook.OnBeforeCreate(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// afterCreate 调用创建后钩子
+func afterCreate[T ICrudEntity](ctx context.Context, entities ...T) error {
+	for _, entity := range entities {
+		if hook, ok := any(entity).(IAfterCreateHook); ok {
+			if err := hook.OnAfterCreate(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// beforeUpdate 调用更新前钩子
+func beforeUpdate[T ICrudEntity](ctx context.Context, changes map[string]interface{}, entities ...T) error {
+	for _, entity := range entities {
+		if hook, ok := any(entity).(IBeforeUpdateHook); ok {
+			if err := hook.OnBeforeUpdate(ctx, changes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// afterUpdate 调用更新后钩子
+func afterUpdate[T ICrudEntity](ctx context.Context, changes map[string]interface{}, entities ...T) error {
+	for _, entity := range entities {
+		if hook, ok := any(entity).(IAfterUpdateHook); ok {
+			if err := hook.OnAfterUpdate(ctx, changes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// beforeDelete 调用删除前钩子
+func beforeDelete[T ICrudEntity](ctx context.Context, entities ...T) error {
+	for _, entity := range entities {
+		if hook, ok := any(entity).(IBeforeDeleteHook); ok {
+			if err := hook.OnBeforeDelete(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// afterDelete 调用删除后钩子
+func afterDelete[T ICrudEntity](ctx context.Context, entities ...T) error {
+	for _, entity := range entities {
+		if hook, ok := any(entity).(IAfterDeleteHook); ok {
+			if err := hook.OnAfterDelete(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// afterFind 调用查询后钩子
+func afterFind[T ICrudEntity](ctx context.Context, entities ...T) error {
+	for _, entity := range entities {
+		if hook, ok := any(entity).(IAfterFindHook); ok {
+			if err := hook.OnAfterFind(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hookedQuery 链式查询构建器，为 Find 与 First 的结果调用查询后钩子
+type hookedQuery[T ICrudEntity] struct {
+	IQuery[T]
+}
+
+func (q hookedQuery[T]) Where(query interface{}, args ...interface{}) IQuery[T] {
+	return hookedQuery[T]{q.IQuery.Where(query, args...)}
+}
+
+func (q hookedQuery[T]) Order(value string) IQuery[T] {
+	return hookedQuery[T]{q.IQuery.Order(value)}
+}
+
+func (q hookedQuery[T]) Select(fields ...string) IQuery[T] {
+	return hookedQuery[T]{q.IQuery.Select(fields...)}
+}
+
+func (q hookedQuery[T]) Preload(relations ...string) IQuery[T] {
+	return hookedQuery[T]{q.IQuery.Preload(relations...)}
+}
+
+func (q hookedQuery[T]) Joins(query string, args ...interface{}) IQuery[T] {
+	return hookedQuery[T]{q.IQuery.Joins(query, args...)}
+}
+
+func (q hookedQuery[T]) Group(fields ...string) IQuery[T] {
+	return hookedQuery[T]{q.IQuery.Group(fields...)}
+}
+
+func (q hookedQuery[T]) Having(query interface{}, args ...interface{}) IQuery[T] {
+	return hookedQuery[T]{q.IQuery.Having(query, args...)}
+}
+
+func (q hookedQuery[T]) Limit(limit int) IQuery[T] {
+	return hookedQuery[T]{q.IQuery.Limit(limit)}
+}
+
+func (q hookedQuery[T]) Offset(offset int) IQuery[T] {
+	return hookedQuery[T]{q.IQuery.Offset(offset)}
+}
+
+func (q hookedQuery[T]) Find(ctx context.Context) ([]T, error) {
+	items, err := q.IQuery.Find(ctx)
+	if err != nil {
+		return items, err
+	}
+	return items, afterFind(ctx, items...)
+}
+
+func (q hookedQuery[T]) First(ctx context.Context) (T, error) {
+	entity, err := q.IQuery.First(ctx)
+	if err != nil {
+		return entity, err
+	}
+	return entity, afterFind(ctx, entity)
+}
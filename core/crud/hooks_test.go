@@ -0,0 +1,127 @@
+package crud
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// testHookedTask 钩子测试用任务，events 记录钩子调用顺序
+type testHookedTask struct {
+	*BaseEntity
+	Title   string   `json:"title"`
+	Slug    string   `json:"slug"`
+	Display string   `json:"display" gorm:"-"`
+	events  []string `gorm:"-"`
+}
+
+func (*testHookedTask) TableName() string {
+	return "test_hooked_tasks"
+}
+
+func (t *testHookedTask) Init() {
+	if t.BaseEntity == nil {
+		t.BaseEntity = &BaseEntity{}
+	}
+}
+
+func (t *testHookedTask) OnBeforeCreate(ctx context.Context) error {
+	if t.Title == "" {
+		return errors.New(errors.ErrValidation, "title is required")
+	}
+	t.Slug = strings.ToLower(t.Title)
+	t.events = append(t.events, "before_create")
+	return nil
+}
+
+func (t *testHookedTask) OnAfterCreate(ctx context.Context) error {
+	t.events = append(t.events, "after_create")
+	if t.Title == "Fail" {
+		return errors.New(errors.ErrInternal, "after create failed")
+	}
+	return nil
+}
+
+func (t *testHookedTask) OnBeforeUpdate(ctx context.Context, changes map[string]interface{}) error {
+	if title, ok := changes["title"].(string); ok {
+		changes["slug"] = strings.ToLower(title)
+	}
+	return nil
+}
+
+func (t *testHookedTask) OnBeforeDelete(ctx context.Context) error {
+	if t.Title == "Locked" {
+		return errors.New(errors.ErrForbidden, "task is locked")
+	}
+	return nil
+}
+
+func (t *testHookedTask) OnAfterFind(ctx context.Context) error {
+	t.Display = "#" + t.Slug
+	return nil
+}
+
+func TestEntityHooks(t *testing.T) {
+	_, tasks := setupTestRepository(t, &testHookedTask{})
+
+	repo := &Repository[*testHookedTask]{
+		crudRepo:    tasks,
+		entityType:  reflect.TypeOf(testHookedTask{}),
+		deleteHooks: hasDeleteHooks(&testHookedTask{}),
+	}
+	ctx := context.Background()
+
+	task := &testHookedTask{BaseEntity: &BaseEntity{}, Title: "Write"}
+	require.NoError(t, repo.Create(ctx, task))
+	require.Equal(t, []string{"before_create", "after_create"}, task.events)
+	require.Equal(t, "write", task.Slug)
+
+	// Before 钩子返回的 AppError 中止操作
+	err := repo.Create(ctx, &testHookedTask{BaseEntity: &BaseEntity{}})
+	require.True(t, errors.Is(err, errors.ErrValidation))
+	err = repo.BatchCreate(ctx, []*testHookedTask{{BaseEntity: &BaseEntity{}, Title: "Ok"}, {BaseEntity: &BaseEntity{}}})
+	require.True(t, errors.Is(err, errors.ErrValidation))
+
+	// 事务中 After 钩子返回错误时回滚
+	err = repo.Transaction(ctx, func(tx IRepository[*testHookedTask]) error {
+		return tx.Create(ctx, &testHookedTask{BaseEntity: &BaseEntity{}, Title: "Fail"})
+	})
+	require.True(t, errors.Is(err, errors.ErrInternal))
+	count, err := repo.Count(ctx, &testHookedTask{})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+
+	require.NoError(t, repo.Update(ctx, task, map[string]interface{}{"title": "Review"}))
+	found, err := repo.FindById(ctx, task.ID)
+	require.NoError(t, err)
+	require.Equal(t, "review", found.Slug)
+	require.Equal(t, "#review", found.Display)
+	items, err := repo.Find(ctx, &testHookedTask{}, options.NewQueryOptions())
+	require.NoError(t, err)
+	require.Equal(t, "#review", items[0].Display)
+	// 链式查询同样调用查询后钩子
+	first, err := repo.Query().Where("title = ?", "Review").First(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "#review", first.Display)
+	items, err = repo.Query().Order("id desc").Limit(1).Find(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "#review", items[0].Display)
+
+	locked := &testHookedTask{BaseEntity: &BaseEntity{}, Title: "Locked"}
+	require.NoError(t, repo.Create(ctx, locked))
+	err = repo.DeleteById(ctx, locked.ID)
+	require.True(t, errors.Is(err, errors.ErrForbidden))
+	err = repo.Transaction(ctx, func(tx IRepository[*testHookedTask]) error {
+		return tx.BatchDelete(ctx, []any{task.ID, locked.ID})
+	})
+	require.True(t, errors.Is(err, errors.ErrForbidden))
+	count, err = repo.Count(ctx, &testHookedTask{})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+	require.NoError(t, repo.DeleteById(ctx, task.ID))
+}
@@ -0,0 +1,16 @@
+package module
+
+import (
+	"context"
+
+	"github.com/kruily/gofastcrud/core/crud/types"
+)
+
+type IAuditWriter interface {
+	IModule
+	// Write 写入审计记录
+	Write(ctx context.Context, logs ...*types.AuditLog) error
+
+	// History 按时间倒序分页查询实体的审计记录，返回记录与总数
+	History(ctx context.Context, entity string, entityID string, page int, pageSize int) ([]*types.AuditLog, int64, error)
+}
@@ -0,0 +1,16 @@
+package module
+
+import (
+	"context"
+
+	"github.com/kruily/gofastcrud/core/crud/types"
+)
+
+type IBatchJobStore interface {
+	IModule
+	// Save 保存任务状态，不存在时创建
+	Save(ctx context.Context, job *types.BatchJob) error
+
+	// Get 获取任务，不存在时返回 ErrNotFound
+	Get(ctx context.Context, id string) (*types.BatchJob, error)
+}
@@ -5,4 +5,5 @@ type ICrudResponse interface {
 	Success(data interface{}) interface{}
 	Error(err error) interface{}
 	Pagenation(items interface{}, total int64, page int, size int) interface{}
+	CursorPagenation(items interface{}, nextCursor string, prevCursor string, limit int) interface{}
 }
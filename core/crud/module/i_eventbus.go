@@ -1,5 +1,16 @@
 package module
 
+import (
+	"context"
+
+	"github.com/kruily/gofastcrud/eventbus"
+)
+
 type IEventBus interface {
 	IModule
+	// Publish 同步发布事件，处理器返回错误时返回该错误
+	Publish(ctx context.Context, event eventbus.Event) error
+
+	// PublishAsync 异步发布事件
+	PublishAsync(ctx context.Context, event eventbus.Event)
 }
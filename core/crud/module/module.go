@@ -16,6 +16,9 @@ const (
 	CasbinService   = "Casbin"
 	EventBusService = "EventBus"
 	FactoryService  = "Factory"
+	BatchJobService = "BatchJob"
+	AuditService    = "Audit"
+	OutboxService   = "Outbox"
 )
 
 // CRUD_MODULE CRUD模组 全局变量
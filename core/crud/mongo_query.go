@@ -0,0 +1,177 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mongoQueryFilter 将查询选项转换为 bson 过滤条件
+func mongoQueryFilter(opts *options.QueryOptions) bson.M {
+	return mongoFilterOf(opts, func(bool) bool { return true })
+}
+
+// mongoQueryFilters 将查询选项拆分为主集合条件与关联条件
+// 关联条件引用 $lookup 写入的关联文档，需在连接后匹配；其余条件在连接前匹配，全文检索的 $text 必须位于管道首个 $match 中
+func mongoQueryFilters(opts *options.QueryOptions) (bson.M, bson.M) {
+	main := mongoFilterOf(opts, func(relation bool) bool { return !relation })
+	relation := mongoFilterOf(opts, func(relation bool) bool { return relation })
+	return main, relation
+}
+
+// mongoFilterOf 构建过滤条件，keep 根据条件是否引用关联文档决定是否保留
+func mongoFilterOf(opts *options.QueryOptions, keep func(relation bool) bool) bson.M {
+	filter := bson.M{}
+	if opts == nil {
+		return filter
+	}
+	aliases := make(map[string]bool, len(opts.Joins))
+	for _, join := range opts.Joins {
+		aliases[join.Alias] = true
+	}
+	isRelation := func(condition bson.M) bool {
+		for path := range condition {
+			if alias, _, found := strings.Cut(path, "."); found && aliases[alias] {
+				return true
+			}
+		}
+		return false
+	}
+	for key, value := range opts.Filter {
+		if keep(isRelation(bson.M{key: value})) {
+			filter[key] = value
+		}
+	}
+	ands := make(bson.A, 0, len(opts.Conditions)+len(opts.Where)+2)
+	if search := mongoSearchFilter(opts); search != nil && keep(false) {
+		ands = append(ands, search)
+	}
+	for query, value := range opts.Where {
+		if condition := mongoWhereCondition(query, value, nil); keep(isRelation(condition)) {
+			ands = append(ands, condition)
+		}
+	}
+	for _, c := range opts.Conditions {
+		if condition := mongoCondition(c); keep(isRelation(condition)) {
+			ands = append(ands, condition)
+		}
+	}
+	if opts.FilterExpr != nil {
+		used := map[string]bool{}
+		collectRelationAliases(*opts.FilterExpr, used)
+		relation := false
+		for alias := range used {
+			relation = relation || aliases[alias]
+		}
+		if keep(relation) {
+			ands = append(ands, mongoFilterExpr(*opts.FilterExpr))
+		}
+	}
+	if len(ands) > 0 {
+		filter["$and"] = ands
+	}
+	return filter
+}
+
+// mongoWhereCondition 将 field op ? 形式的条件转换为 bson 条件，不符合该形式时按字段相等处理
+// storageName 用于将字段名转换为 bson 名称，为 nil 时保持原名
+func mongoWhereCondition(query string, value interface{}, storageName func(string) string) bson.M {
+	matches := mongoWherePattern.FindStringSubmatch(query)
+	if matches == nil {
+		return bson.M{query: value}
+	}
+	name := matches[1]
+	if storageName != nil {
+		name = storageName(name)
+	}
+	switch op := strings.ToLower(matches[2]); op {
+	case "like", "not like":
+		regex := primitive.Regex{Pattern: likePattern(fmt.Sprint(value)), Options: "i"}
+		if op == "like" {
+			return bson.M{name: regex}
+		}
+		return bson.M{name: bson.M{"$not": regex}}
+	default:
+		return bson.M{name: bson.M{mongoWhereOperators[op]: value}}
+	}
+}
+
+// mongoProjection 将选择的字段转换为 bson 投影，未选择字段时返回 nil
+func mongoProjection(entityType reflect.Type, fields []string) bson.M {
+	if len(fields) == 0 {
+		return nil
+	}
+	projection := bson.M{}
+	for _, field := range fields {
+		if f, ok := lookupEntityField(entityType, field); ok {
+			field = f.BSON
+		}
+		projection[field] = 1
+	}
+	return projection
+}
+
+// mongoCondition 将过滤条件转换为 bson 条件，关联字段使用 $lookup 输出的 别名.字段 路径
+func mongoCondition(c options.Condition) bson.M {
+	if c.Table != "" {
+		c.Field = c.Table + "." + c.Field
+	}
+	switch c.Op {
+	case options.OpNeq:
+		return bson.M{c.Field: bson.M{"$ne": c.Value}}
+	case options.OpGt, options.OpGte, options.OpLt, options.OpLte, options.OpIn:
+		return bson.M{c.Field: bson.M{"$" + c.Op: c.Value}}
+	case options.OpNin:
+		return bson.M{c.Field: bson.M{"$nin": c.Value}}
+	case options.OpLike:
+		return bson.M{c.Field: primitive.Regex{Pattern: regexp.QuoteMeta(fmt.Sprint(c.Value)), Options: "i"}}
+	case options.OpNlike:
+		return bson.M{c.Field: bson.M{"$not": primitive.Regex{Pattern: regexp.QuoteMeta(fmt.Sprint(c.Value)), Options: "i"}}}
+	case options.OpBetween:
+		if values, ok := c.Value.([]interface{}); ok && len(values) == 2 {
+			return bson.M{c.Field: bson.M{"$gte": values[0], "$lte": values[1]}}
+		}
+	case options.OpNull:
+		if isNull, _ := c.Value.(bool); isNull {
+			return bson.M{c.Field: nil}
+		}
+		return bson.M{c.Field: bson.M{"$ne": nil}}
+	}
+	return bson.M{c.Field: c.Value}
+}
+
+// mongoKeysetFilter 构建 keyset 条件：{$or: [{k1: {$gt: v1}}, {k1: v1, k2: {$gt: v2}}, ...]}
+func mongoKeysetFilter(keys []cursorKey, backward bool) bson.M {
+	ors := make(bson.A, 0, len(keys))
+	for i, key := range keys {
+		cond := bson.M{}
+		for _, prev := range keys[:i] {
+			cond[prev.Name] = prev.value
+		}
+		op := "$lt"
+		if keysetAscending(key, backward) {
+			op = "$gt"
+		}
+		cond[key.Name] = bson.M{op: key.value}
+		ors = append(ors, cond)
+	}
+	return bson.M{"$or": ors}
+}
+
+// mongoSortFields 根据游标键生成 qmgo 排序参数
+func mongoSortFields(keys []cursorKey, backward bool) []string {
+	sorts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if keysetAscending(key, backward) {
+			sorts = append(sorts, key.Name)
+		} else {
+			sorts = append(sorts, "-"+key.Name)
+		}
+	}
+	return sorts
+}
@@ -0,0 +1,78 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testMongoMember mongodb 查询测试用成员
+type testMongoMember struct {
+	BaseMongoEntity `bson:",inline"`
+	UserName        string `json:"userName" bson:"user_name"`
+	Age             int    `json:"age" bson:"age"`
+}
+
+func (*testMongoMember) TableName() string {
+	return "test_mongo_members"
+}
+
+func (*testMongoMember) Init() {}
+
+func TestMongoQueryOptions(t *testing.T) {
+	entityType := reflect.TypeOf(testMongoMember{})
+	opts := options.NewQueryOptions(
+		options.WithPage(2),
+		options.WithPageSize(10),
+		options.WithOrderBy("userName desc"),
+		options.WithSelect([]string{"userName"}),
+	)
+	opts.Where["age >= ?"] = 18
+	opts.Where["user_name"] = "tom"
+
+	findOpts, err := mongoFindOptionsOf(entityType, opts)
+	require.NoError(t, err)
+	require.Equal(t, []string{"-user_name"}, findOpts.sorts)
+	require.Equal(t, bson.M{"user_name": 1}, findOpts.projection)
+	require.Equal(t, int64(10), findOpts.skip)
+	require.Equal(t, int64(10), findOpts.limit)
+	require.False(t, findOpts.textScore)
+
+	filter := mongoQueryFilter(opts)
+	require.ElementsMatch(t, bson.A{
+		bson.M{"age": bson.M{"$gte": 18}},
+		bson.M{"user_name": "tom"},
+	}, filter["$and"])
+
+	// 全文检索按相关度排序
+	options.WithSearch("go")(opts)
+	options.WithSearchFields([]string{"user_name"})(opts)
+	options.WithSearchMode(options.SearchModeFullText)(opts)
+	options.WithSearchRank(true)(opts)
+	findOpts, err = mongoFindOptionsOf(entityType, opts)
+	require.NoError(t, err)
+	require.True(t, findOpts.textScore)
+
+	_, err = mongoFindOptionsOf(entityType, options.NewQueryOptions(options.WithOrderBy("age; drop")))
+	require.Error(t, err)
+
+	// Where 条件同时支持 field op ? 与文档
+	query := newMongoQuery[*testMongoMember](nil, entityType)
+	cond, err := query.condition(queryCond{query: "userName like ?", args: []interface{}{"to%"}})
+	require.NoError(t, err)
+	require.Equal(t, bson.M{"user_name": primitive.Regex{Pattern: "^to.*$", Options: "i"}}, cond)
+	// 过滤参数的 like 与 Where 一致，不区分大小写
+	require.Equal(t, bson.M{"user_name": primitive.Regex{Pattern: `a\.b`, Options: "i"}},
+		mongoCondition(options.Condition{Field: "user_name", Op: options.OpLike, Value: "a.b"}))
+	require.Equal(t, bson.M{"user_name": bson.M{"$not": primitive.Regex{Pattern: "to", Options: "i"}}},
+		mongoCondition(options.Condition{Field: "user_name", Op: options.OpNlike, Value: "to"}))
+	cond, err = query.condition(queryCond{query: struct {
+		Age int `bson:"age"`
+	}{Age: 3}})
+	require.NoError(t, err)
+	require.Equal(t, bson.M{"age": int32(3)}, cond)
+}
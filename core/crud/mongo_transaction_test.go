@@ -0,0 +1,125 @@
+package crud
+
+import (
+	"context"
+	stderrors "errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/qiniu/qmgo"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongooptions "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testMongoTransactionURI 事务测试使用的副本集地址，未设置时跳过需要连接 MongoDB 的用例
+// 本地可通过 mongod --replSet rs0 启动并执行 rs.initiate() 后设置，如 mongodb://localhost:27017/?replicaSet=rs0
+const testMongoTransactionURI = "GOFASTCRUD_MONGO_URI"
+
+func TestMongoTransactionSession(t *testing.T) {
+	// 未配置客户端时返回明确的错误
+	repo := &mongoRepository[*testMongoMember]{entityType: reflect.TypeOf(testMongoMember{})}
+	err := repo.Transaction(context.Background(), func(tx IRepository[*testMongoMember]) error {
+		t.Fatal("callback must not run without a client")
+		return nil
+	})
+	require.True(t, errors.Is(err, errors.ErrTransactionNotSupported))
+
+	require.True(t, mongoTransactionUnsupported(qmgo.ErrTransactionNotSupported))
+	require.True(t, mongoTransactionUnsupported(mongo.CommandError{Code: 20, Message: "Transaction numbers are only allowed on a replica set member or mongos"}))
+	require.False(t, mongoTransactionUnsupported(mongo.CommandError{Code: 112, Message: "WriteConflict"}))
+	require.False(t, mongoTransactionUnsupported(nil))
+
+	// 事务中的仓储将调用方上下文绑定到事务会话，上下文中的值保持不变
+	client, err := mongo.Connect(context.Background(), mongooptions.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	require.NoError(t, err)
+	defer client.Disconnect(context.Background())
+	session, err := client.StartSession()
+	require.NoError(t, err)
+	defer session.EndSession(context.Background())
+
+	tx := repo.withSession(session)
+	type key struct{}
+	ctx := tx.bind(context.WithValue(context.Background(), key{}, "acme"))
+	require.Equal(t, session, mongo.SessionFromContext(ctx))
+	require.Equal(t, "acme", ctx.Value(key{}))
+	require.Equal(t, session, tx.LockForUpdate().(*mongoRepository[*testMongoMember]).session)
+	require.Nil(t, mongo.SessionFromContext(repo.bind(context.Background())))
+
+	// 已在事务中时加入当前事务
+	joined := false
+	require.NoError(t, tx.Transaction(context.Background(), func(inner IRepository[*testMongoMember]) error {
+		joined = inner == IRepository[*testMongoMember](tx)
+		return nil
+	}))
+	require.True(t, joined)
+}
+
+func TestMongoTransaction(t *testing.T) {
+	uri := os.Getenv(testMongoTransactionURI)
+	if uri == "" {
+		t.Skipf("%s is not set, skipping mongodb replica set tests", testMongoTransactionURI)
+	}
+	ctx := context.Background()
+	client, err := qmgo.NewClient(ctx, &qmgo.Config{Uri: uri})
+	require.NoError(t, err)
+	defer client.Close(ctx)
+	db := client.Database("gofastcrud_test")
+	require.NoError(t, db.Collection((&testMongoMember{}).TableName()).DropCollection(ctx))
+
+	repo := &Repository[*testMongoMember]{
+		crudRepo:   newMongoRepository(client, db, &testMongoMember{}),
+		entityType: reflect.TypeOf(testMongoMember{}),
+	}
+	newMember := func(name string) *testMongoMember {
+		return &testMongoMember{BaseMongoEntity: BaseMongoEntity{CreatedAt: time.Now()}, UserName: name}
+	}
+
+	// 提交
+	require.NoError(t, repo.Transaction(ctx, func(tx IRepository[*testMongoMember]) error {
+		return tx.BatchCreate(ctx, []*testMongoMember{newMember("tom"), newMember("amy")})
+	}))
+	count, err := repo.Count(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	// 回滚：事务内可读到自己的写入，提交前其他会话不可见
+	rollback := stderrors.New("rollback")
+	err = repo.Transaction(ctx, func(tx IRepository[*testMongoMember]) error {
+		if err := tx.Create(ctx, newMember("bob")); err != nil {
+			return err
+		}
+		exists, err := tx.Exists(ctx, bson.M{"user_name": "bob"})
+		require.NoError(t, err)
+		require.True(t, exists)
+		exists, err = repo.Exists(ctx, bson.M{"user_name": "bob"})
+		require.NoError(t, err)
+		require.False(t, exists)
+		return rollback
+	})
+	require.ErrorIs(t, err, rollback)
+	exists, err := repo.Exists(ctx, bson.M{"user_name": "bob"})
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// 批量更新与删除
+	members, err := repo.FindAll(ctx, bson.M{})
+	require.NoError(t, err)
+	for _, member := range members {
+		member.UserName += "!"
+	}
+	require.NoError(t, repo.Transaction(ctx, func(tx IRepository[*testMongoMember]) error {
+		if err := tx.BatchUpdate(ctx, members); err != nil {
+			return err
+		}
+		return tx.BatchDelete(ctx, []any{members[0].Id})
+	}))
+	remaining, err := repo.FindAll(ctx, bson.M{})
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	require.Equal(t, members[1].UserName, remaining[0].UserName)
+}
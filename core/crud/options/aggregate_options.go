@@ -0,0 +1,104 @@
+package options
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// 聚合函数
+const (
+	AggregateSum   = "sum"
+	AggregateCount = "count"
+	AggregateMax   = "max"
+	AggregateMin   = "min"
+	AggregateAvg   = "avg"
+)
+
+// IsAggregateFunc 检查是否为支持的聚合函数
+func IsAggregateFunc(fn string) bool {
+	switch fn {
+	case AggregateSum, AggregateCount, AggregateMax, AggregateMin, AggregateAvg:
+		return true
+	}
+	return false
+}
+
+// Having 分组结果过滤条件，作用于聚合值
+type Having struct {
+	Op    string // gt gte lt lte eq neq
+	Value float64
+}
+
+// HavingOperators having 支持的比较操作符
+var HavingOperators = map[string]string{
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+	"eq":  "=",
+	"neq": "<>",
+}
+
+// ParseHaving 解析 having 表达式，格式为 op:value，如 gt:10
+func ParseHaving(expr string) (*Having, error) {
+	parts := strings.SplitN(expr, ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("having must be in the form op:value")
+	}
+	if _, ok := HavingOperators[parts[0]]; !ok {
+		return nil, errors.New("invalid having operator: " + parts[0])
+	}
+	value, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return nil, errors.New("invalid having value: " + parts[1])
+	}
+	return &Having{Op: parts[0], Value: value}, nil
+}
+
+// AggregateOptions 聚合选项
+type AggregateOptions struct {
+	// 聚合函数
+	Func string
+	// 聚合字段
+	Field string
+	// 分组字段
+	GroupBy []string
+	// 分组结果过滤
+	Having *Having
+	// 过滤条件（仅使用搜索与过滤部分）
+	Query *QueryOptions
+}
+
+// NewAggregateOptions 创建聚合选项
+func NewAggregateOptions(fn string, field string, opts ...func(*AggregateOptions)) *AggregateOptions {
+	options := &AggregateOptions{
+		Func:  fn,
+		Field: field,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// WithGroupBy 设置分组字段
+func WithGroupBy(groupBy ...string) func(*AggregateOptions) {
+	return func(a *AggregateOptions) {
+		a.GroupBy = groupBy
+	}
+}
+
+// WithHaving 设置分组结果过滤
+func WithHaving(having *Having) func(*AggregateOptions) {
+	return func(a *AggregateOptions) {
+		a.Having = having
+	}
+}
+
+// WithQuery 设置过滤条件
+func WithQuery(query *QueryOptions) func(*AggregateOptions) {
+	return func(a *AggregateOptions) {
+		a.Query = query
+	}
+}
@@ -0,0 +1,96 @@
+package options
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// 过滤操作符
+const (
+	OpEq      = "eq"
+	OpNeq     = "neq"
+	OpGt      = "gt"
+	OpGte     = "gte"
+	OpLt      = "lt"
+	OpLte     = "lte"
+	OpIn      = "in"
+	OpNin     = "nin"
+	OpLike    = "like"
+	OpNlike   = "nlike"
+	OpBetween = "between"
+	OpNull    = "null"
+)
+
+// FilterOperators 支持的全部过滤操作符
+var FilterOperators = []string{OpGt, OpGte, OpLt, OpLte, OpEq, OpNeq, OpIn, OpNin, OpLike, OpNlike, OpBetween, OpNull}
+
+// Condition 过滤条件，Field 为存储字段名（列名或 bson 名），Table 为关联别名，为空表示主表
+// in/nin 的 Value 为 []interface{}，between 为两个元素的 []interface{}，null 为 bool，like/nlike 为不含通配符的字符串
+type Condition struct {
+	Table string
+	Field string
+	Op    string
+	Value interface{}
+}
+
+// Expression 转换为 gorm 条件表达式，列名带表名限定以免与关联表重名
+func (c Condition) Expression() (clause.Expression, error) {
+	column := clause.Column{Table: clause.CurrentTable, Name: c.Field}
+	if c.Table != "" {
+		column.Table = c.Table
+	}
+	switch c.Op {
+	case OpEq:
+		return clause.Eq{Column: column, Value: c.Value}, nil
+	case OpNeq:
+		return clause.Neq{Column: column, Value: c.Value}, nil
+	case OpGt:
+		return clause.Gt{Column: column, Value: c.Value}, nil
+	case OpGte:
+		return clause.Gte{Column: column, Value: c.Value}, nil
+	case OpLt:
+		return clause.Lt{Column: column, Value: c.Value}, nil
+	case OpLte:
+		return clause.Lte{Column: column, Value: c.Value}, nil
+	case OpIn, OpNin:
+		values, ok := c.Value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s requires a list value", c.Op)
+		}
+		if c.Op == OpNin {
+			return clause.Not(clause.IN{Column: column, Values: values}), nil
+		}
+		return clause.IN{Column: column, Values: values}, nil
+	case OpLike:
+		return containsLike(column, c.Value, false), nil
+	case OpNlike:
+		return containsLike(column, c.Value, true), nil
+	case OpBetween:
+		values, ok := c.Value.([]interface{})
+		if !ok || len(values) != 2 {
+			return nil, fmt.Errorf("between requires two values")
+		}
+		return clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []interface{}{column, values[0], values[1]}}, nil
+	case OpNull:
+		if isNull, _ := c.Value.(bool); isNull {
+			return clause.Eq{Column: column, Value: nil}, nil
+		}
+		return clause.Neq{Column: column, Value: nil}, nil
+	}
+	return nil, fmt.Errorf("unsupported operator %s", c.Op)
+}
+
+// containsLike 生成包含匹配条件 column LIKE '%value%'，value 中的 \、%、_ 按字面匹配
+// 转义字符以参数传入 ESCAPE，避免各方言字符串字面量中反斜杠含义不同
+func containsLike(column clause.Column, value interface{}, not bool) clause.Expression {
+	sql := "? LIKE ? ESCAPE ?"
+	if not {
+		sql = "? NOT LIKE ? ESCAPE ?"
+	}
+	return clause.Expr{SQL: sql, Vars: []interface{}{column, "%" + likeEscaper.Replace(fmt.Sprint(value)) + "%", `\`}}
+}
+
+// likeEscaper 转义 LIKE 模式中的通配符与转义字符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
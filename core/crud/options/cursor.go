@@ -0,0 +1,82 @@
+package options
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidCursor 游标无法解析
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor 游标分页令牌
+// Keys 为排序字段（最后一个为ID兜底字段），Values 为对应行的字段值
+type Cursor struct {
+	Keys     []string          `json:"k"`
+	Values   []json.RawMessage `json:"v"`
+	Backward bool              `json:"b,omitempty"` // 是否向前翻页
+}
+
+// EncodeCursor 编码游标为不透明字符串
+func EncodeCursor(c *Cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor 解码游标
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := &Cursor{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if len(c.Keys) == 0 || len(c.Keys) != len(c.Values) {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// OrderField 排序字段
+type OrderField struct {
+	Field string
+	Desc  bool
+}
+
+// orderFieldPattern 排序字段名只允许标识符
+var orderFieldPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// ParseOrderBy 解析排序表达式，如 ["created_at desc, id asc"]
+func ParseOrderBy(orderBy []string) ([]OrderField, error) {
+	fields := make([]OrderField, 0)
+	for _, order := range orderBy {
+		for _, part := range strings.Split(order, ",") {
+			tokens := strings.Fields(part)
+			if len(tokens) == 0 {
+				continue
+			}
+			if len(tokens) > 2 || !orderFieldPattern.MatchString(tokens[0]) {
+				return nil, errors.New("invalid order_by: " + strings.TrimSpace(part))
+			}
+			field := OrderField{Field: tokens[0]}
+			if len(tokens) == 2 {
+				switch strings.ToLower(tokens[1]) {
+				case "asc":
+				case "desc":
+					field.Desc = true
+				default:
+					return nil, errors.New("invalid order direction: " + tokens[1])
+				}
+			}
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
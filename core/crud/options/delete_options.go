@@ -16,7 +16,16 @@ type DeleteOptions struct {
 func NewDeleteOptions(opts ...*DeleteOptions) *DeleteOptions {
 	opt := &DeleteOptions{}
 	for _, o := range opts {
-		*opt = *o
+		if o == nil {
+			continue
+		}
+		opt.Force = opt.Force || o.Force
+		if !o.DeletedAt.IsZero() {
+			opt.DeletedAt = o.DeletedAt
+		}
+		if o.DeletedBy != "" {
+			opt.DeletedBy = o.DeletedBy
+		}
 	}
 	return opt
 }
@@ -0,0 +1,38 @@
+package options
+
+import (
+	"gorm.io/gorm"
+)
+
+// Expand 关联展开，按路径由浅到深排列，上级路径总是先于下级出现
+type Expand struct {
+	Path          string   // 关联路径，gorm 为 Go 字段名、mongodb 为 bson 名，以 . 分隔
+	Table         string   // 关联表（集合）名
+	LocalColumn   string   // 上级实体连接列
+	ForeignColumn string   // 关联实体连接列
+	Many          bool     // 是否一对多
+	DeletedAt     string   // 关联实体软删除列，mongodb 据此排除已删除记录（gorm 由 Preload 自动处理）
+	Select        []string // 关联实体选择的列，为空表示全部
+}
+
+// WithExpand 设置关联展开
+func WithExpand(expands ...Expand) func(*QueryOptions) {
+	return func(q *QueryOptions) {
+		q.Expand = expands
+	}
+}
+
+// applyExpand 使用 Preload 加载关联，指定了列时只查询这些列
+func (q *QueryOptions) applyExpand(db *gorm.DB) *gorm.DB {
+	for _, expand := range q.Expand {
+		if len(expand.Select) == 0 {
+			db = db.Preload(expand.Path)
+			continue
+		}
+		columns := expand.Select
+		db = db.Preload(expand.Path, func(tx *gorm.DB) *gorm.DB {
+			return tx.Select(columns)
+		})
+	}
+	return db
+}
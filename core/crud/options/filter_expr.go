@@ -0,0 +1,62 @@
+package options
+
+import (
+	"fmt"
+
+	"gorm.io/gorm/clause"
+)
+
+// FilterExpr 过滤表达式树，每个节点只能是 And、Or、Not、Condition 之一
+type FilterExpr struct {
+	And       []FilterExpr
+	Or        []FilterExpr
+	Not       *FilterExpr
+	Condition *Condition
+}
+
+// Expression 转换为 gorm 条件表达式，分组会加括号
+func (e FilterExpr) Expression() (clause.Expression, error) {
+	switch {
+	case e.Condition != nil:
+		return e.Condition.Expression()
+	case e.Not != nil:
+		expr, err := e.Not.Expression()
+		if err != nil {
+			return nil, err
+		}
+		return clause.Not(expr), nil
+	case len(e.And) > 0:
+		exprs, err := filterExpressions(e.And)
+		if err != nil {
+			return nil, err
+		}
+		return clause.And(exprs...), nil
+	case len(e.Or) > 0:
+		exprs, err := filterExpressions(e.Or)
+		if err != nil {
+			return nil, err
+		}
+		return clause.Or(exprs...), nil
+	}
+	return nil, fmt.Errorf("empty filter expression")
+}
+
+// filterExpressions 转换子表达式
+func filterExpressions(children []FilterExpr) ([]clause.Expression, error) {
+	exprs := make([]clause.Expression, 0, len(children))
+	for _, child := range children {
+		expr, err := child.Expression()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	return exprs, nil
+}
+
+// WithFilterExpr 设置过滤表达式
+func WithFilterExpr(expr *FilterExpr) func(*QueryOptions) {
+	return func(q *QueryOptions) {
+		q.FilterExpr = expr
+	}
+}
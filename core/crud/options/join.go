@@ -0,0 +1,58 @@
+package options
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Join 关联字段过滤与排序所需的 LEFT JOIN（mongodb 为 $lookup），仅支持单值关联
+type Join struct {
+	Table         string // 关联表（集合）名
+	Alias         string // 别名，即关联字段的 json 名称
+	LocalColumn   string // 主表连接列
+	ForeignColumn string // 关联表连接列
+	DeletedAt     string // 关联表软删除列，非空时只连接未删除的记录
+}
+
+// Expression 转换为 gorm 连接条件
+func (j Join) Expression() clause.Expr {
+	on := clause.Expr{
+		SQL:  "LEFT JOIN ? ? ON ? = ?",
+		Vars: []interface{}{clause.Table{Name: j.Table}, clause.Table{Name: j.Alias}, clause.Column{Table: j.Alias, Name: j.ForeignColumn}, clause.Column{Table: clause.CurrentTable, Name: j.LocalColumn}},
+	}
+	if j.DeletedAt != "" {
+		on.SQL += " AND ? IS NULL"
+		on.Vars = append(on.Vars, clause.Column{Table: j.Alias, Name: j.DeletedAt})
+	}
+	return on
+}
+
+// WithJoins 追加关联连接，同一别名只连接一次
+func WithJoins(joins ...Join) func(*QueryOptions) {
+	return func(q *QueryOptions) {
+		for _, join := range joins {
+			if !q.HasJoin(join.Alias) {
+				q.Joins = append(q.Joins, join)
+			}
+		}
+	}
+}
+
+// HasJoin 判断是否已连接别名
+func (q *QueryOptions) HasJoin(alias string) bool {
+	for _, join := range q.Joins {
+		if join.Alias == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// applyJoins 应用关联连接
+func (q *QueryOptions) applyJoins(db *gorm.DB) *gorm.DB {
+	for _, join := range q.Joins {
+		expr := join.Expression()
+		db = db.Joins(expr.SQL, expr.Vars...)
+	}
+	return db
+}
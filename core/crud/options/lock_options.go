@@ -0,0 +1,32 @@
+package options
+
+// LockOptions 悲观锁选项
+type LockOptions struct {
+	// 无法立即获得锁时直接报错（FOR UPDATE NOWAIT）
+	NoWait bool
+	// 跳过已被锁定的记录（FOR UPDATE SKIP LOCKED），与 NoWait 同时设置时以 NoWait 为准
+	SkipLocked bool
+}
+
+// NewLockOptions 创建锁选项
+func NewLockOptions(opts ...*LockOptions) *LockOptions {
+	opt := &LockOptions{}
+	for _, o := range opts {
+		if o == nil {
+			continue
+		}
+		opt.NoWait = opt.NoWait || o.NoWait
+		opt.SkipLocked = opt.SkipLocked || o.SkipLocked
+	}
+	return opt
+}
+
+// WithNoWait 无法立即获得锁时直接报错
+func WithNoWait() *LockOptions {
+	return &LockOptions{NoWait: true}
+}
+
+// WithSkipLocked 跳过已被锁定的记录
+func WithSkipLocked() *LockOptions {
+	return &LockOptions{SkipLocked: true}
+}
@@ -1,7 +1,10 @@
 package options
 
 import (
+	"strings"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // QueryOptions 查询选项
@@ -15,14 +18,32 @@ type QueryOptions struct {
 	Where map[string]interface{}
 	// 预加载关系
 	Preload []string
+	// 经过校验的关联展开
+	Expand []Expand
 	// 选择特定字段
 	Select []string
 	// 搜索关键词
 	Search string
 	// 搜索字段
 	SearchFields []string
+	// 搜索方式，为空时使用 SearchModeLike
+	SearchMode string
+	// 全文检索时按相关度排序，相关度优先于 OrderBy
+	SearchRank bool
 	// 过滤条件
 	Filter map[string]interface{}
+	// 按实体 filter 标签编译的过滤条件
+	Conditions []Condition
+	// 可嵌套 and/or/not 的过滤表达式，与其他条件以 AND 组合
+	FilterExpr *FilterExpr
+	// 关联过滤与排序所需的连接
+	Joins []Join
+	// 游标分页（开启后忽略 Page/PageSize）
+	UseCursor bool
+	// 游标令牌，为空表示第一页
+	Cursor string
+	// 游标分页每页数量
+	Limit int
 }
 
 // isSpecialParam 检查是否为特殊参数
@@ -30,7 +51,12 @@ func isSpecialParam(key string) bool {
 	specialParams := []string{
 		"page", "page_size", "order_by",
 		"search", "search_fields", "preload",
-		"fields",
+		"fields", "cursor", "limit",
+		"func", "field", "group_by", "having",
+		"filter", "search_mode", "expand",
+	}
+	if strings.HasPrefix(key, "fields[") {
+		return true
 	}
 	for _, param := range specialParams {
 		if key == param {
@@ -89,6 +115,20 @@ func WithSearchFields(searchFields []string) func(*QueryOptions) {
 	}
 }
 
+// WithSearchMode 设置搜索方式
+func WithSearchMode(mode string) func(*QueryOptions) {
+	return func(q *QueryOptions) {
+		q.SearchMode = mode
+	}
+}
+
+// WithSearchRank 设置全文检索按相关度排序
+func WithSearchRank(rank bool) func(*QueryOptions) {
+	return func(q *QueryOptions) {
+		q.SearchRank = rank
+	}
+}
+
 // WithPreload 设置预加载
 func WithPreload(preload []string) func(*QueryOptions) {
 	return func(q *QueryOptions) {
@@ -110,31 +150,41 @@ func WithFilter(filter map[string]interface{}) func(*QueryOptions) {
 	}
 }
 
-// applyQueryOptions 应用查询选项
-func (q *QueryOptions) ApplyQueryOptions(db *gorm.DB) *gorm.DB {
-	// 应用搜索
-	if q.Search != "" && len(q.SearchFields) > 0 {
-		for _, field := range q.SearchFields {
-			db = db.Or(field+" LIKE ?", "%"+q.Search+"%")
-		}
+// WithConditions 追加过滤条件
+func WithConditions(conditions ...Condition) func(*QueryOptions) {
+	return func(q *QueryOptions) {
+		q.Conditions = append(q.Conditions, conditions...)
 	}
+}
 
-	// 应用过滤条件
-	if len(q.Filter) > 0 {
-		for key, value := range q.Filter {
-			db = db.Where(key, value)
-		}
+// WithCursor 设置游标分页
+func WithCursor(cursor string) func(*QueryOptions) {
+	return func(q *QueryOptions) {
+		q.UseCursor = true
+		q.Cursor = cursor
 	}
+}
 
-	// 应用查询条件
-	if len(q.Where) > 0 {
-		for key, value := range q.Where {
-			db = db.Where(key, value)
-		}
+// WithLimit 设置游标分页每页数量
+func WithLimit(limit int) func(*QueryOptions) {
+	return func(q *QueryOptions) {
+		q.Limit = limit
 	}
+}
+
+// applyQueryOptions 应用查询选项
+func (q *QueryOptions) ApplyQueryOptions(db *gorm.DB) *gorm.DB {
+	// 应用搜索与过滤条件
+	db = q.ApplyFilters(db)
 
-	// 应用排序
-	if len(q.OrderBy) > 0 {
+	// 应用排序，全文检索按相关度排序时相关度优先（游标模式由游标键决定排序）
+	if strategy, table := q.fullTextSearch(db); strategy != nil && q.SearchRank && !q.UseCursor {
+		orders := []clause.Expression{strategy.Rank(table, q.SearchFields, q.Search)}
+		for _, order := range q.OrderBy {
+			orders = append(orders, clause.Expr{SQL: order})
+		}
+		db = db.Order(clause.OrderBy{Expression: clause.CommaExpression{Exprs: orders}})
+	} else if len(q.OrderBy) > 0 {
 		for _, order := range q.OrderBy {
 			db = db.Order(order)
 		}
@@ -146,17 +196,105 @@ func (q *QueryOptions) ApplyQueryOptions(db *gorm.DB) *gorm.DB {
 			db = db.Preload(preload)
 		}
 	}
+	db = q.applyExpand(db)
 
-	// 应用选择特定字段
+	// 应用选择特定字段，存在关联连接时限定为主表字段
 	if len(q.Select) > 0 {
-		db = db.Select(q.Select)
+		selects := q.Select
+		if table := statementTable(db); len(q.Joins) > 0 && table != "" {
+			selects = make([]string, len(q.Select))
+			for i, field := range q.Select {
+				if !strings.Contains(field, ".") {
+					field = table + "." + field
+				}
+				selects[i] = field
+			}
+		}
+		db = db.Select(selects)
 	}
 
-	// 应用分页
-	if q.Page > 0 && q.PageSize > 0 {
+	// 应用分页（游标模式由仓储自行处理）
+	if !q.UseCursor && q.Page > 0 && q.PageSize > 0 {
 		offset := (q.Page - 1) * q.PageSize
 		db = db.Offset(offset).Limit(q.PageSize)
 	}
 
 	return db
 }
+
+// ApplyFilters 仅应用搜索与过滤条件（不含排序、预加载、字段选择与分页），用于统计与聚合
+func (q *QueryOptions) ApplyFilters(db *gorm.DB) *gorm.DB {
+	if q == nil {
+		return db
+	}
+	db = q.applyJoins(db)
+
+	// 应用搜索，全文检索使用方言策略；多个 LIKE 搜索字段之间为 OR 并作为整体与其他条件 AND
+	if strategy, table := q.fullTextSearch(db); strategy != nil {
+		db = db.Where(strategy.Match(table, q.SearchFields, q.Search))
+	} else if q.Search != "" && len(q.SearchFields) > 0 {
+		likes := make([]clause.Expression, 0, len(q.SearchFields))
+		for _, field := range q.SearchFields {
+			likes = append(likes, containsLike(clause.Column{Table: clause.CurrentTable, Name: field}, q.Search, false))
+		}
+		db = db.Where(clause.Or(likes...))
+	}
+
+	// 应用过滤条件
+	if len(q.Filter) > 0 {
+		for key, value := range q.Filter {
+			db = db.Where(key, value)
+		}
+	}
+
+	// 应用编译后的过滤条件
+	for _, condition := range q.Conditions {
+		expr, err := condition.Expression()
+		if err != nil {
+			db.AddError(err)
+			continue
+		}
+		db = db.Where(expr)
+	}
+
+	// 应用过滤表达式
+	if q.FilterExpr != nil {
+		expr, err := q.FilterExpr.Expression()
+		if err != nil {
+			db.AddError(err)
+		} else {
+			db = db.Where(expr)
+		}
+	}
+
+	// 应用查询条件
+	if len(q.Where) > 0 {
+		for key, value := range q.Where {
+			db = db.Where(key, value)
+		}
+	}
+
+	return db
+}
+
+// fullTextSearch 返回当前方言的全文检索策略与表名，未启用全文检索或方言不支持时返回 nil，退化为 LIKE 搜索
+func (q *QueryOptions) fullTextSearch(db *gorm.DB) (SearchStrategy, string) {
+	if q.SearchMode != SearchModeFullText || q.Search == "" || len(q.SearchFields) == 0 {
+		return nil, ""
+	}
+	strategy, ok := GetSearchStrategy(db.Dialector.Name())
+	if !ok {
+		return nil, ""
+	}
+	return strategy, statementTable(db)
+}
+
+// statementTable 返回查询的主表名，尚未解析模型时先解析
+func statementTable(db *gorm.DB) string {
+	if db.Statement.Table == "" && db.Statement.Model != nil {
+		if err := db.Statement.Parse(db.Statement.Model); err != nil {
+			db.AddError(err)
+		}
+	}
+	return db.Statement.Table
+}
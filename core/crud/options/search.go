@@ -0,0 +1,202 @@
+package options
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 搜索方式
+const (
+	SearchModeLike     = "like"     // 各搜索字段 LIKE %keyword% 以 OR 组合
+	SearchModeFullText = "fulltext" // 使用数据库原生全文检索
+)
+
+// SearchStrategy 全文检索策略，按 gorm 方言名（Dialector.Name()）注册
+type SearchStrategy interface {
+	// Match 返回全文匹配条件
+	Match(table string, columns []string, search string) clause.Expression
+	// Rank 返回按相关度从高到低排序的表达式
+	Rank(table string, columns []string, search string) clause.Expression
+	// Migrate 创建全文检索所需的索引，需可重复执行
+	Migrate(db *gorm.DB, table string, columns []string) error
+}
+
+var (
+	searchStrategiesMu sync.RWMutex
+	searchStrategies   = map[string]SearchStrategy{
+		"postgres": PostgresSearch{Config: "simple"},
+		"mysql":    MySQLSearch{},
+		"sqlite":   SQLiteSearch{},
+	}
+)
+
+// RegisterSearchStrategy 注册或替换方言的全文检索策略
+func RegisterSearchStrategy(dialect string, strategy SearchStrategy) {
+	searchStrategiesMu.Lock()
+	defer searchStrategiesMu.Unlock()
+	searchStrategies[dialect] = strategy
+}
+
+// GetSearchStrategy 获取方言的全文检索策略
+func GetSearchStrategy(dialect string) (SearchStrategy, bool) {
+	searchStrategiesMu.RLock()
+	defer searchStrategiesMu.RUnlock()
+	strategy, ok := searchStrategies[dialect]
+	return strategy, ok
+}
+
+// PostgresSearch 基于 tsvector 与 websearch_to_tsquery 的全文检索，索引为表达式 GIN 索引
+type PostgresSearch struct {
+	Config string // 文本搜索配置，如 simple、english
+}
+
+// document 拼接各列生成 tsvector，须与索引表达式一致；table 非空时列名带表名限定，避免连接查询时列名歧义
+func (s PostgresSearch) document(table string, columns []string) string {
+	parts := make([]string, len(columns))
+	for i, column := range columns {
+		parts[i] = fmt.Sprintf(`coalesce(%s, '')`, qualifiedColumn(table, column, '"'))
+	}
+	return fmt.Sprintf("to_tsvector('%s', %s)", s.Config, strings.Join(parts, " || ' ' || "))
+}
+
+// Match 全文匹配条件
+func (s PostgresSearch) Match(table string, columns []string, search string) clause.Expression {
+	return clause.Expr{SQL: fmt.Sprintf("%s @@ websearch_to_tsquery('%s', ?)", s.document(table, columns), s.Config), Vars: []interface{}{search}}
+}
+
+// Rank 按 ts_rank 降序
+func (s PostgresSearch) Rank(table string, columns []string, search string) clause.Expression {
+	return clause.Expr{SQL: fmt.Sprintf("ts_rank(%s, websearch_to_tsquery('%s', ?)) DESC", s.document(table, columns), s.Config), Vars: []interface{}{search}}
+}
+
+// Migrate 创建 GIN 表达式索引
+func (s PostgresSearch) Migrate(db *gorm.DB, table string, columns []string) error {
+	return db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIN (%s)",
+		quoteIdent(searchIndexName(table), '"'), quoteIdent(table, '"'), s.document("", columns))).Error
+}
+
+// MySQLSearch 基于 FULLTEXT 索引与 MATCH ... AGAINST 的全文检索
+type MySQLSearch struct {
+	BooleanMode bool // 使用 IN BOOLEAN MODE，默认 IN NATURAL LANGUAGE MODE
+}
+
+// against 生成 MATCH ... AGAINST 表达式，列顺序须与索引一致，列名带表名限定
+func (s MySQLSearch) against(table string, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = qualifiedColumn(table, column, '`')
+	}
+	mode := "IN NATURAL LANGUAGE MODE"
+	if s.BooleanMode {
+		mode = "IN BOOLEAN MODE"
+	}
+	return fmt.Sprintf("MATCH(%s) AGAINST (? %s)", strings.Join(quoted, ","), mode)
+}
+
+// Match 全文匹配条件
+func (s MySQLSearch) Match(table string, columns []string, search string) clause.Expression {
+	return clause.Expr{SQL: s.against(table, columns), Vars: []interface{}{search}}
+}
+
+// Rank 按相关度降序
+func (s MySQLSearch) Rank(table string, columns []string, search string) clause.Expression {
+	return clause.Expr{SQL: s.against(table, columns) + " DESC", Vars: []interface{}{search}}
+}
+
+// Migrate 创建 FULLTEXT 索引
+func (s MySQLSearch) Migrate(db *gorm.DB, table string, columns []string) error {
+	name := searchIndexName(table)
+	if db.Migrator().HasIndex(table, name) {
+		return nil
+	}
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdent(column, '`')
+	}
+	return db.Exec(fmt.Sprintf("CREATE FULLTEXT INDEX %s ON %s (%s)",
+		quoteIdent(name, '`'), quoteIdent(table, '`'), strings.Join(quoted, ","))).Error
+}
+
+// SQLiteSearch 基于 FTS5 外部内容表的全文检索，表名为 <table>_fts，通过触发器与原表同步
+// 需要 sqlite 编译时启用 FTS5（mattn/go-sqlite3 使用 sqlite_fts5 构建标签），未启用时 Migrate 返回 no such module: fts5
+type SQLiteSearch struct{}
+
+// Match 原表 rowid 命中 FTS 表
+func (SQLiteSearch) Match(table string, columns []string, search string) clause.Expression {
+	fts := quoteIdent(table+"_fts", '"')
+	return clause.Expr{
+		SQL:  fmt.Sprintf("%s.rowid IN (SELECT rowid FROM %s WHERE %s MATCH ?)", quoteIdent(table, '"'), fts, fts),
+		Vars: []interface{}{fts5Query(search)},
+	}
+}
+
+// Rank 按 bm25 排序，值越小越相关
+func (SQLiteSearch) Rank(table string, columns []string, search string) clause.Expression {
+	fts := quoteIdent(table+"_fts", '"')
+	return clause.Expr{
+		SQL:  fmt.Sprintf("(SELECT rank FROM %s WHERE %s.rowid = %s.rowid AND %s MATCH ?) ASC", fts, fts, quoteIdent(table, '"'), fts),
+		Vars: []interface{}{fts5Query(search)},
+	}
+}
+
+// Migrate 创建 FTS5 表与同步触发器，并重建索引
+func (SQLiteSearch) Migrate(db *gorm.DB, table string, columns []string) error {
+	fts := quoteIdent(table+"_fts", '"')
+	quotedTable := quoteIdent(table, '"')
+	quoted := make([]string, len(columns))
+	newValues := make([]string, len(columns))
+	oldValues := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdent(column, '"')
+		newValues[i] = "new." + quoted[i]
+		oldValues[i] = "old." + quoted[i]
+	}
+	cols := strings.Join(quoted, ", ")
+	insertNew := fmt.Sprintf("INSERT INTO %s(rowid, %s) VALUES (new.rowid, %s);", fts, cols, strings.Join(newValues, ", "))
+	deleteOld := fmt.Sprintf("INSERT INTO %s(%s, rowid, %s) VALUES ('delete', old.rowid, %s);", fts, fts, cols, strings.Join(oldValues, ", "))
+	statements := []string{
+		fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(%s, content='%s')", fts, cols, table),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER INSERT ON %s BEGIN %s END", quoteIdent(table+"_fts_ai", '"'), quotedTable, insertNew),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER DELETE ON %s BEGIN %s END", quoteIdent(table+"_fts_ad", '"'), quotedTable, deleteOld),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER UPDATE ON %s BEGIN %s %s END", quoteIdent(table+"_fts_au", '"'), quotedTable, deleteOld, insertNew),
+		fmt.Sprintf("INSERT INTO %s(%s) VALUES ('rebuild')", fts, fts),
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fts5Query 将关键词拆分为带引号的词组，避免 FTS5 查询语法错误
+func fts5Query(search string) string {
+	terms := strings.Fields(search)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(terms, " ")
+}
+
+// searchIndexName 全文索引名
+func searchIndexName(table string) string {
+	return "idx_" + table + "_fts"
+}
+
+// qualifiedColumn 返回带表名限定的列名，table 为空时只返回列名
+func qualifiedColumn(table, column string, quote byte) string {
+	if table == "" {
+		return quoteIdent(column, quote)
+	}
+	return quoteIdent(table, quote) + "." + quoteIdent(column, quote)
+}
+
+// quoteIdent 使用给定引号包裹标识符
+func quoteIdent(name string, quote byte) string {
+	q := string(quote)
+	return q + strings.ReplaceAll(name, q, q+q) + q
+}
@@ -0,0 +1,246 @@
+package crud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kruily/gofastcrud/core/crud/module"
+	"github.com/kruily/gofastcrud/core/crud/types"
+	"github.com/kruily/gofastcrud/core/di"
+	"github.com/kruily/gofastcrud/errors"
+	"gorm.io/gorm"
+)
+
+// eventTypes 实体名称到实体类型的映射，发件箱投递时用于还原事件中的实体
+var eventTypes sync.Map
+
+// registerEventType 登记实体类型，创建仓储时调用
+func registerEventType(entityType reflect.Type) {
+	eventTypes.Store(eventEntityName(entityType.Name()), entityType)
+}
+
+// outboxStore 支持在当前连接（事务）中写入发件箱的仓储实现
+type outboxStore interface {
+	outboxDB() *gorm.DB
+}
+
+// Outbox 事务发件箱：与发件箱使用同一 gorm 实例的实体，异步领域事件与数据变更在同一事务中写入 outbox_messages 表，
+// 由 Run 轮询投递到事件总线，投递成功后标记，失败时记录次数与原因并在下次轮询重试，保证至少投递一次；超过最大投递次数的事件进入死信
+type Outbox struct {
+	db            *gorm.DB
+	bus           module.IEventBus
+	batchSize     int           // 每次轮询投递的事件数
+	maxAttempts   int           // 最大投递次数，超过后进入死信
+	retention     time.Duration // 已投递记录的保留时间
+	deadRetention time.Duration // 死信的保留时间
+	spec          string        // 投递任务的调度表达式
+	mu            sync.Mutex    // 避免同一进程中的轮询重叠
+}
+
+// OutboxOption 发件箱选项
+type OutboxOption func(*Outbox)
+
+// WithOutboxBatchSize 设置每次轮询投递的事件数，默认 100
+func WithOutboxBatchSize(size int) OutboxOption {
+	return func(o *Outbox) {
+		o.batchSize = size
+	}
+}
+
+// WithOutboxMaxAttempts 设置最大投递次数，默认 10，超过后进入死信
+func WithOutboxMaxAttempts(attempts int) OutboxOption {
+	return func(o *Outbox) {
+		o.maxAttempts = attempts
+	}
+}
+
+// WithOutboxRetention 设置已投递记录的保留时间，默认 24 小时，为 0 时投递后即清理
+func WithOutboxRetention(retention time.Duration) OutboxOption {
+	return func(o *Outbox) {
+		o.retention = retention
+	}
+}
+
+// WithOutboxDeadLetterRetention 设置死信的保留时间，默认 7 天，期间可通过 Requeue 重新投递
+func WithOutboxDeadLetterRetention(retention time.Duration) OutboxOption {
+	return func(o *Outbox) {
+		o.deadRetention = retention
+	}
+}
+
+// WithOutboxSpec 设置投递任务的调度表达式（含秒），默认每 5 秒投递一次
+func WithOutboxSpec(spec string) OutboxOption {
+	return func(o *Outbox) {
+		o.spec = spec
+	}
+}
+
+// NewOutbox 创建事务发件箱，事件投递到 bus，并自动迁移 outbox_messages 表
+func NewOutbox(db *gorm.DB, bus module.IEventBus, opts ...OutboxOption) (*Outbox, error) {
+	if err := db.AutoMigrate(&types.OutboxMessage{}); err != nil {
+		return nil, err
+	}
+	outbox := &Outbox{
+		db:            db,
+		bus:           bus,
+		batchSize:     100,
+		maxAttempts:   10,
+		retention:     24 * time.Hour,
+		deadRetention: 7 * 24 * time.Hour,
+		spec:          "*/5 * * * * *",
+	}
+	for _, opt := range opts {
+		opt(outbox)
+	}
+	return outbox, nil
+}
+
+// eventOutbox 获取事务发件箱，未注册 OutboxService 时返回 nil
+func eventOutbox() *Outbox {
+	if outbox, err := di.SINGLE().ResolveSingleton(module.OutboxService); err == nil {
+		if outbox, ok := outbox.(*Outbox); ok {
+			return outbox
+		}
+	}
+	return nil
+}
+
+// covers 仓储连接是否与发件箱属于同一 gorm 实例，会话与事务保留实例的连接池
+func (o *Outbox) covers(db *gorm.DB) bool {
+	return db.Config.ConnPool == o.db.Config.ConnPool
+}
+
+// GetName 任务名称
+func (o *Outbox) GetName() string {
+	return "outbox_relay"
+}
+
+// Spec 投递任务的调度表达式
+func (o *Outbox) Spec() string {
+	return o.spec
+}
+
+// Run 投递待发送的事件并清理过期的已投递记录，有事件投递失败时返回错误
+func (o *Outbox) Run(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	messages := make([]*types.OutboxMessage, 0)
+	err := o.db.WithContext(ctx).
+		Where("delivered_at IS NULL AND dead_at IS NULL AND attempts < ?", o.maxAttempts).
+		Order("created_at").Limit(o.batchSize).
+		Find(&messages).Error
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, message := range messages {
+		if err := o.deliver(ctx, message); err != nil {
+			failed++
+		}
+	}
+	if err := o.expire(ctx); err != nil {
+		return err
+	}
+	if err := o.cleanup(ctx); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return errors.New(errors.ErrInternal, fmt.Sprintf("%d outbox events failed to deliver", failed))
+	}
+	return nil
+}
+
+// deliver 投递一条事件并记录结果
+func (o *Outbox) deliver(ctx context.Context, message *types.OutboxMessage) error {
+	updates := map[string]interface{}{"attempts": gorm.Expr("attempts + 1")}
+	event, err := decodeEntityEvent(message.Payload)
+	if err == nil {
+		err = o.bus.Publish(ctx, event)
+	}
+	if err != nil {
+		updates["last_error"] = err.Error()
+	} else {
+		updates["delivered_at"] = time.Now()
+		updates["last_error"] = ""
+	}
+	if updateErr := o.db.WithContext(ctx).Model(message).Updates(updates).Error; updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+// expire 将达到最大投递次数仍未投递的事件标记为死信
+func (o *Outbox) expire(ctx context.Context) error {
+	return o.db.WithContext(ctx).Model(&types.OutboxMessage{}).
+		Where("delivered_at IS NULL AND dead_at IS NULL AND attempts >= ?", o.maxAttempts).
+		Update("dead_at", time.Now()).Error
+}
+
+// cleanup 删除超过保留时间的已投递记录与死信
+func (o *Outbox) cleanup(ctx context.Context) error {
+	now := time.Now()
+	return o.db.WithContext(ctx).
+		Where("delivered_at IS NOT NULL AND delivered_at <= ?", now.Add(-o.retention)).
+		Or("dead_at IS NOT NULL AND dead_at <= ?", now.Add(-o.deadRetention)).
+		Delete(&types.OutboxMessage{}).Error
+}
+
+// Requeue 将死信重新加入投递队列并清零投递次数，ids 为空时重新投递全部死信
+func (o *Outbox) Requeue(ctx context.Context, ids ...string) error {
+	db := o.db.WithContext(ctx).Model(&types.OutboxMessage{}).Where("dead_at IS NOT NULL")
+	if len(ids) > 0 {
+		db = db.Where("id IN ?", ids)
+	}
+	return db.Updates(map[string]interface{}{"dead_at": nil, "attempts": 0}).Error
+}
+
+// store 在仓储当前的会话中写入事件，与数据变更一同提交
+func (o *Outbox) store(ctx context.Context, store outboxStore, event *EntityEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	message := &types.OutboxMessage{
+		ID:        uuid.NewString(),
+		EventName: event.Name,
+		Payload:   string(payload),
+		CreatedAt: event.OccurredAt,
+	}
+	return store.outboxDB().WithContext(ctx).Create(message).Error
+}
+
+// decodeEntityEvent 从 json 还原事件，已登记的实体还原为实体或实体列表，ID 为 json 解码后的值
+func decodeEntityEvent(payload string) (*EntityEvent, error) {
+	var decoded struct {
+		EntityEvent
+		Data json.RawMessage `json:"data,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(payload), &decoded); err != nil {
+		return nil, err
+	}
+	event := decoded.EntityEvent
+	if len(decoded.Data) == 0 {
+		return &event, nil
+	}
+	if entityType, ok := eventTypes.Load(event.Entity); ok {
+		target := reflect.New(reflect.PointerTo(entityType.(reflect.Type)))
+		if decoded.Data[0] == '[' {
+			target = reflect.New(reflect.SliceOf(reflect.PointerTo(entityType.(reflect.Type))))
+		}
+		if err := json.Unmarshal(decoded.Data, target.Interface()); err != nil {
+			return nil, err
+		}
+		event.Data = target.Elem().Interface()
+		return &event, nil
+	}
+	if err := json.Unmarshal(decoded.Data, &event.Data); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
@@ -0,0 +1,116 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kruily/gofastcrud/core/crud/module"
+	"github.com/kruily/gofastcrud/core/crud/types"
+	"github.com/kruily/gofastcrud/core/di"
+	"github.com/kruily/gofastcrud/eventbus"
+	"github.com/stretchr/testify/require"
+)
+
+// testOutboxOrder 发件箱测试用订单
+type testOutboxOrder struct {
+	*BaseEntity
+	Title string `json:"title"`
+}
+
+func (*testOutboxOrder) TableName() string {
+	return "test_outbox_orders"
+}
+
+func (o *testOutboxOrder) Init() {
+	if o.BaseEntity == nil {
+		o.BaseEntity = &BaseEntity{}
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	db, orders := setupTestRepository(t, &testOutboxOrder{})
+	bus := eventbus.New()
+	outbox, err := NewOutbox(db, bus, WithOutboxMaxAttempts(2), WithOutboxRetention(0))
+	require.NoError(t, err)
+	require.NoError(t, di.SINGLE().BindSingletonWithName(module.OutboxService, outbox))
+	t.Cleanup(func() { di.SINGLE().Unbind(module.OutboxService) })
+
+	calls := 0
+	var delivered *EntityEvent
+	bus.Subscribe(EventName(&testOutboxOrder{}, EventCreated), func(ctx context.Context, event eventbus.Event) error {
+		calls++
+		if calls == 1 {
+			return errors.New("subscriber unavailable")
+		}
+		delivered = event.(*EntityEvent)
+		return nil
+	})
+	bus.Subscribe(EventName(&testOutboxOrder{}, EventUpdated), func(ctx context.Context, event eventbus.Event) error {
+		return errors.New("always fails")
+	})
+
+	registerEventType(reflect.TypeOf(testOutboxOrder{}))
+	repo := &Repository[*testOutboxOrder]{
+		crudRepo:   orders,
+		entityType: reflect.TypeOf(testOutboxOrder{}),
+		events:     eventModeOf(&testOutboxOrder{}),
+	}
+	require.True(t, repo.atomic())
+	ctx := context.Background()
+	pending := func() int64 {
+		var count int64
+		require.NoError(t, db.Model(&types.OutboxMessage{}).Count(&count).Error)
+		return count
+	}
+
+	// 事件与数据变更在同一事务中写入，回滚时一并丢弃
+	order := &testOutboxOrder{BaseEntity: &BaseEntity{}, Title: "first"}
+	require.NoError(t, repo.Create(ctx, order))
+	rollback := errors.New("rollback")
+	err = repo.Transaction(ctx, func(tx IRepository[*testOutboxOrder]) error {
+		if err := tx.Create(ctx, &testOutboxOrder{BaseEntity: &BaseEntity{}, Title: "lost"}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	require.ErrorIs(t, err, rollback)
+	require.Equal(t, int64(1), pending())
+	require.Zero(t, calls)
+
+	// 投递失败时记录次数与原因，下次轮询重试，成功后清理
+	require.Error(t, outbox.Run(ctx))
+	var message types.OutboxMessage
+	require.NoError(t, db.First(&message).Error)
+	require.Equal(t, 1, message.Attempts)
+	require.Equal(t, "subscriber unavailable", message.LastError)
+	require.NoError(t, outbox.Run(ctx))
+	require.Equal(t, int64(0), pending())
+	require.Equal(t, "testOutboxOrder.created", delivered.Name)
+	require.Equal(t, "first", delivered.Data.(*testOutboxOrder).Title)
+
+	// 超过最大投递次数后进入死信，不再重试
+	require.NoError(t, repo.Update(ctx, order, map[string]interface{}{"title": "second"}))
+	require.Error(t, outbox.Run(ctx))
+	require.Error(t, outbox.Run(ctx))
+	require.NoError(t, outbox.Run(ctx))
+	var failed types.OutboxMessage
+	require.NoError(t, db.First(&failed).Error)
+	require.Equal(t, 2, failed.Attempts)
+	require.Nil(t, failed.DeliveredAt)
+	require.NotNil(t, failed.DeadAt)
+
+	// 死信可重新投递
+	require.NoError(t, outbox.Requeue(ctx, failed.ID))
+	require.Error(t, outbox.Run(ctx))
+	var requeued types.OutboxMessage
+	require.NoError(t, db.First(&requeued).Error)
+	require.Equal(t, 1, requeued.Attempts)
+	require.Nil(t, requeued.DeadAt)
+
+	// 死信超过保留时间后清理
+	outbox.deadRetention = 0
+	require.Error(t, outbox.Run(ctx))
+	require.Equal(t, int64(0), pending())
+}
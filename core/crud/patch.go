@@ -0,0 +1,287 @@
+package crud
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/kruily/gofastcrud/errors"
+)
+
+// PATCH 请求支持的内容类型
+const (
+	MergePatchContentType = "application/merge-patch+json" // RFC 7396
+	JSONPatchContentType  = "application/json-patch+json"  // RFC 6902
+)
+
+// PatchOperation JSON Patch 操作
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// mergePatch 按 RFC 7396 合并，patch 中的 null 表示删除字段
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
+
+// applyJSONPatch 按 RFC 6902 依次执行操作，任一操作失败则整体失败
+func applyJSONPatch(doc interface{}, ops []PatchOperation) (interface{}, error) {
+	var err error
+	for i, op := range ops {
+		switch op.Op {
+		case "add":
+			doc, err = patchAdd(doc, op.Path, op.Value)
+		case "remove":
+			doc, _, err = patchRemove(doc, op.Path)
+		case "replace":
+			if doc, _, err = patchRemove(doc, op.Path); err == nil {
+				doc, err = patchAdd(doc, op.Path, op.Value)
+			}
+		case "move":
+			var value interface{}
+			if doc, value, err = patchRemove(doc, op.From); err == nil {
+				doc, err = patchAdd(doc, op.Path, value)
+			}
+		case "copy":
+			var value interface{}
+			if value, err = patchGet(doc, op.From); err == nil {
+				doc, err = patchAdd(doc, op.Path, deepCopyJSON(value))
+			}
+		case "test":
+			var value interface{}
+			if value, err = patchGet(doc, op.Path); err == nil && !reflect.DeepEqual(value, op.Value) {
+				err = fmt.Errorf("test failed at %s", op.Path)
+			}
+		default:
+			err = fmt.Errorf("unsupported op %q", op.Op)
+		}
+		if err != nil {
+			return nil, errors.New(errors.ErrInvalidParam, fmt.Sprintf("json patch operation %d: %v", i, err))
+		}
+	}
+	return doc, nil
+}
+
+// patchedFields 比较补丁前后的文档，返回以存储字段名为键、取自补丁后实体的变更值
+// 不允许修改主键与关联字段，乐观锁版本号只能保持不变
+func patchedFields(entityType reflect.Type, dbType string, original, patched map[string]interface{}, entity any) (map[string]interface{}, error) {
+	keys := make(map[string]struct{}, len(patched))
+	for key := range original {
+		keys[key] = struct{}{}
+	}
+	for key := range patched {
+		keys[key] = struct{}{}
+	}
+	fields := make(map[string]interface{})
+	for key := range keys {
+		if reflect.DeepEqual(original[key], patched[key]) {
+			continue
+		}
+		f, ok := lookupEntityField(entityType, key)
+		if !ok {
+			return nil, errors.New(errors.ErrInvalidParam, "invalid field: "+key)
+		}
+		switch {
+		case strings.EqualFold(f.Name, "id"):
+			return nil, errors.New(errors.ErrInvalidParam, "id cannot be modified")
+		case f.JSON == versionField:
+			return nil, errors.New(errors.ErrVersionConflict, "the record has been modified by others")
+		case isRelationType(f.Type):
+			return nil, errors.New(errors.ErrInvalidParam, "relation field cannot be patched: "+key)
+		}
+		value, ok := fieldValue(reflect.ValueOf(entity), f.Index)
+		if !ok {
+			return nil, errors.New(errors.ErrInvalidParam, "invalid field: "+key)
+		}
+		fields[f.StorageName(dbType)] = value.Interface()
+	}
+	return fields, nil
+}
+
+// isRelationType 判断字段是否为关联实体（结构体或结构体切片，time.Time 除外）
+func isRelationType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// parsePointer 解析 JSON Pointer（RFC 6901）
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex 解析数组下标，allowEnd 为 true 时允许 "-" 与 len 表示末尾
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > length || (index == length && !allowEnd) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return index, nil
+}
+
+// patchGet 读取 pointer 指向的值
+func patchGet(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %s does not exist", pointer)
+			}
+			doc = value
+		case []interface{}:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, fmt.Errorf("path %s does not exist", pointer)
+		}
+	}
+	return doc, nil
+}
+
+// patchAdd 在 pointer 处添加值，数组插入，对象覆盖
+func patchAdd(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := patchGet(doc, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(node), true)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node[:index], append([]interface{}{value}, node[index:]...)...)
+		return patchSet(doc, parentPointer, node)
+	default:
+		return nil, fmt.Errorf("path %s does not exist", pointer)
+	}
+}
+
+// patchRemove 删除 pointer 处的值并返回被删除的值
+func patchRemove(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the whole document")
+	}
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := patchGet(doc, parentPointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		value, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("path %s does not exist", pointer)
+		}
+		delete(node, last)
+		return doc, value, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := node[index]
+		node = append(node[:index:index], node[index+1:]...)
+		doc, err = patchSet(doc, parentPointer, node)
+		return doc, value, err
+	default:
+		return nil, nil, fmt.Errorf("path %s does not exist", pointer)
+	}
+}
+
+// patchSet 替换 pointer 处的值，用于数组长度变化后写回父节点
+func patchSet(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	if pointer == "" {
+		return value, nil
+	}
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := patchGet(doc, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+	tokens, _ := parsePointer(pointer)
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		index, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = value
+	}
+	return doc, nil
+}
+
+// deepCopyJSON 复制 JSON 值，避免 copy 操作后两处共享同一对象
+func deepCopyJSON(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var copied interface{}
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return value
+	}
+	return copied
+}
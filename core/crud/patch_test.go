@@ -0,0 +1,47 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	target := map[string]interface{}{"title": "a", "score": 1.0, "tags": map[string]interface{}{"x": 1.0, "y": 2.0}}
+	patch := map[string]interface{}{"title": "b", "score": nil, "tags": map[string]interface{}{"y": nil, "z": 3.0}}
+	require.Equal(t, map[string]interface{}{"title": "b", "tags": map[string]interface{}{"x": 1.0, "z": 3.0}}, mergePatch(target, patch))
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	doc := map[string]interface{}{"title": "a", "score": 1.0, "list": []interface{}{"x", "y"}}
+	patched, err := applyJSONPatch(doc, []PatchOperation{
+		{Op: "test", Path: "/title", Value: "a"},
+		{Op: "replace", Path: "/title", Value: "b"},
+		{Op: "remove", Path: "/score"},
+		{Op: "add", Path: "/list/1", Value: "z"},
+		{Op: "add", Path: "/list/-", Value: "w"},
+		{Op: "copy", From: "/title", Path: "/copied"},
+		{Op: "move", From: "/copied", Path: "/a~1b"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"title": "b", "list": []interface{}{"x", "z", "y", "w"}, "a/b": "b"}, patched)
+
+	_, err = applyJSONPatch(map[string]interface{}{"title": "a"}, []PatchOperation{{Op: "test", Path: "/title", Value: "b"}})
+	require.Error(t, err)
+	_, err = applyJSONPatch(map[string]interface{}{}, []PatchOperation{{Op: "remove", Path: "/missing"}})
+	require.Error(t, err)
+}
+
+func TestPatchedFields(t *testing.T) {
+	entity := &testArticle{BaseEntity: &BaseEntity{ID: 1}, Title: "b", Score: 2}
+	entityType := reflect.TypeOf(entity).Elem()
+	original := map[string]interface{}{"id": 1.0, "title": "a", "score": 2.0}
+
+	fields, err := patchedFields(entityType, DB_TYPE_GORM, original, map[string]interface{}{"id": 1.0, "title": "b", "score": 2.0}, entity)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"title": "b"}, fields)
+
+	_, err = patchedFields(entityType, DB_TYPE_GORM, original, map[string]interface{}{"id": 2.0, "title": "a", "score": 2.0}, entity)
+	require.Error(t, err)
+}
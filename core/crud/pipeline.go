@@ -0,0 +1,260 @@
+package crud
+
+import (
+	"reflect"
+
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Pipeline MongoDB 聚合管道构建器，通过 IRepository.Aggregate 执行
+// 字段名可使用 json 名称、Go 字段名或 bson 名称，实体字段会转换为 bson 名称，其余名称（如 $group 生成的字段）原样使用
+// 执行时仓储追加租户、数据权限与未删除条件：首个阶段为 $match 或 $geoNear 时合并到其查询条件中，否则在管道前追加 $match
+// $lookup 关联的集合不附加这些条件
+type Pipeline struct {
+	entityType reflect.Type
+	stages     bson.A
+	err        error
+}
+
+// NewPipeline 创建实体 T 的聚合管道
+func NewPipeline[T ICrudEntity]() *Pipeline {
+	entityType := reflect.TypeOf((*T)(nil)).Elem()
+	for entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+	return &Pipeline{entityType: entityType, stages: bson.A{}}
+}
+
+// Ref 返回字段在表达式中的引用，如 "$user_name"
+func (p *Pipeline) Ref(field string) string {
+	return "$" + p.field(field)
+}
+
+// Match 添加 $match 阶段，顶层字段名转换为 bson 名称
+func (p *Pipeline) Match(filter bson.M) *Pipeline {
+	return p.Stage(bson.M{"$match": p.fields(filter)})
+}
+
+// Group 添加 $group 阶段，by 为分组字段：string 按单个字段分组，[]string 按多个字段分组（以传入名称为键），nil 汇总全部文档，其他值作为 _id 表达式原样使用
+// accumulators 为累加器，如 bson.M{"total": bson.M{"$sum": p.Ref("amount")}}
+func (p *Pipeline) Group(by interface{}, accumulators bson.M) *Pipeline {
+	var id interface{}
+	switch v := by.(type) {
+	case string:
+		id = p.Ref(v)
+	case []string:
+		group := bson.M{}
+		for _, field := range v {
+			group[field] = p.Ref(field)
+		}
+		id = group
+	default:
+		id = v
+	}
+	group := bson.M{"_id": id}
+	for name, accumulator := range accumulators {
+		group[name] = accumulator
+	}
+	return p.Stage(bson.M{"$group": group})
+}
+
+// Lookup 添加 $lookup 阶段，按 localField 与 from 集合的 foreignField 关联，结果写入 as
+func (p *Pipeline) Lookup(from, localField, foreignField, as string) *Pipeline {
+	return p.Stage(bson.M{"$lookup": bson.M{
+		"from":         from,
+		"localField":   p.field(localField),
+		"foreignField": foreignField,
+		"as":           as,
+	}})
+}
+
+// LookupPipeline 添加带子管道的 $lookup 阶段，let 定义子管道中可用的变量
+func (p *Pipeline) LookupPipeline(from string, let bson.M, sub *Pipeline, as string) *Pipeline {
+	stages, err := sub.Build()
+	if err != nil {
+		p.err = err
+		return p
+	}
+	lookup := bson.M{"from": from, "pipeline": stages, "as": as}
+	if len(let) > 0 {
+		lookup["let"] = let
+	}
+	return p.Stage(bson.M{"$lookup": lookup})
+}
+
+// Unwind 添加 $unwind 阶段，preserveEmpty 为 true 时保留字段为空或空数组的文档
+func (p *Pipeline) Unwind(field string, preserveEmpty bool) *Pipeline {
+	if !preserveEmpty {
+		return p.Stage(bson.M{"$unwind": p.Ref(field)})
+	}
+	return p.Stage(bson.M{"$unwind": bson.M{"path": p.Ref(field), "preserveNullAndEmptyArrays": true}})
+}
+
+// Facet 添加 $facet 阶段，在同一批输入文档上执行多个子管道，每个子管道的结果写入同名字段
+func (p *Pipeline) Facet(facets map[string]*Pipeline) *Pipeline {
+	facet := bson.M{}
+	for name, sub := range facets {
+		stages, err := sub.Build()
+		if err != nil {
+			p.err = err
+			return p
+		}
+		facet[name] = stages
+	}
+	return p.Stage(bson.M{"$facet": facet})
+}
+
+// Project 添加 $project 阶段，顶层字段名转换为 bson 名称
+func (p *Pipeline) Project(fields bson.M) *Pipeline {
+	return p.Stage(bson.M{"$project": p.fields(fields)})
+}
+
+// AddFields 添加 $addFields 阶段
+func (p *Pipeline) AddFields(fields bson.M) *Pipeline {
+	return p.Stage(bson.M{"$addFields": fields})
+}
+
+// Sort 添加 $sort 阶段，排序表达式与 OrderBy 一致，如 "total desc, userName asc"
+func (p *Pipeline) Sort(orderBy ...string) *Pipeline {
+	orders, err := options.ParseOrderBy(orderBy)
+	if err != nil {
+		p.err = errors.Wrap(err, errors.ErrInvalidParam, "invalid order")
+		return p
+	}
+	sort := bson.D{}
+	for _, order := range orders {
+		direction := 1
+		if order.Desc {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: p.field(order.Field), Value: direction})
+	}
+	return p.Stage(bson.M{"$sort": sort})
+}
+
+// Skip 添加 $skip 阶段
+func (p *Pipeline) Skip(n int64) *Pipeline {
+	return p.Stage(bson.M{"$skip": n})
+}
+
+// Limit 添加 $limit 阶段
+func (p *Pipeline) Limit(n int64) *Pipeline {
+	return p.Stage(bson.M{"$limit": n})
+}
+
+// Count 添加 $count 阶段，文档数量写入 as 字段
+func (p *Pipeline) Count(as string) *Pipeline {
+	return p.Stage(bson.M{"$count": as})
+}
+
+// Stage 添加自定义阶段，用于构建器未提供的阶段
+func (p *Pipeline) Stage(stage interface{}) *Pipeline {
+	p.stages = append(p.stages, stage)
+	return p
+}
+
+// Build 返回管道的全部阶段，构建过程中出现的错误在此返回
+func (p *Pipeline) Build() (bson.A, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.stages, nil
+}
+
+// field 将实体字段名转换为 bson 名称
+func (p *Pipeline) field(name string) string {
+	if f, ok := lookupEntityField(p.entityType, name); ok {
+		return f.BSON
+	}
+	return name
+}
+
+// fields 转换顶层字段名，操作符（$and、$or 等）原样保留
+func (p *Pipeline) fields(m bson.M) bson.M {
+	converted := make(bson.M, len(m))
+	for key, value := range m {
+		if len(key) > 0 && key[0] == '$' {
+			converted[key] = value
+			continue
+		}
+		converted[p.field(key)] = value
+	}
+	return converted
+}
+
+// pipelineStages 将 Aggregate 接收的管道转换为阶段列表，支持 *Pipeline、bson.A、mongo.Pipeline 与 []bson.M 等切片
+func pipelineStages(pipeline interface{}) (bson.A, error) {
+	switch v := pipeline.(type) {
+	case *Pipeline:
+		return v.Build()
+	case bson.A:
+		return v, nil
+	case nil:
+		return bson.A{}, nil
+	}
+	value := reflect.ValueOf(pipeline)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return nil, errors.New(errors.ErrInvalidParam, "invalid aggregation pipeline: "+value.Type().String())
+	}
+	stages := make(bson.A, 0, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		stages = append(stages, value.Index(i).Interface())
+	}
+	return stages, nil
+}
+
+// scopedPipeline 将范围条件合并到管道中：首个阶段为 $match（可能包含只能位于首个阶段的 $text）时与其条件取交集，
+// 为 $geoNear 时合并到其 query，其余情况在管道前追加 $match；$search 等必须位于首个阶段且无法附加条件的阶段返回错误
+func scopedPipeline(stages bson.A, filter interface{}) (bson.A, error) {
+	if m, ok := filter.(bson.M); ok && len(m) == 0 {
+		return stages, nil
+	}
+	if len(stages) == 0 {
+		return bson.A{bson.M{"$match": filter}}, nil
+	}
+	first, err := pipelineStage(stages[0])
+	if err != nil {
+		return nil, err
+	}
+	scoped := append(bson.A{}, stages...)
+	switch first.Key {
+	case "$match":
+		scoped[0] = bson.M{"$match": bson.M{"$and": bson.A{first.Value, filter}}}
+	case "$geoNear":
+		geoNear, ok := first.Value.(bson.D)
+		if !ok {
+			return nil, errors.New(errors.ErrInvalidParam, "invalid $geoNear stage")
+		}
+		merged, found := bson.D{}, false
+		for _, option := range geoNear {
+			if option.Key == "query" {
+				option.Value, found = bson.M{"$and": bson.A{option.Value, filter}}, true
+			}
+			merged = append(merged, option)
+		}
+		if !found {
+			merged = append(merged, bson.E{Key: "query", Value: filter})
+		}
+		scoped[0] = bson.D{{Key: "$geoNear", Value: merged}}
+	case "$search", "$searchMeta", "$vectorSearch":
+		return nil, errors.New(errors.ErrInvalidParam, first.Key+" must be the first stage and cannot be combined with tenant, data permission or soft delete conditions")
+	default:
+		scoped = append(bson.A{bson.M{"$match": filter}}, stages...)
+	}
+	return scoped, nil
+}
+
+// pipelineStage 将阶段转换为 bson.E，阶段须为仅包含一个操作符的文档
+func pipelineStage(stage interface{}) (bson.E, error) {
+	data, err := bson.Marshal(stage)
+	if err != nil {
+		return bson.E{}, errors.Wrap(err, errors.ErrInvalidParam, "invalid aggregation stage")
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil || len(doc) != 1 {
+		return bson.E{}, errors.New(errors.ErrInvalidParam, "aggregation stage must contain exactly one operator")
+	}
+	return doc[0], nil
+}
@@ -0,0 +1,85 @@
+package crud
+
+import (
+	"testing"
+
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestPipeline(t *testing.T) {
+	p := NewPipeline[*testMongoMember]()
+	stages, err := p.
+		Match(bson.M{"age": bson.M{"$gte": 18}, "$or": bson.A{}}).
+		Lookup("orders", "userName", "member", "orders").
+		Unwind("orders", true).
+		Group("userName", bson.M{"total": bson.M{"$sum": p.Ref("orders.amount")}}).
+		Facet(map[string]*Pipeline{
+			"top":   NewPipeline[*testMongoMember]().Sort("total desc").Limit(3),
+			"count": NewPipeline[*testMongoMember]().Count("n"),
+		}).
+		Build()
+	require.NoError(t, err)
+	require.Equal(t, bson.A{
+		bson.M{"$match": bson.M{"age": bson.M{"$gte": 18}, "$or": bson.A{}}},
+		bson.M{"$lookup": bson.M{"from": "orders", "localField": "user_name", "foreignField": "member", "as": "orders"}},
+		bson.M{"$unwind": bson.M{"path": "$orders", "preserveNullAndEmptyArrays": true}},
+		bson.M{"$group": bson.M{"_id": "$user_name", "total": bson.M{"$sum": "$orders.amount"}}},
+		bson.M{"$facet": bson.M{
+			"top":   bson.A{bson.M{"$sort": bson.D{{Key: "total", Value: -1}}}, bson.M{"$limit": int64(3)}},
+			"count": bson.A{bson.M{"$count": "n"}},
+		}},
+	}, stages)
+
+	// 构建错误在 Build 时返回
+	_, err = NewPipeline[*testMongoMember]().Sort("age; drop").Build()
+	require.True(t, errors.Is(err, errors.ErrInvalidParam))
+	_, err = NewPipeline[*testMongoMember]().Facet(map[string]*Pipeline{
+		"bad": NewPipeline[*testMongoMember]().Sort("age sideways"),
+	}).Build()
+	require.Error(t, err)
+
+	// 管道可为 bson 阶段切片
+	stages, err = pipelineStages(mongo.Pipeline{{{Key: "$limit", Value: 1}}})
+	require.NoError(t, err)
+	require.Equal(t, bson.A{bson.D{{Key: "$limit", Value: 1}}}, stages)
+	_, err = pipelineStages(bson.M{"$limit": 1})
+	require.True(t, errors.Is(err, errors.ErrInvalidParam))
+}
+
+func TestScopedPipeline(t *testing.T) {
+	scope := bson.M{"tenant_id": "acme"}
+
+	// 范围条件合并到首个 $match，$text 仍位于首个阶段
+	stages, err := scopedPipeline(bson.A{bson.M{"$match": bson.M{"$text": bson.M{"$search": "go"}}}, bson.M{"$limit": 1}}, scope)
+	require.NoError(t, err)
+	require.Equal(t, bson.A{
+		bson.M{"$match": bson.M{"$and": bson.A{bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: "go"}}}}, scope}}},
+		bson.M{"$limit": 1},
+	}, stages)
+
+	// $geoNear 合并到 query
+	near := bson.D{{Key: "near", Value: bson.A{1.5, 2.5}}, {Key: "distanceField", Value: "dist"}}
+	stages, err = scopedPipeline(bson.A{bson.D{{Key: "$geoNear", Value: near}}}, scope)
+	require.NoError(t, err)
+	require.Equal(t, bson.D{{Key: "$geoNear", Value: append(near, bson.E{Key: "query", Value: scope})}}, stages[0])
+	stages, err = scopedPipeline(bson.A{bson.M{"$geoNear": bson.M{"distanceField": "dist", "query": bson.M{"a": 1}}}}, scope)
+	require.NoError(t, err)
+	geoNear := stages[0].(bson.D)[0].Value.(bson.D)
+	require.Contains(t, geoNear, bson.E{Key: "query", Value: bson.M{"$and": bson.A{bson.D{{Key: "a", Value: int32(1)}}, scope}}})
+
+	// 其他阶段前追加 $match，无范围条件时原样返回
+	stages, err = scopedPipeline(bson.A{bson.M{"$limit": 1}}, scope)
+	require.NoError(t, err)
+	require.Equal(t, bson.A{bson.M{"$match": scope}, bson.M{"$limit": 1}}, stages)
+	stages, err = scopedPipeline(bson.A{bson.M{"$limit": 1}}, bson.M{})
+	require.NoError(t, err)
+	require.Equal(t, bson.A{bson.M{"$limit": 1}}, stages)
+
+	_, err = scopedPipeline(bson.A{bson.M{"$search": bson.M{"text": bson.M{}}}}, scope)
+	require.True(t, errors.Is(err, errors.ErrInvalidParam))
+	_, err = scopedPipeline(bson.A{bson.M{"$match": bson.M{}, "$limit": 1}}, scope)
+	require.True(t, errors.Is(err, errors.ErrInvalidParam))
+}
@@ -0,0 +1,135 @@
+package crud
+
+import (
+	"context"
+)
+
+// IQuery 链式查询构建器
+// 构建器不可变：每次调用都会返回新的构建器，原构建器不受影响，可安全地在多个请求间复用
+type IQuery[T ICrudEntity] interface {
+	// Where 添加查询条件，多次调用以 AND 连接
+	// gorm 支持任意 gorm 条件；mongodb 支持 bson 文档或 "field op ?" 形式的简单条件
+	Where(query interface{}, args ...interface{}) IQuery[T]
+	// Order 添加排序，如 "created_at desc"
+	Order(value string) IQuery[T]
+	// Select 选择返回字段
+	Select(fields ...string) IQuery[T]
+	// Preload 预加载关联
+	Preload(relations ...string) IQuery[T]
+	// Joins 关联查询，mongodb 仅支持按关联名称（等同于 Preload）
+	Joins(query string, args ...interface{}) IQuery[T]
+	// Group 分组，仅 gorm 支持
+	Group(fields ...string) IQuery[T]
+	// Having 分组过滤，仅 gorm 支持
+	Having(query interface{}, args ...interface{}) IQuery[T]
+	// Limit 限制返回数量
+	Limit(limit int) IQuery[T]
+	// Offset 跳过记录数
+	Offset(offset int) IQuery[T]
+
+	// Find 查询列表
+	Find(ctx context.Context) ([]T, error)
+	// First 查询第一条记录
+	First(ctx context.Context) (T, error)
+	// Count 统计记录数（忽略排序与分页）
+	Count(ctx context.Context) (int64, error)
+	// Exists 检查是否存在记录
+	Exists(ctx context.Context) (bool, error)
+}
+
+// queryCond 查询条件
+type queryCond struct {
+	query interface{}
+	args  []interface{}
+}
+
+// queryScope 查询构建器状态
+type queryScope struct {
+	wheres   []queryCond
+	orders   []string
+	selects  []string
+	preloads []string
+	joins    []queryCond
+	groups   []string
+	havings  []queryCond
+	limit    int
+	offset   int
+}
+
+// clone 复制状态，切片重新分配，避免不同构建器共享底层数组
+func (s queryScope) clone() queryScope {
+	return queryScope{
+		wheres:   append([]queryCond(nil), s.wheres...),
+		orders:   append([]string(nil), s.orders...),
+		selects:  append([]string(nil), s.selects...),
+		preloads: append([]string(nil), s.preloads...),
+		joins:    append([]queryCond(nil), s.joins...),
+		groups:   append([]string(nil), s.groups...),
+		havings:  append([]queryCond(nil), s.havings...),
+		limit:    s.limit,
+		offset:   s.offset,
+	}
+}
+
+// scopeWhere 添加查询条件
+func scopeWhere(query interface{}, args ...interface{}) func(*queryScope) {
+	return func(s *queryScope) {
+		s.wheres = append(s.wheres, queryCond{query: query, args: args})
+	}
+}
+
+// scopeOrder 添加排序
+func scopeOrder(value string) func(*queryScope) {
+	return func(s *queryScope) {
+		s.orders = append(s.orders, value)
+	}
+}
+
+// scopeSelect 选择字段
+func scopeSelect(fields ...string) func(*queryScope) {
+	return func(s *queryScope) {
+		s.selects = append(s.selects, fields...)
+	}
+}
+
+// scopePreload 预加载关联
+func scopePreload(relations ...string) func(*queryScope) {
+	return func(s *queryScope) {
+		s.preloads = append(s.preloads, relations...)
+	}
+}
+
+// scopeJoins 关联查询
+func scopeJoins(query string, args ...interface{}) func(*queryScope) {
+	return func(s *queryScope) {
+		s.joins = append(s.joins, queryCond{query: query, args: args})
+	}
+}
+
+// scopeGroup 分组
+func scopeGroup(fields ...string) func(*queryScope) {
+	return func(s *queryScope) {
+		s.groups = append(s.groups, fields...)
+	}
+}
+
+// scopeHaving 分组过滤
+func scopeHaving(query interface{}, args ...interface{}) func(*queryScope) {
+	return func(s *queryScope) {
+		s.havings = append(s.havings, queryCond{query: query, args: args})
+	}
+}
+
+// scopeLimit 限制数量
+func scopeLimit(limit int) func(*queryScope) {
+	return func(s *queryScope) {
+		s.limit = limit
+	}
+}
+
+// scopeOffset 跳过记录数
+func scopeOffset(offset int) func(*queryScope) {
+	return func(s *queryScope) {
+		s.offset = offset
+	}
+}
@@ -0,0 +1,128 @@
+package crud
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// gormQuery gorm查询构建器
+type gormQuery[T ICrudEntity] struct {
+	session func(ctx context.Context) *gorm.DB // 创建会话，追加租户等全局条件
+	lock    *clause.Locking
+	scope   queryScope
+}
+
+// newGormQuery 创建gorm查询构建器
+func newGormQuery[T ICrudEntity](session func(ctx context.Context) *gorm.DB, lock *clause.Locking) *gormQuery[T] {
+	return &gormQuery[T]{session: session, lock: lock}
+}
+
+// with 复制当前状态并应用修改
+func (q *gormQuery[T]) with(apply func(*queryScope)) IQuery[T] {
+	scope := q.scope.clone()
+	apply(&scope)
+	return &gormQuery[T]{session: q.session, lock: q.lock, scope: scope}
+}
+
+func (q *gormQuery[T]) Where(query interface{}, args ...interface{}) IQuery[T] {
+	return q.with(scopeWhere(query, args...))
+}
+
+func (q *gormQuery[T]) Order(value string) IQuery[T] {
+	return q.with(scopeOrder(value))
+}
+
+func (q *gormQuery[T]) Select(fields ...string) IQuery[T] {
+	return q.with(scopeSelect(fields...))
+}
+
+func (q *gormQuery[T]) Preload(relations ...string) IQuery[T] {
+	return q.with(scopePreload(relations...))
+}
+
+func (q *gormQuery[T]) Joins(query string, args ...interface{}) IQuery[T] {
+	return q.with(scopeJoins(query, args...))
+}
+
+func (q *gormQuery[T]) Group(fields ...string) IQuery[T] {
+	return q.with(scopeGroup(fields...))
+}
+
+func (q *gormQuery[T]) Having(query interface{}, args ...interface{}) IQuery[T] {
+	return q.with(scopeHaving(query, args...))
+}
+
+func (q *gormQuery[T]) Limit(limit int) IQuery[T] {
+	return q.with(scopeLimit(limit))
+}
+
+func (q *gormQuery[T]) Offset(offset int) IQuery[T] {
+	return q.with(scopeOffset(offset))
+}
+
+// build 根据状态构建新的gorm会话
+// paging 为 false 时忽略排序、预加载、分页与锁，用于统计
+func (q *gormQuery[T]) build(ctx context.Context, paging bool) *gorm.DB {
+	db := q.session(ctx).Model(new(T))
+	for _, join := range q.scope.joins {
+		db = db.Joins(join.query.(string), join.args...)
+	}
+	for _, where := range q.scope.wheres {
+		db = db.Where(where.query, where.args...)
+	}
+	for _, group := range q.scope.groups {
+		db = db.Group(group)
+	}
+	for _, having := range q.scope.havings {
+		db = db.Having(having.query, having.args...)
+	}
+	if !paging {
+		return db
+	}
+	db = gormLocking(db, q.lock)
+	if len(q.scope.selects) > 0 {
+		db = db.Select(q.scope.selects)
+	}
+	for _, preload := range q.scope.preloads {
+		db = db.Preload(preload)
+	}
+	for _, order := range q.scope.orders {
+		db = db.Order(order)
+	}
+	if q.scope.limit > 0 {
+		db = db.Limit(q.scope.limit)
+	}
+	if q.scope.offset > 0 {
+		db = db.Offset(q.scope.offset)
+	}
+	return db
+}
+
+// Find 查询列表
+func (q *gormQuery[T]) Find(ctx context.Context) ([]T, error) {
+	entities := make([]T, 0)
+	err := q.build(ctx, true).Find(&entities).Error
+	return entities, err
+}
+
+// First 查询第一条记录
+func (q *gormQuery[T]) First(ctx context.Context) (T, error) {
+	entity := NewModel[T]()
+	err := q.build(ctx, true).First(entity).Error
+	return entity, err
+}
+
+// Count 统计记录数
+func (q *gormQuery[T]) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := q.build(ctx, false).Count(&count).Error
+	return count, err
+}
+
+// Exists 检查是否存在记录
+func (q *gormQuery[T]) Exists(ctx context.Context) (bool, error) {
+	count, err := q.Count(ctx)
+	return count > 0, err
+}
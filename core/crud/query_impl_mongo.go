@@ -0,0 +1,388 @@
+package crud
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/qiniu/qmgo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// mongoQuery mongodb查询构建器
+type mongoQuery[T ICrudEntity] struct {
+	collection *qmgo.Collection
+	session    mongo.Session // 事务会话，为空表示不在事务中
+	entityType reflect.Type
+	lock       func(ctx context.Context, filter interface{}) error                // 悲观锁，读取前执行
+	scoped     func(ctx context.Context, filter interface{}) (interface{}, error) // 追加租户、软删除等全局条件
+	scope      queryScope
+}
+
+// newMongoQuery 创建mongodb查询构建器
+func newMongoQuery[T ICrudEntity](collection *qmgo.Collection, entityType reflect.Type) *mongoQuery[T] {
+	return &mongoQuery[T]{collection: collection, entityType: entityType}
+}
+
+// with 复制当前状态并应用修改
+func (q *mongoQuery[T]) with(apply func(*queryScope)) IQuery[T] {
+	scope := q.scope.clone()
+	apply(&scope)
+	return &mongoQuery[T]{collection: q.collection, session: q.session, entityType: q.entityType, lock: q.lock, scoped: q.scoped, scope: scope}
+}
+
+func (q *mongoQuery[T]) Where(query interface{}, args ...interface{}) IQuery[T] {
+	return q.with(scopeWhere(query, args...))
+}
+
+func (q *mongoQuery[T]) Order(value string) IQuery[T] {
+	return q.with(scopeOrder(value))
+}
+
+func (q *mongoQuery[T]) Select(fields ...string) IQuery[T] {
+	return q.with(scopeSelect(fields...))
+}
+
+func (q *mongoQuery[T]) Preload(relations ...string) IQuery[T] {
+	return q.with(scopePreload(relations...))
+}
+
+func (q *mongoQuery[T]) Joins(query string, args ...interface{}) IQuery[T] {
+	return q.with(scopeJoins(query, args...))
+}
+
+func (q *mongoQuery[T]) Group(fields ...string) IQuery[T] {
+	return q.with(scopeGroup(fields...))
+}
+
+func (q *mongoQuery[T]) Having(query interface{}, args ...interface{}) IQuery[T] {
+	return q.with(scopeHaving(query, args...))
+}
+
+func (q *mongoQuery[T]) Limit(limit int) IQuery[T] {
+	return q.with(scopeLimit(limit))
+}
+
+func (q *mongoQuery[T]) Offset(offset int) IQuery[T] {
+	return q.with(scopeOffset(offset))
+}
+
+// Find 查询列表
+func (q *mongoQuery[T]) Find(ctx context.Context) ([]T, error) {
+	entities := make([]T, 0)
+	if err := q.all(ctx, q.scope.limit, &entities); err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// First 查询第一条记录，无记录时返回 qmgo.ErrNoSuchDocuments
+func (q *mongoQuery[T]) First(ctx context.Context) (T, error) {
+	entities := make([]T, 0, 1)
+	if err := q.all(ctx, 1, &entities); err != nil {
+		return NewModel[T](), err
+	}
+	if len(entities) == 0 {
+		return NewModel[T](), qmgo.ErrNoSuchDocuments
+	}
+	return entities[0], nil
+}
+
+// Count 统计记录数
+func (q *mongoQuery[T]) Count(ctx context.Context) (int64, error) {
+	ctx = q.bind(ctx)
+	filter, err := q.filter(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return q.collection.Find(ctx, filter).Count()
+}
+
+// Exists 检查是否存在记录
+func (q *mongoQuery[T]) Exists(ctx context.Context) (bool, error) {
+	count, err := q.Count(ctx)
+	return count > 0, err
+}
+
+// bind 将操作绑定到事务会话
+func (q *mongoQuery[T]) bind(ctx context.Context) context.Context {
+	if q.session == nil {
+		return ctx
+	}
+	return mongo.NewSessionContext(ctx, q.session)
+}
+
+// all 执行查询，存在关联时使用聚合管道
+func (q *mongoQuery[T]) all(ctx context.Context, limit int, result interface{}) error {
+	ctx = q.bind(ctx)
+	if len(q.scope.groups) > 0 || len(q.scope.havings) > 0 {
+		return errors.New(errors.ErrInternal, "group/having is not supported by mongodb query, use GroupAggregate instead")
+	}
+	filter, err := q.filter(ctx)
+	if err != nil {
+		return err
+	}
+	sorts, err := q.sorts()
+	if err != nil {
+		return err
+	}
+	lookups, err := q.lookups()
+	if err != nil {
+		return err
+	}
+	if q.lock != nil {
+		if err := q.lock(ctx, filter); err != nil {
+			return err
+		}
+	}
+
+	if len(lookups) == 0 {
+		query := q.collection.Find(ctx, filter)
+		if len(sorts) > 0 {
+			query = query.Sort(sorts...)
+		}
+		if projection := q.projection(); projection != nil {
+			query = query.Select(projection)
+		}
+		if q.scope.offset > 0 {
+			query = query.Skip(int64(q.scope.offset))
+		}
+		if limit > 0 {
+			query = query.Limit(int64(limit))
+		}
+		return query.All(result)
+	}
+
+	pipeline := bson.A{bson.M{"$match": filter}}
+	if len(sorts) > 0 {
+		pipeline = append(pipeline, bson.M{"$sort": mongoSortDocument(sorts)})
+	}
+	if q.scope.offset > 0 {
+		pipeline = append(pipeline, bson.M{"$skip": q.scope.offset})
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.M{"$limit": limit})
+	}
+	pipeline = append(pipeline, lookups...)
+	if projection := q.projection(); projection != nil {
+		pipeline = append(pipeline, bson.M{"$project": projection})
+	}
+	return q.collection.Aggregate(ctx, pipeline).All(result)
+}
+
+// filter 合并所有查询条件
+func (q *mongoQuery[T]) filter(ctx context.Context) (interface{}, error) {
+	conds := make(bson.A, 0, len(q.scope.wheres))
+	for _, where := range q.scope.wheres {
+		cond, err := q.condition(where)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+	}
+	var filter interface{}
+	switch len(conds) {
+	case 0:
+		filter = bson.M{}
+	case 1:
+		filter = conds[0]
+	default:
+		filter = bson.M{"$and": conds}
+	}
+	if q.scoped != nil {
+		return q.scoped(ctx, filter)
+	}
+	return filter, nil
+}
+
+// mongoWherePattern 简单条件格式：field op ?
+var mongoWherePattern = regexp.MustCompile(`(?i)^\s*([A-Za-z_][A-Za-z0-9_.]*)\s*(=|!=|<>|>=|<=|>|<|not in|in|not like|like)\s*\(?\?\)?\s*$`)
+
+// mongoWhereOperators 条件操作符与 mongodb 操作符映射
+var mongoWhereOperators = map[string]string{
+	"=":      "$eq",
+	"!=":     "$ne",
+	"<>":     "$ne",
+	">":      "$gt",
+	">=":     "$gte",
+	"<":      "$lt",
+	"<=":     "$lte",
+	"in":     "$in",
+	"not in": "$nin",
+}
+
+// condition 将单个条件转换为 bson 文档
+func (q *mongoQuery[T]) condition(where queryCond) (bson.M, error) {
+	switch query := where.query.(type) {
+	case bson.M:
+		return query, nil
+	case map[string]interface{}:
+		return bson.M(query), nil
+	case bson.D:
+		cond := bson.M{}
+		for _, e := range query {
+			cond[e.Key] = e.Value
+		}
+		return cond, nil
+	case string:
+		if !mongoWherePattern.MatchString(query) || len(where.args) != 1 {
+			return nil, errors.New(errors.ErrInvalidParam, "unsupported mongodb where clause: "+query)
+		}
+		return mongoWhereCondition(query, where.args[0], q.storageName), nil
+	case nil:
+		return bson.M{}, nil
+	}
+	// 结构体等其他类型按 bson 编码后的字段匹配
+	raw, err := bson.Marshal(where.query)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidParam, "unsupported mongodb where clause type")
+	}
+	cond := bson.M{}
+	if err := bson.Unmarshal(raw, &cond); err != nil {
+		return nil, err
+	}
+	return cond, nil
+}
+
+// likePattern 将 SQL LIKE 模式转换为正则表达式
+func likePattern(like string) string {
+	pattern := regexp.QuoteMeta(like)
+	pattern = strings.ReplaceAll(pattern, "%", ".*")
+	pattern = strings.ReplaceAll(pattern, "_", ".")
+	return "^" + pattern + "$"
+}
+
+// sorts 将排序表达式转换为 qmgo 排序参数
+func (q *mongoQuery[T]) sorts() ([]string, error) {
+	return mongoOrderSorts(q.entityType, q.scope.orders)
+}
+
+// mongoOrderSorts 将排序表达式转换为 qmgo 排序参数，字段名转换为 bson 名称
+func mongoOrderSorts(entityType reflect.Type, orderBy []string) ([]string, error) {
+	orders, err := options.ParseOrderBy(orderBy)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.ErrInvalidParam, "invalid order")
+	}
+	sorts := make([]string, 0, len(orders))
+	for _, order := range orders {
+		name := order.Field
+		if f, ok := lookupEntityField(entityType, name); ok {
+			name = f.BSON
+		}
+		if order.Desc {
+			name = "-" + name
+		}
+		sorts = append(sorts, name)
+	}
+	return sorts, nil
+}
+
+// projection 字段选择
+func (q *mongoQuery[T]) projection() bson.M {
+	projection := mongoProjection(q.entityType, q.scope.selects)
+	if projection == nil {
+		return nil
+	}
+	for _, relation := range q.relations() {
+		if f, ok := lookupEntityField(q.entityType, relation); ok {
+			projection[f.BSON] = 1
+		}
+	}
+	return projection
+}
+
+// relations 需要加载的关联（Preload 与 Joins）
+func (q *mongoQuery[T]) relations() []string {
+	relations := append([]string(nil), q.scope.preloads...)
+	for _, join := range q.scope.joins {
+		relations = append(relations, join.query.(string))
+	}
+	return relations
+}
+
+// lookups 将关联转换为 $lookup 阶段
+func (q *mongoQuery[T]) lookups() (bson.A, error) {
+	stages := bson.A{}
+	for _, relation := range q.relations() {
+		lookup, err := mongoRelationLookup(q.entityType, relation)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, lookup...)
+	}
+	return stages, nil
+}
+
+// storageName 将字段名转换为 bson 名称，未知字段原样返回
+func (q *mongoQuery[T]) storageName(name string) string {
+	if f, ok := lookupEntityField(q.entityType, name); ok {
+		return f.BSON
+	}
+	return name
+}
+
+// mongoRelationLookup 根据关联字段生成 $lookup 阶段
+// 单个关联（指针或结构体）：本地字段 <Relation>ID，对方 _id
+// 多个关联（切片）：本地 _id，对方字段 <Entity>ID
+// 可通过 gorm 标签 foreignKey 指定外键字段
+func mongoRelationLookup(entityType reflect.Type, relation string) (bson.A, error) {
+	f, ok := lookupEntityField(entityType, relation)
+	if !ok {
+		return nil, errors.New(errors.ErrInvalidParam, "unknown relation: "+relation)
+	}
+	many := f.Type.Kind() == reflect.Slice
+	target := f.Type
+	for target.Kind() == reflect.Ptr || target.Kind() == reflect.Slice {
+		target = target.Elem()
+	}
+	model, ok := reflect.New(target).Interface().(ICrudEntity)
+	if target.Kind() != reflect.Struct || !ok {
+		return nil, errors.New(errors.ErrInvalidParam, "field is not a relation: "+relation)
+	}
+	foreignKey := gormTagSetting(f.Tag, "foreignKey")
+
+	lookup := bson.M{"from": model.TableName(), "as": f.BSON}
+	if many {
+		if foreignKey == "" {
+			foreignKey = entityType.Name() + "ID"
+		}
+		foreign, ok := lookupEntityField(target, foreignKey)
+		if !ok {
+			return nil, errors.New(errors.ErrInvalidParam, "relation foreign key not found: "+foreignKey)
+		}
+		lookup["localField"] = "_id"
+		lookup["foreignField"] = foreign.BSON
+		return bson.A{bson.M{"$lookup": lookup}}, nil
+	}
+
+	if foreignKey == "" {
+		foreignKey = f.Name + "ID"
+	}
+	local, ok := lookupEntityField(entityType, foreignKey)
+	if !ok {
+		return nil, errors.New(errors.ErrInvalidParam, "relation foreign key not found: "+foreignKey)
+	}
+	lookup["localField"] = local.BSON
+	lookup["foreignField"] = "_id"
+	return bson.A{
+		bson.M{"$lookup": lookup},
+		bson.M{"$unwind": bson.M{"path": "$" + f.BSON, "preserveNullAndEmptyArrays": true}},
+	}, nil
+}
+
+// mongoSortDocument 将 qmgo 排序参数转换为 $sort 文档
+func mongoSortDocument(sorts []string) bson.D {
+	doc := make(bson.D, 0, len(sorts))
+	for _, sort := range sorts {
+		if strings.HasPrefix(sort, "-") {
+			doc = append(doc, bson.E{Key: sort[1:], Value: -1})
+		} else {
+			doc = append(doc, bson.E{Key: sort, Value: 1})
+		}
+	}
+	return doc
+}
@@ -0,0 +1,289 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// relationTag 关联字段标签，列出允许过滤与排序的关联实体字段（json 名称），如 `relation:"name,created_at"`
+// 过滤操作符仍由关联实体字段自身的 filter 标签决定
+const relationTag = "relation"
+
+// entityRelation 关联（belongs to / has one / has many）元信息
+type entityRelation struct {
+	Alias      string       // 关联字段 json 名称，作为 JOIN 别名与 $lookup 输出字段
+	Source     entityField  // 关联字段
+	Many       bool         // 是否一对多
+	Type       reflect.Type // 关联实体类型
+	Table      string       // 关联表（集合）名
+	LocalKey   entityField  // 主实体连接字段
+	ForeignKey entityField  // 关联实体连接字段
+	Fields     []string     // 允许过滤与排序的关联字段
+}
+
+// lookupRelation 根据 json 名称查找声明了 relation 标签的单值关联字段
+func lookupRelation(entityType reflect.Type, alias string) (entityRelation, error) {
+	if entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+	for _, f := range entityFields(entityType) {
+		if f.JSON != alias || f.Tag.Get(relationTag) == "" {
+			continue
+		}
+		relation, err := newEntityRelation(entityType, f)
+		if err != nil {
+			return entityRelation{}, err
+		}
+		if relation.Many {
+			return entityRelation{}, errors.New(errors.ErrInvalidParam, fmt.Sprintf("relation %s must be a belongs-to or has-one relation", alias))
+		}
+		for _, name := range strings.Split(f.Tag.Get(relationTag), ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				relation.Fields = append(relation.Fields, name)
+			}
+		}
+		return relation, nil
+	}
+	return entityRelation{}, errors.New(errors.ErrInvalidParam, fmt.Sprintf("relation %s is not filterable", alias))
+}
+
+// newEntityRelation 解析关联字段的关联实体与连接字段
+// 外键规则与 gorm 一致：foreignKey 在主实体上为 belongs to，否则为 has one / has many，默认外键为 <字段名>ID / <实体名>ID
+func newEntityRelation(entityType reflect.Type, field entityField) (entityRelation, error) {
+	relatedType := field.Type
+	many := false
+	for relatedType.Kind() == reflect.Ptr || relatedType.Kind() == reflect.Slice {
+		many = many || relatedType.Kind() == reflect.Slice
+		relatedType = relatedType.Elem()
+	}
+	if relatedType.Kind() != reflect.Struct || relatedType == timeType {
+		return entityRelation{}, errors.New(errors.ErrInvalidParam, fmt.Sprintf("field %s is not a relation", field.JSON))
+	}
+	if gormTagSetting(field.Tag, "many2many") != "" {
+		return entityRelation{}, errors.New(errors.ErrInvalidParam, fmt.Sprintf("many-to-many relation %s is not supported", field.JSON))
+	}
+
+	relation := entityRelation{Alias: field.JSON, Source: field, Type: relatedType, Table: entityTableName(relatedType), Many: many}
+	foreignKey := gormTagSetting(field.Tag, "foreignKey")
+	references := gormTagSetting(field.Tag, "references")
+	if references == "" {
+		references = "ID"
+	}
+	var localOK, foreignOK bool
+	if local, ok := fieldByGoName(entityType, foreignKey, field.Name+"ID"); ok && !many {
+		// belongs to：外键在主实体上
+		relation.LocalKey, localOK = local, true
+		relation.ForeignKey, foreignOK = fieldByGoName(relatedType, references)
+	} else {
+		// has one / has many：外键在关联实体上
+		relation.LocalKey, localOK = fieldByGoName(entityType, references)
+		relation.ForeignKey, foreignOK = fieldByGoName(relatedType, foreignKey, entityType.Name()+"ID")
+	}
+	if !localOK || !foreignOK {
+		return entityRelation{}, errors.New(errors.ErrInternal, fmt.Sprintf("cannot resolve foreign key of relation %s", field.JSON))
+	}
+	return relation, nil
+}
+
+// Field 查找允许过滤与排序的关联字段
+func (r entityRelation) Field(name string) (entityField, error) {
+	for _, allowed := range r.Fields {
+		if allowed != name {
+			continue
+		}
+		if f, ok := lookupEntityField(r.Type, name); ok {
+			return f, nil
+		}
+	}
+	return entityField{}, errors.New(errors.ErrInvalidParam, fmt.Sprintf("field %s.%s is not allowed", r.Alias, name))
+}
+
+// Join 生成连接选项
+func (r entityRelation) Join(dbType string) options.Join {
+	join := options.Join{
+		Table:         r.Table,
+		Alias:         r.Alias,
+		LocalColumn:   r.LocalKey.StorageName(dbType),
+		ForeignColumn: r.ForeignKey.StorageName(dbType),
+	}
+	if meta := softDeleteMetaOf(r.Type, dbType); meta.Enabled {
+		join.DeletedAt = meta.DeletedAt
+	}
+	return join
+}
+
+// resolveRelationPath 解析 relation.field 形式的路径
+func resolveRelationPath(entityType reflect.Type, path string) (entityRelation, entityField, error) {
+	alias, name, ok := strings.Cut(path, ".")
+	if !ok || strings.Contains(name, ".") {
+		return entityRelation{}, entityField{}, errors.New(errors.ErrInvalidParam, "invalid relation path: "+path)
+	}
+	relation, err := lookupRelation(entityType, alias)
+	if err != nil {
+		return entityRelation{}, entityField{}, err
+	}
+	field, err := relation.Field(name)
+	if err != nil {
+		return entityRelation{}, entityField{}, err
+	}
+	return relation, field, nil
+}
+
+// fieldByGoName 按 Go 字段名查找字段，依次尝试候选名称
+func fieldByGoName(entityType reflect.Type, names ...string) (entityField, bool) {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		for _, f := range entityFields(entityType) {
+			if f.Name == name {
+				return f, true
+			}
+		}
+	}
+	return entityField{}, false
+}
+
+// entityTableName 获取实体表名，优先使用 TableName 方法
+func entityTableName(entityType reflect.Type) string {
+	if tabler, ok := reflect.New(entityType).Interface().(interface{ TableName() string }); ok {
+		return tabler.TableName()
+	}
+	return namingStrategy.TableName(entityType.Name())
+}
+
+// relationQueryFields 关联实体上允许过滤的字段，字段名为 relation.field
+func relationQueryFields(entity any) []QueryField {
+	entityType := reflect.TypeOf(entity)
+	fields := make([]QueryField, 0)
+	for _, f := range entityFields(entityType) {
+		if f.Tag.Get(relationTag) == "" {
+			continue
+		}
+		relation, err := lookupRelation(entityType, f.JSON)
+		if err != nil {
+			continue
+		}
+		for _, name := range relation.Fields {
+			if field, err := relation.Field(name); err == nil {
+				fields = append(fields, QueryField{Field: relation.Alias + "." + name, FilterTag: field.Tag.Get("filter")})
+			}
+		}
+	}
+	return fields
+}
+
+// relationOptions 为过滤条件与排序中引用的关联添加连接，并将排序中的关联路径转换为 别名.字段
+// 存在连接时 gorm 排序中的主表字段会加上表名限定，避免与关联表列名冲突
+func relationOptions(entityType reflect.Type, dbType string, opts *options.QueryOptions) error {
+	aliases := make(map[string]bool)
+	for _, condition := range opts.Conditions {
+		if condition.Table != "" {
+			aliases[condition.Table] = true
+		}
+	}
+	if opts.FilterExpr != nil {
+		collectRelationAliases(*opts.FilterExpr, aliases)
+	}
+
+	// 游标模式的排序由游标键决定，不支持关联排序
+	orders := make([]string, 0)
+	ordersChanged := false
+	for _, orderBy := range opts.OrderBy {
+		if opts.UseCursor || (!strings.Contains(orderBy, ".") && len(aliases) == 0) {
+			orders = append(orders, orderBy)
+			continue
+		}
+		fields, err := options.ParseOrderBy([]string{orderBy})
+		if err != nil {
+			return errors.Wrap(err, errors.ErrInvalidParam, "invalid order_by")
+		}
+		ordersChanged = true
+		for _, order := range fields {
+			column := order.Field
+			if strings.Contains(order.Field, ".") {
+				relation, field, err := resolveRelationPath(entityType, order.Field)
+				if err != nil {
+					return err
+				}
+				aliases[relation.Alias] = true
+				column = relation.Alias + "." + field.StorageName(dbType)
+			} else if f, ok := lookupEntityField(entityType, order.Field); ok && dbType != DB_TYPE_MONGODB {
+				column = entityTableName(entityType) + "." + f.StorageName(dbType)
+			}
+			if order.Desc {
+				column += " desc"
+			}
+			orders = append(orders, column)
+		}
+	}
+	if ordersChanged {
+		opts.OrderBy = []string{strings.Join(orders, ", ")}
+	}
+
+	sorted := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		sorted = append(sorted, alias)
+	}
+	sort.Strings(sorted)
+	for _, alias := range sorted {
+		relation, err := lookupRelation(entityType, alias)
+		if err != nil {
+			return err
+		}
+		options.WithJoins(relation.Join(dbType))(opts)
+	}
+	return nil
+}
+
+// collectRelationAliases 收集过滤表达式中引用的关联别名
+func collectRelationAliases(expr options.FilterExpr, aliases map[string]bool) {
+	if expr.Condition != nil && expr.Condition.Table != "" {
+		aliases[expr.Condition.Table] = true
+	}
+	if expr.Not != nil {
+		collectRelationAliases(*expr.Not, aliases)
+	}
+	for _, child := range expr.And {
+		collectRelationAliases(child, aliases)
+	}
+	for _, child := range expr.Or {
+		collectRelationAliases(child, aliases)
+	}
+}
+
+// mongoLookupStages 将关联连接转换为 $lookup 与 $unwind 阶段，关联文档写入别名字段
+func mongoLookupStages(joins []options.Join) bson.A {
+	stages := bson.A{}
+	for _, join := range joins {
+		match := bson.M{"$expr": bson.M{"$eq": bson.A{"$" + join.ForeignColumn, "$$local"}}}
+		if join.DeletedAt != "" {
+			match[join.DeletedAt] = nil
+		}
+		stages = append(stages,
+			bson.M{"$lookup": bson.M{
+				"from":     join.Table,
+				"let":      bson.M{"local": "$" + join.LocalColumn},
+				"pipeline": bson.A{bson.M{"$match": match}},
+				"as":       join.Alias,
+			}},
+			bson.M{"$unwind": bson.M{"path": "$" + join.Alias, "preserveNullAndEmptyArrays": true}},
+		)
+	}
+	return stages
+}
+
+// mongoUnsetLookups 移除 $lookup 写入的关联文档
+func mongoUnsetLookups(joins []options.Join) bson.M {
+	fields := bson.M{}
+	for _, join := range joins {
+		fields[join.Alias] = 0
+	}
+	return bson.M{"$project": fields}
+}
@@ -0,0 +1,127 @@
+package crud
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// testCategory 关联测试用分类
+type testCategory struct {
+	*BaseEntity
+	Name  string      `json:"name" filter:"eq,like"`
+	Books []*testBook `json:"books" gorm:"foreignKey:CategoryID" expand:"true"`
+}
+
+func (*testCategory) TableName() string {
+	return "test_categories"
+}
+
+func (c *testCategory) Init() {
+	if c.BaseEntity == nil {
+		c.BaseEntity = &BaseEntity{}
+	}
+}
+
+// testBook 关联测试用书籍，允许按分类名称过滤与排序
+type testBook struct {
+	*BaseEntity
+	Title      string        `json:"title" filter:"eq"`
+	CategoryID uint64        `json:"category_id"`
+	Category   *testCategory `json:"category" gorm:"foreignKey:CategoryID" relation:"name" expand:"true"`
+}
+
+func (*testBook) TableName() string {
+	return "test_books"
+}
+
+func (b *testBook) Init() {
+	if b.BaseEntity == nil {
+		b.BaseEntity = &BaseEntity{}
+	}
+}
+
+// setupRelationRepository 创建分类与书籍，书籍 a、c 属于 Fiction，b 属于 History，d 属于已删除的 Archived
+func setupRelationRepository(t *testing.T) *gormRepository[*testBook] {
+	db, books := setupTestRepository(t, &testBook{}, &testCategory{})
+	ctx := context.Background()
+	categories := newGormRepository(db, &testCategory{})
+	for _, name := range []string{"Fiction", "History", "Archived"} {
+		require.NoError(t, categories.Create(ctx, &testCategory{BaseEntity: &BaseEntity{}, Name: name}))
+	}
+	for i, categoryID := range []uint64{1, 2, 1, 3} {
+		require.NoError(t, books.Create(ctx, &testBook{BaseEntity: &BaseEntity{}, Title: string(rune('a' + i)), CategoryID: categoryID}))
+	}
+	require.NoError(t, categories.DeleteById(ctx, uint64(3)))
+	return books
+}
+
+func TestRelationFilters(t *testing.T) {
+	books := setupRelationRepository(t)
+	ctx := context.Background()
+
+	find := func(query url.Values, orderBy string) []*testBook {
+		conditions, err := compileFilters(books.entityType, DB_TYPE_GORM, query)
+		require.NoError(t, err)
+		opts := options.NewQueryOptions(options.WithConditions(conditions...), options.WithOrderBy(orderBy))
+		require.NoError(t, relationOptions(books.entityType, DB_TYPE_GORM, opts))
+		items, err := books.Find(ctx, &testBook{}, opts)
+		require.NoError(t, err)
+		return items
+	}
+
+	require.Len(t, find(url.Values{"category.name": {"Fiction"}}, "id desc"), 2)
+	require.Len(t, find(url.Values{"category.name_like": {"i"}, "title": {"b"}}, "id desc"), 1)
+	// 已软删除的分类不参与连接
+	require.Len(t, find(url.Values{"category.name": {"Archived"}}, "id desc"), 0)
+
+	items := find(url.Values{}, "category.name desc, id asc")
+	require.Len(t, items, 4)
+	require.Equal(t, []string{"b", "a", "c", "d"}, []string{items[0].Title, items[1].Title, items[2].Title, items[3].Title})
+
+	for _, query := range []url.Values{
+		{"category.id": {"1"}},         // 未在 relation 标签中声明
+		{"category.name_gt": {"a"}},    // 关联字段未声明的操作符
+		{"title.name": {"a"}},          // 非关联字段
+		{"category.name.first": {"a"}}, // 只支持一层关联
+	} {
+		_, err := compileFilters(books.entityType, DB_TYPE_GORM, query)
+		require.True(t, errors.Is(err, errors.ErrInvalidParam), query.Encode())
+	}
+	err := relationOptions(books.entityType, DB_TYPE_GORM, options.NewQueryOptions(options.WithOrderBy("category.created_at desc")))
+	require.True(t, errors.Is(err, errors.ErrInvalidParam))
+}
+
+func TestMongoRelationFilter(t *testing.T) {
+	conditions, err := compileFilters(reflect.TypeOf(testBook{}), DB_TYPE_MONGODB, url.Values{"category.name_like": {"fic"}})
+	require.NoError(t, err)
+	opts := options.NewQueryOptions(options.WithConditions(conditions...))
+	require.NoError(t, relationOptions(reflect.TypeOf(testBook{}), DB_TYPE_MONGODB, opts))
+	require.Len(t, opts.Joins, 1)
+
+	stages := mongoLookupStages(opts.Joins)
+	require.Len(t, stages, 2)
+	lookup := stages[0].(bson.M)["$lookup"].(bson.M)
+	require.Equal(t, "test_categories", lookup["from"])
+	require.Equal(t, "category", lookup["as"])
+	require.Contains(t, mongoQueryFilter(opts)["$and"], mongoCondition(options.Condition{Field: "category.name", Op: options.OpLike, Value: "fic"}))
+
+	// 全文检索与关联过滤组合时，$text 位于 $lookup 之前的首个 $match，关联条件在 $lookup 之后匹配
+	options.WithConditions(options.Condition{Field: "title", Op: options.OpEq, Value: "go"})(opts)
+	opts.Search, opts.SearchFields, opts.SearchMode = "golang", []string{"title"}, options.SearchModeFullText
+	main, relation := mongoQueryFilters(opts)
+	require.Equal(t, bson.M{"$and": bson.A{bson.M{"$text": bson.M{"$search": "golang"}}, bson.M{"title": "go"}}}, main)
+	require.Equal(t, bson.M{"$and": bson.A{mongoCondition(options.Condition{Field: "category.name", Op: options.OpLike, Value: "fic"})}}, relation)
+	stages = mongoMatchStages(main, relation, opts.Joins)
+	require.Len(t, stages, 4)
+	require.Equal(t, bson.M{"$match": main}, stages[0])
+	require.Contains(t, stages[1].(bson.M), "$lookup")
+	require.Equal(t, bson.M{"$match": relation}, stages[3])
+	require.Len(t, mongoMatchStages(main, bson.M{}, nil), 1)
+}
@@ -2,9 +2,11 @@ package crud
 
 import (
 	"context"
+	"log"
 	"reflect"
 
 	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/core/crud/types"
 	"github.com/kruily/gofastcrud/core/database"
 )
 
@@ -17,12 +19,20 @@ type IRepository[T ICrudEntity] interface {
 	DeleteById(ctx context.Context, id any, opts ...*options.DeleteOptions) error
 	FindById(ctx context.Context, id any) (T, error)
 	Find(ctx context.Context, entity T, opts *options.QueryOptions) ([]T, error)
+	FindByCursor(ctx context.Context, entity T, opts *options.QueryOptions) (*CursorPage[T], error)
 	Count(ctx context.Context, entity T) (int64, error)
 
 	// 批量操作
 	BatchCreate(ctx context.Context, entities []T, opts ...*options.BatchOptions) error
 	BatchUpdate(ctx context.Context, entities []T) error
 	BatchDelete(ctx context.Context, ids []any, opts ...*options.DeleteOptions) error
+	Upsert(ctx context.Context, entity T, conflictColumns []string, updateColumns []string) error
+	BatchUpsert(ctx context.Context, entities []T, conflictColumns []string, updateColumns []string, opts ...*options.BatchOptions) error
+
+	// 软删除
+	Restore(ctx context.Context, id any) error
+	ForceDelete(ctx context.Context, id any) error
+	FindTrashed(ctx context.Context, opts *options.QueryOptions) ([]T, int64, error)
 
 	// 条件查询
 	FindOne(ctx context.Context, query interface{}, args ...interface{}) (T, error)
@@ -31,43 +41,54 @@ type IRepository[T ICrudEntity] interface {
 
 	// 高级查询
 	// Page(ctx context.Context, page int, pageSize int) ([]T, int64, error)
-	// Where(query interface{}, args ...interface{}) IRepository[T]
-	// Order(value interface{}) IRepository[T]
-	// Select(query interface{}, args ...interface{}) IRepository[T]
-	// Preload(query ...string) IRepository[T]
-	// Joins(query string, args ...interface{}) IRepository[T]
-	// Group(query string) IRepository[T]
-	// Having(query interface{}, args ...interface{}) IRepository[T]
+	// Query 创建链式查询构建器，每次调用都是独立会话
+	Query() IQuery[T]
 
 	// 事务操作
 	Transaction(ctx context.Context, fc func(tx IRepository[T]) error) error
 	// WithTx(tx *gorm.DB) IRepository[T]
 
 	// 聚合操作
-	// Sum(ctx context.Context, field string) (float64, error)
-	// CountField(ctx context.Context, field string) (int64, error)
-	// Max(ctx context.Context, field string) (float64, error)
-	// Min(ctx context.Context, field string) (float64, error)
-	// Avg(ctx context.Context, field string) (float64, error)
-
-	// 锁
-	// LockForUpdate() IRepository[T]
-	// SharedLock() IRepository[T]
-
-	// Session 创建新会话，避免污染原有查询
-	// Session() IRepository[T]
+	Sum(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error)
+	CountField(ctx context.Context, field string, opts ...*options.QueryOptions) (int64, error)
+	Max(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error)
+	Min(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error)
+	Avg(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error)
+	GroupAggregate(ctx context.Context, opts *options.AggregateOptions) ([]map[string]interface{}, error)
+	// Aggregate 执行聚合管道并将结果解码到 out（切片指针），pipeline 可为 *Pipeline 或 bson 阶段切片，仅支持 MongoDB
+	Aggregate(ctx context.Context, pipeline interface{}, out interface{}) error
+
+	// 锁，返回加锁的仓储副本，需在 Transaction 回调中使用
+	LockForUpdate(opts ...*options.LockOptions) IRepository[T]
+	SharedLock(opts ...*options.LockOptions) IRepository[T]
 
 	// 查询钩子
 	// AddQueryHook(hook QueryHook) IRepository[T]
 }
 
-// Repository 仓储实现
+// Repository 仓储实现，统一调用实体生命周期钩子，写操作后记录审计日志并发布领域事件
+// 写操作及其中的查询（审计快照、删除前加载等）使用主库，配置只读副本时不受复制延迟影响
 type Repository[T ICrudEntity] struct {
-	crudRepo   IRepository[T]
-	entityType reflect.Type
+	crudRepo    IRepository[T]
+	entityType  reflect.Type
+	audited     bool       // 实体是否启用审计
+	deleteHooks bool       // 实体是否实现删除钩子
+	events      EventMode  // 领域事件发布方式
+	pending     *txEffects // 事务中暂存的审计日志与事件，提交后执行
+}
+
+// txEffects 事务中暂存的副作用，提交后写入审计日志并发布异步事件，回滚时丢弃
+type txEffects struct {
+	audits []*types.AuditLog
+	events []*EntityEvent
 }
 
-// NewRepository 创建仓储实例
+// withRepository 返回使用 repo 执行操作的仓储副本，副作用暂存到 pending
+func (r *Repository[T]) withRepository(repo IRepository[T], pending *txEffects) *Repository[T] {
+	return &Repository[T]{crudRepo: repo, entityType: r.entityType, audited: r.audited, deleteHooks: r.deleteHooks, events: r.events, pending: pending}
+}
+
+// NewRepository 创建仓储实例，实体实现 IDatasource 时使用其指定的数据源，数据源未配置时记录日志并使用 db
 func NewRepository[T ICrudEntity](db *database.Database, entity T) *Repository[T] {
 	entityType := reflect.TypeOf(entity)
 	if entityType.Kind() == reflect.Ptr {
@@ -75,14 +96,24 @@ func NewRepository[T ICrudEntity](db *database.Database, entity T) *Repository[T
 	}
 
 	repo := &Repository[T]{
-		entityType: entityType,
+		entityType:  entityType,
+		audited:     auditEnabled(entity),
+		deleteHooks: hasDeleteHooks(entity),
+		events:      eventModeOf(entity),
+	}
+	registerEventType(entityType)
+	if resolved, err := entityDatabase(db, entity); err != nil {
+		// 通过 ControllerFactory 注册的实体由 Migrate 返回该错误并终止启动
+		log.Printf("Failed to resolve datasource, using the given database: %v", err)
+	} else {
+		db = resolved
 	}
 	// 根据实体的DBType选择具体的仓储实现
 	switch entity.DBType() {
 	case DB_TYPE_GORM:
 		repo.crudRepo = newGormRepository(db.DB(), entity)
 	case DB_TYPE_MONGODB:
-		repo.crudRepo = newMongoRepository(db.MDB(), entity)
+		repo.crudRepo = newMongoRepository(db.MClient(), db.MDB(), entity)
 	}
 
 	return repo
@@ -90,51 +121,243 @@ func NewRepository[T ICrudEntity](db *database.Database, entity T) *Repository[T
 
 // FindOne 查询单个实体
 func (r *Repository[T]) FindOne(ctx context.Context, query interface{}, args ...interface{}) (T, error) {
-
-	return r.crudRepo.FindOne(ctx, query, args...)
+	entity, err := r.crudRepo.FindOne(ctx, query, args...)
+	if err != nil {
+		return entity, err
+	}
+	return entity, afterFind(ctx, entity)
 }
 
 // Find 查询实体列表
 func (r *Repository[T]) Find(ctx context.Context, entity T, opts *options.QueryOptions) ([]T, error) {
-	return r.crudRepo.Find(ctx, entity, opts)
+	items, err := r.crudRepo.Find(ctx, entity, opts)
+	if err != nil {
+		return items, err
+	}
+	return items, afterFind(ctx, items...)
+}
+
+// FindByCursor 游标分页查询
+func (r *Repository[T]) FindByCursor(ctx context.Context, entity T, opts *options.QueryOptions) (*CursorPage[T], error) {
+	page, err := r.crudRepo.FindByCursor(ctx, entity, opts)
+	if err != nil {
+		return page, err
+	}
+	return page, afterFind(ctx, page.Items...)
+}
+
+// Query 创建链式查询构建器，Find 与 First 的结果同样调用查询后钩子
+func (r *Repository[T]) Query() IQuery[T] {
+	return hookedQuery[T]{r.crudRepo.Query()}
 }
 
 // FindAll 查询所有符合条件的记录
 func (r *Repository[T]) FindAll(ctx context.Context, query interface{}, args ...interface{}) ([]T, error) {
-
-	return r.crudRepo.FindAll(ctx, query, args...)
+	items, err := r.crudRepo.FindAll(ctx, query, args...)
+	if err != nil {
+		return items, err
+	}
+	return items, afterFind(ctx, items...)
 }
 
 // FindById 根据ID查询
 func (r *Repository[T]) FindById(ctx context.Context, id any) (T, error) {
-
-	return r.crudRepo.FindById(ctx, id)
+	entity, err := r.crudRepo.FindById(ctx, id)
+	if err != nil {
+		return entity, err
+	}
+	return entity, afterFind(ctx, entity)
 }
 
 // Create 创建实体
 func (r *Repository[T]) Create(ctx context.Context, entity T) error {
-	return r.crudRepo.Create(ctx, entity)
+	ctx = database.WithPrimary(ctx)
+	if r.atomic() {
+		return r.transact(ctx, func(tx *Repository[T]) error {
+			return tx.Create(ctx, entity)
+		})
+	}
+	if err := beforeCreate(ctx, entity); err != nil {
+		return err
+	}
+	if err := r.crudRepo.Create(ctx, entity); err != nil {
+		return err
+	}
+	if r.auditing() {
+		r.record(ctx, types.AuditActionCreate, entity.GetID(), nil, auditSnapshot(entity))
+	}
+	if err := r.emit(ctx, EventCreated, entity.GetID(), entity, nil); err != nil {
+		return err
+	}
+	return afterCreate(ctx, entity)
 }
 
 // BatchCreate 批量创建
 func (r *Repository[T]) BatchCreate(ctx context.Context, entities []T, opts ...*options.BatchOptions) error {
-	return r.crudRepo.BatchCreate(ctx, entities, opts...)
+	ctx = database.WithPrimary(ctx)
+	if r.atomic() {
+		return r.transact(ctx, func(tx *Repository[T]) error {
+			return tx.BatchCreate(ctx, entities, opts...)
+		})
+	}
+	if err := beforeCreate(ctx, entities...); err != nil {
+		return err
+	}
+	if err := r.crudRepo.BatchCreate(ctx, entities, opts...); err != nil {
+		return err
+	}
+	if r.auditing() {
+		for _, entity := range entities {
+			r.record(ctx, types.AuditActionCreate, entity.GetID(), nil, auditSnapshot(entity))
+		}
+	}
+	if err := r.emitBatch(ctx, EventBatchCreated, entityIDs(entities), entities); err != nil {
+		return err
+	}
+	return afterCreate(ctx, entities...)
 }
 
-// Transaction 事务操作
+// Transaction 事务操作，回调中的仓储同样调用钩子，审计日志与异步事件在提交后执行，回滚时丢弃
 func (r *Repository[T]) Transaction(ctx context.Context, fc func(tx IRepository[T]) error) error {
-	return r.crudRepo.Transaction(ctx, fc)
+	var pending *txEffects
+	err := r.crudRepo.Transaction(ctx, func(tx IRepository[T]) error {
+		// 事务重试时回调会重新执行，只保留最后一次执行的副作用
+		pending = &txEffects{}
+		return fc(r.withRepository(tx, pending))
+	})
+	if err != nil {
+		return err
+	}
+	if r.pending != nil {
+		r.pending.audits = append(r.pending.audits, pending.audits...)
+		r.pending.events = append(r.pending.events, pending.events...)
+		return nil
+	}
+	r.flush(ctx, pending.audits...)
+	publishAsync(ctx, pending.events...)
+	return nil
 }
 
 // BatchDelete 批量删除
 func (r *Repository[T]) BatchDelete(ctx context.Context, ids []any, opts ...*options.DeleteOptions) error {
-
-	return r.crudRepo.BatchDelete(ctx, ids, opts...)
+	ctx = database.WithPrimary(ctx)
+	if r.atomic() {
+		return r.transact(ctx, func(tx *Repository[T]) error {
+			return tx.BatchDelete(ctx, ids, opts...)
+		})
+	}
+	if !r.auditing() && !r.deleteHooks {
+		if err := r.crudRepo.BatchDelete(ctx, ids, opts...); err != nil {
+			return err
+		}
+		return r.emitBatch(ctx, EventBatchDeleted, ids, nil)
+	}
+	existing := make(map[int]T, len(ids))
+	for i, id := range ids {
+		if entity, err := r.crudRepo.FindById(ctx, id); err == nil {
+			if err := beforeDelete(ctx, entity); err != nil {
+				return err
+			}
+			existing[i] = entity
+		}
+	}
+	if err := r.crudRepo.BatchDelete(ctx, ids, opts...); err != nil {
+		return err
+	}
+	for i, id := range ids {
+		entity, ok := existing[i]
+		if !ok {
+			continue
+		}
+		if r.auditing() {
+			r.record(ctx, types.AuditActionDelete, id, auditSnapshot(entity), nil)
+		}
+		if err := afterDelete(ctx, entity); err != nil {
+			return err
+		}
+	}
+	return r.emitBatch(ctx, EventBatchDeleted, ids, nil)
 }
 
 // BatchUpdate 批量更新
 func (r *Repository[T]) BatchUpdate(ctx context.Context, entities []T) error {
-	return r.crudRepo.BatchUpdate(ctx, entities)
+	ctx = database.WithPrimary(ctx)
+	if r.atomic() {
+		return r.transact(ctx, func(tx *Repository[T]) error {
+			return tx.BatchUpdate(ctx, entities)
+		})
+	}
+	if err := beforeUpdate(ctx, nil, entities...); err != nil {
+		return err
+	}
+	auditing := r.auditing()
+	befores := make([]map[string]interface{}, len(entities))
+	if auditing {
+		for i, entity := range entities {
+			befores[i], _ = r.snapshot(ctx, entity.GetID())
+		}
+	}
+	if err := r.crudRepo.BatchUpdate(ctx, entities); err != nil {
+		return err
+	}
+	if auditing {
+		for i, entity := range entities {
+			after, _ := r.snapshot(ctx, entity.GetID())
+			r.record(ctx, types.AuditActionUpdate, entity.GetID(), befores[i], after)
+		}
+	}
+	if err := r.emitBatch(ctx, EventBatchUpdated, entityIDs(entities), entities); err != nil {
+		return err
+	}
+	return afterUpdate(ctx, nil, entities...)
+}
+
+// Upsert 插入或更新，调用创建钩子；冲突字段可能不是主键，审计日志只记录写入后的值
+func (r *Repository[T]) Upsert(ctx context.Context, entity T, conflictColumns []string, updateColumns []string) error {
+	ctx = database.WithPrimary(ctx)
+	if r.atomic() {
+		return r.transact(ctx, func(tx *Repository[T]) error {
+			return tx.Upsert(ctx, entity, conflictColumns, updateColumns)
+		})
+	}
+	if err := beforeCreate(ctx, entity); err != nil {
+		return err
+	}
+	if err := r.crudRepo.Upsert(ctx, entity, conflictColumns, updateColumns); err != nil {
+		return err
+	}
+	if r.auditing() {
+		r.record(ctx, types.AuditActionUpsert, entity.GetID(), nil, auditSnapshot(entity))
+	}
+	if err := r.emit(ctx, EventUpserted, entity.GetID(), entity, nil); err != nil {
+		return err
+	}
+	return afterCreate(ctx, entity)
+}
+
+// BatchUpsert 批量插入或更新
+func (r *Repository[T]) BatchUpsert(ctx context.Context, entities []T, conflictColumns []string, updateColumns []string, opts ...*options.BatchOptions) error {
+	ctx = database.WithPrimary(ctx)
+	if r.atomic() {
+		return r.transact(ctx, func(tx *Repository[T]) error {
+			return tx.BatchUpsert(ctx, entities, conflictColumns, updateColumns, opts...)
+		})
+	}
+	if err := beforeCreate(ctx, entities...); err != nil {
+		return err
+	}
+	if err := r.crudRepo.BatchUpsert(ctx, entities, conflictColumns, updateColumns, opts...); err != nil {
+		return err
+	}
+	if r.auditing() {
+		for _, entity := range entities {
+			r.record(ctx, types.AuditActionUpsert, entity.GetID(), nil, auditSnapshot(entity))
+		}
+	}
+	if err := r.emitBatch(ctx, EventBatchUpserted, entityIDs(entities), entities); err != nil {
+		return err
+	}
+	return afterCreate(ctx, entities...)
 }
 
 // Count 统计记录数
@@ -150,20 +373,190 @@ func (r *Repository[T]) Exists(ctx context.Context, query interface{}, args ...i
 
 // Delete 删除实体
 func (r *Repository[T]) Delete(ctx context.Context, entity T, opts ...*options.DeleteOptions) error {
-	return r.crudRepo.Delete(ctx, entity, opts...)
+	ctx = database.WithPrimary(ctx)
+	if r.atomic() {
+		return r.transact(ctx, func(tx *Repository[T]) error {
+			return tx.Delete(ctx, entity, opts...)
+		})
+	}
+	if err := beforeDelete(ctx, entity); err != nil {
+		return err
+	}
+	auditing := r.auditing()
+	var before map[string]interface{}
+	found := false
+	if auditing {
+		before, found = r.snapshot(ctx, entity.GetID())
+	}
+	if err := r.crudRepo.Delete(ctx, entity, opts...); err != nil {
+		return err
+	}
+	if found {
+		r.record(ctx, types.AuditActionDelete, entity.GetID(), before, nil)
+	}
+	if err := r.emit(ctx, EventDeleted, entity.GetID(), entity, nil); err != nil {
+		return err
+	}
+	return afterDelete(ctx, entity)
 }
 
 // DeleteById 根据ID删除
 func (r *Repository[T]) DeleteById(ctx context.Context, id any, opts ...*options.DeleteOptions) error {
-	entity := NewModel[T]()
-	if err := entity.SetID(id); err != nil {
+	ctx = database.WithPrimary(ctx)
+	if r.atomic() {
+		return r.transact(ctx, func(tx *Repository[T]) error {
+			return tx.DeleteById(ctx, id, opts...)
+		})
+	}
+	return r.deleteById(ctx, types.AuditActionDelete, EventDeleted, id, func() error {
+		return r.crudRepo.DeleteById(ctx, id, opts...)
+	})
+}
+
+// deleteById 按 ID 删除，先查询记录以调用删除钩子、记录审计日志并携带到删除事件中，记录不存在时只执行删除
+func (r *Repository[T]) deleteById(ctx context.Context, action string, eventAction string, id any, deleteFn func() error) error {
+	if !r.auditing() && !r.deleteHooks && !r.publishing() {
+		return deleteFn()
+	}
+	entity, err := r.crudRepo.FindById(ctx, id)
+	found := err == nil
+	if found {
+		if err := beforeDelete(ctx, entity); err != nil {
+			return err
+		}
+	}
+	if err := deleteFn(); err != nil {
+		return err
+	}
+	if r.auditing() && (found || action == types.AuditActionForceDelete) {
+		var before map[string]interface{}
+		if found {
+			before = auditSnapshot(entity)
+		}
+		r.record(ctx, action, id, before, nil)
+	}
+	if !found {
+		if action == types.AuditActionForceDelete {
+			return r.emit(ctx, eventAction, id, nil, nil)
+		}
+		return nil
+	}
+	if err := r.emit(ctx, eventAction, id, entity, nil); err != nil {
+		return err
+	}
+	return afterDelete(ctx, entity)
+}
+
+// Restore 恢复软删除的记录
+func (r *Repository[T]) Restore(ctx context.Context, id any) error {
+	ctx = database.WithPrimary(ctx)
+	if r.atomic() {
+		return r.transact(ctx, func(tx *Repository[T]) error {
+			return tx.Restore(ctx, id)
+		})
+	}
+	if err := r.crudRepo.Restore(ctx, id); err != nil {
 		return err
 	}
-	return r.crudRepo.DeleteById(ctx, entity, opts...)
+	if r.auditing() {
+		after, _ := r.snapshot(ctx, id)
+		r.record(ctx, types.AuditActionRestore, id, nil, after)
+	}
+	return r.emit(ctx, EventRestored, id, nil, nil)
+}
+
+// ForceDelete 物理删除记录，已软删除的记录不调用删除钩子，也不记录删除前的值
+func (r *Repository[T]) ForceDelete(ctx context.Context, id any) error {
+	ctx = database.WithPrimary(ctx)
+	if r.atomic() {
+		return r.transact(ctx, func(tx *Repository[T]) error {
+			return tx.ForceDelete(ctx, id)
+		})
+	}
+	return r.deleteById(ctx, types.AuditActionForceDelete, EventForceDeleted, id, func() error {
+		return r.crudRepo.ForceDelete(ctx, id)
+	})
+}
+
+// FindTrashed 查询已软删除的记录
+func (r *Repository[T]) FindTrashed(ctx context.Context, opts *options.QueryOptions) ([]T, int64, error) {
+	items, total, err := r.crudRepo.FindTrashed(ctx, opts)
+	if err != nil {
+		return items, total, err
+	}
+	return items, total, afterFind(ctx, items...)
 }
 
 // Update 更新实体
 func (r *Repository[T]) Update(ctx context.Context, entity T, updateFields map[string]interface{}) error {
-	// 如果没有提供更新字段，则使用整个实体进行更新
-	return r.crudRepo.Update(ctx, entity, updateFields)
+	ctx = database.WithPrimary(ctx)
+	if r.atomic() {
+		return r.transact(ctx, func(tx *Repository[T]) error {
+			return tx.Update(ctx, entity, updateFields)
+		})
+	}
+	if err := beforeUpdate(ctx, updateFields, entity); err != nil {
+		return err
+	}
+	auditing := r.auditing()
+	var before map[string]interface{}
+	if auditing {
+		before, _ = r.snapshot(ctx, entity.GetID())
+	}
+	if err := r.crudRepo.Update(ctx, entity, updateFields); err != nil {
+		return err
+	}
+	if auditing {
+		after, _ := r.snapshot(ctx, entity.GetID())
+		r.record(ctx, types.AuditActionUpdate, entity.GetID(), before, after)
+	}
+	if err := r.emit(ctx, EventUpdated, entity.GetID(), entity, updateFields); err != nil {
+		return err
+	}
+	return afterUpdate(ctx, updateFields, entity)
+}
+
+// Sum 字段求和
+func (r *Repository[T]) Sum(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error) {
+	return r.crudRepo.Sum(ctx, field, opts...)
+}
+
+// CountField 统计字段非空记录数
+func (r *Repository[T]) CountField(ctx context.Context, field string, opts ...*options.QueryOptions) (int64, error) {
+	return r.crudRepo.CountField(ctx, field, opts...)
+}
+
+// Max 字段最大值
+func (r *Repository[T]) Max(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error) {
+	return r.crudRepo.Max(ctx, field, opts...)
+}
+
+// Min 字段最小值
+func (r *Repository[T]) Min(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error) {
+	return r.crudRepo.Min(ctx, field, opts...)
+}
+
+// Avg 字段平均值
+func (r *Repository[T]) Avg(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error) {
+	return r.crudRepo.Avg(ctx, field, opts...)
+}
+
+// GroupAggregate 分组聚合
+func (r *Repository[T]) GroupAggregate(ctx context.Context, opts *options.AggregateOptions) ([]map[string]interface{}, error) {
+	return r.crudRepo.GroupAggregate(ctx, opts)
+}
+
+// Aggregate 执行聚合管道
+func (r *Repository[T]) Aggregate(ctx context.Context, pipeline interface{}, out interface{}) error {
+	return r.crudRepo.Aggregate(ctx, pipeline, out)
+}
+
+// LockForUpdate 返回加排他锁的仓储
+func (r *Repository[T]) LockForUpdate(opts ...*options.LockOptions) IRepository[T] {
+	return r.withRepository(r.crudRepo.LockForUpdate(opts...), r.pending)
+}
+
+// SharedLock 返回加共享锁的仓储
+func (r *Repository[T]) SharedLock(opts ...*options.LockOptions) IRepository[T] {
+	return r.withRepository(r.crudRepo.SharedLock(opts...), r.pending)
 }
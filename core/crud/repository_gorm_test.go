@@ -0,0 +1,313 @@
+package crud
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testArticle 测试用实体
+type testArticle struct {
+	*BaseEntity
+	Title string  `json:"title" filter:"eq,neq,like,nlike,in,nin"`
+	Score float64 `json:"score" filter:"gt,gte,lt,lte,between"`
+}
+
+func (*testArticle) TableName() string {
+	return "test_articles"
+}
+
+func (a *testArticle) Init() {
+	if a.BaseEntity == nil {
+		a.BaseEntity = &BaseEntity{}
+	}
+}
+
+func setupGormRepository(t *testing.T) (*gorm.DB, *gormRepository[*testArticle]) {
+	return setupTestRepository(t, &testArticle{})
+}
+
+// setupTestRepository 创建内存 sqlite 数据库，迁移 entity 与 models，返回 entity 的 gorm 仓储
+// 限制为单个连接，保证所有会话使用同一个内存数据库
+func setupTestRepository[T ICrudEntity](t *testing.T, entity T, models ...interface{}) (*gorm.DB, *gormRepository[T]) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	require.NoError(t, db.AutoMigrate(append([]interface{}{entity}, models...)...))
+	return db, newGormRepository(db, entity)
+}
+
+func seedArticles(t *testing.T, repo *gormRepository[*testArticle], n int) {
+	for i := 1; i <= n; i++ {
+		article := &testArticle{BaseEntity: &BaseEntity{}, Title: fmt.Sprintf("article-%02d", i), Score: float64(i % 3)}
+		require.NoError(t, repo.Create(context.Background(), article))
+	}
+}
+
+func TestGormFindByCursor(t *testing.T) {
+	_, repo := setupGormRepository(t)
+	seedArticles(t, repo, 7)
+	ctx := context.Background()
+
+	newOpts := func(cursor string) *options.QueryOptions {
+		return options.NewQueryOptions(
+			options.WithOrderBy("score desc"),
+			options.WithCursor(cursor),
+			options.WithLimit(3),
+		)
+	}
+
+	// 向后翻页直到结束，收集全部ID
+	var seen []uint64
+	var pages []*CursorPage[*testArticle]
+	cursor := ""
+	for {
+		page, err := repo.FindByCursor(ctx, &testArticle{}, newOpts(cursor))
+		require.NoError(t, err)
+		pages = append(pages, page)
+		for _, item := range page.Items {
+			seen = append(seen, item.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	require.Len(t, seen, 7)
+	require.Len(t, pages, 3)
+	require.Empty(t, pages[0].PrevCursor)
+	require.NotEmpty(t, pages[1].PrevCursor)
+
+	// 从第三页向前翻页应得到第二页
+	prev, err := repo.FindByCursor(ctx, &testArticle{}, newOpts(pages[2].PrevCursor))
+	require.NoError(t, err)
+	require.Equal(t, pages[1].Items, prev.Items)
+	require.NotEmpty(t, prev.PrevCursor)
+	require.NotEmpty(t, prev.NextCursor)
+
+	// 游标与排序不匹配时返回错误
+	mismatch := options.NewQueryOptions(options.WithOrderBy("title asc"), options.WithCursor(pages[0].NextCursor), options.WithLimit(3))
+	_, err = repo.FindByCursor(ctx, &testArticle{}, mismatch)
+	require.Error(t, err)
+}
+
+func TestGormAggregate(t *testing.T) {
+	_, repo := setupGormRepository(t)
+	seedArticles(t, repo, 7)
+	ctx := context.Background()
+
+	// 分数依次为 1 2 0 1 2 0 1
+	sum, err := repo.Sum(ctx, "score")
+	require.NoError(t, err)
+	require.Equal(t, 7.0, sum)
+
+	positive := options.NewQueryOptions()
+	positive.Where["score > ?"] = 0
+	count, err := repo.CountField(ctx, "score", positive)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), count)
+
+	max, err := repo.Max(ctx, "score")
+	require.NoError(t, err)
+	require.Equal(t, 2.0, max)
+
+	rows, err := repo.GroupAggregate(ctx, options.NewAggregateOptions(options.AggregateCount, "id",
+		options.WithGroupBy("score"),
+		options.WithHaving(&options.Having{Op: "gte", Value: 3}),
+	))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	score, _ := toFloat64(rows[0]["score"])
+	require.Equal(t, 1.0, score)
+	require.Equal(t, 3.0, rows[0]["value"])
+
+	// 未知字段返回错误
+	_, err = repo.Sum(ctx, "unknown")
+	require.Error(t, err)
+}
+
+func TestGormQueryBuilder(t *testing.T) {
+	_, repo := setupGormRepository(t)
+	seedArticles(t, repo, 7)
+	ctx := context.Background()
+
+	base := repo.Query().Where("score = ?", 1)
+	ordered := base.Order("id desc").Limit(2)
+	// 派生构建器不影响原构建器
+	narrowed := base.Where("title = ?", "article-04")
+
+	all, err := base.Find(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+
+	top, err := ordered.Find(ctx)
+	require.NoError(t, err)
+	require.Len(t, top, 2)
+	require.Equal(t, "article-07", top[0].Title)
+
+	one, err := narrowed.First(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "article-04", one.Title)
+
+	count, err := ordered.Count(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+
+	exists, err := repo.Query().Where("score > ?", 5).Exists(ctx)
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+func TestGormLocking(t *testing.T) {
+	// postgres 生成锁子句
+	pg, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DisableAutomaticPing: true})
+	require.NoError(t, err)
+	pgRepo := newGormRepository(pg, &testArticle{}).LockForUpdate(options.WithSkipLocked()).(*gormRepository[*testArticle])
+	sql := pg.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var articles []*testArticle
+		return gormLocking(tx, pgRepo.lock).Find(&articles)
+	})
+	require.Contains(t, sql, "FOR UPDATE SKIP LOCKED")
+
+	// sqlite 忽略锁子句，事务内照常查询
+	_, repo := setupGormRepository(t)
+	seedArticles(t, repo, 3)
+	ctx := context.Background()
+	err = repo.Transaction(ctx, func(tx IRepository[*testArticle]) error {
+		article, err := tx.LockForUpdate(options.WithNoWait()).FindById(ctx, uint64(1))
+		if err != nil {
+			return err
+		}
+		return tx.Update(ctx, article, map[string]interface{}{"score": 10})
+	})
+	require.NoError(t, err)
+	article, err := repo.FindById(ctx, uint64(1))
+	require.NoError(t, err)
+	require.Equal(t, 10.0, article.Score)
+}
+
+func TestGormSoftDelete(t *testing.T) {
+	_, repo := setupGormRepository(t)
+	seedArticles(t, repo, 3)
+	ctx := context.Background()
+
+	require.NoError(t, repo.DeleteById(ctx, uint64(1), options.WithDeletedBy("42")))
+	_, err := repo.FindById(ctx, uint64(1))
+	require.Error(t, err)
+	count, err := repo.Count(ctx, &testArticle{})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	trashed, total, err := repo.FindTrashed(ctx, options.NewQueryOptions(options.WithPage(1), options.WithPageSize(10)))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	require.Equal(t, "42", trashed[0].DeletedBy)
+
+	// 恢复后可再次查询，重复恢复返回错误
+	require.NoError(t, repo.Restore(ctx, uint64(1)))
+	article, err := repo.FindById(ctx, uint64(1))
+	require.NoError(t, err)
+	require.Empty(t, article.DeletedBy)
+	require.Error(t, repo.Restore(ctx, uint64(1)))
+
+	// 物理删除后回收站中也不存在
+	require.NoError(t, repo.BatchDelete(ctx, []any{uint64(2)}))
+	require.NoError(t, repo.ForceDelete(ctx, uint64(2)))
+	_, total, err = repo.FindTrashed(ctx, options.NewQueryOptions())
+	require.NoError(t, err)
+	require.Zero(t, total)
+}
+
+// testVersionedArticle 支持乐观锁的测试实体
+type testVersionedArticle struct {
+	*BaseEntity
+	Versioned
+	Title string `json:"title"`
+}
+
+func (*testVersionedArticle) TableName() string {
+	return "test_versioned_articles"
+}
+
+func (a *testVersionedArticle) Init() {
+	if a.BaseEntity == nil {
+		a.BaseEntity = &BaseEntity{}
+	}
+}
+
+func TestGormOptimisticLock(t *testing.T) {
+	db, _ := setupGormRepository(t)
+	require.NoError(t, db.AutoMigrate(&testVersionedArticle{}))
+	repo := newGormRepository(db, &testVersionedArticle{})
+	ctx := context.Background()
+
+	article := &testVersionedArticle{BaseEntity: &BaseEntity{}, Title: "v1"}
+	require.NoError(t, repo.Create(ctx, article))
+	require.Equal(t, int64(1), article.Version)
+
+	stale, err := repo.FindById(ctx, article.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Update(ctx, article, map[string]interface{}{"title": "v2"}))
+	require.Equal(t, int64(2), article.Version)
+
+	// 使用过期版本更新返回冲突，且实体版本号不变
+	err = repo.Update(ctx, stale, map[string]interface{}{"title": "stale"})
+	require.True(t, errors.Is(err, errors.ErrVersionConflict))
+	require.Equal(t, int64(1), stale.Version)
+
+	article.Title = "v3"
+	require.NoError(t, repo.Update(ctx, article, nil))
+	saved, err := repo.FindById(ctx, article.ID)
+	require.NoError(t, err)
+	require.Equal(t, "v3", saved.Title)
+	require.Equal(t, int64(3), saved.Version)
+}
+
+func TestGormUpsert(t *testing.T) {
+	db, repo := setupGormRepository(t)
+	seedArticles(t, repo, 2)
+	ctx := context.Background()
+
+	// 主键冲突时只更新指定字段
+	article := &testArticle{BaseEntity: &BaseEntity{ID: 1}, Title: "changed", Score: 9}
+	require.NoError(t, repo.Upsert(ctx, article, nil, []string{"score"}))
+	saved, err := repo.FindById(ctx, uint64(1))
+	require.NoError(t, err)
+	require.Equal(t, "article-01", saved.Title)
+	require.Equal(t, 9.0, saved.Score)
+
+	articles := []*testArticle{
+		{BaseEntity: &BaseEntity{ID: 2}, Title: "updated", Score: 5},
+		{BaseEntity: &BaseEntity{ID: 3}, Title: "inserted", Score: 6},
+	}
+	require.NoError(t, repo.BatchUpsert(ctx, articles, []string{"id"}, nil))
+	saved, err = repo.FindById(ctx, uint64(2))
+	require.NoError(t, err)
+	require.Equal(t, "updated", saved.Title)
+	count, err := repo.Count(ctx, &testArticle{})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+
+	require.Error(t, repo.Upsert(ctx, article, []string{"unknown"}, nil))
+
+	// 乐观锁实体冲突时版本号自增
+	require.NoError(t, db.AutoMigrate(&testVersionedArticle{}))
+	versionedRepo := newGormRepository(db, &testVersionedArticle{})
+	require.NoError(t, versionedRepo.Create(ctx, &testVersionedArticle{BaseEntity: &BaseEntity{}, Title: "v1"}))
+	require.NoError(t, versionedRepo.Upsert(ctx, &testVersionedArticle{BaseEntity: &BaseEntity{ID: 1}, Title: "v2"}, nil, nil))
+	versioned, err := versionedRepo.FindById(ctx, uint64(1))
+	require.NoError(t, err)
+	require.Equal(t, "v2", versioned.Title)
+	require.Equal(t, int64(2), versioned.Version)
+}
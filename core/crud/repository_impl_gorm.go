@@ -2,17 +2,25 @@ package crud
 
 import (
 	"context"
+	"database/sql"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
 // gormRepository gorm仓储实现
 type gormRepository[T ICrudEntity] struct {
 	db         *gorm.DB
 	entityType reflect.Type
-	preloads   []string
+	lock       *clause.Locking // 悲观锁，仅作用于返回实体的查询
+	softDelete softDeleteMeta  // 软删除字段
+	tenant     string          // 租户字段，为空表示实体不区分租户
 }
 
 // newGormRepository 创建gorm仓储实例
@@ -24,7 +32,8 @@ func newGormRepository[T ICrudEntity](db *gorm.DB, entity T) *gormRepository[T]
 	return &gormRepository[T]{
 		db:         db,
 		entityType: entityType,
-		preloads:   make([]string, 0),
+		softDelete: softDeleteMetaOf(entityType, DB_TYPE_GORM),
+		tenant:     tenantColumn(entityType, DB_TYPE_GORM),
 	}
 }
 
@@ -39,19 +48,92 @@ func newGormRepository[T ICrudEntity](db *gorm.DB, entity T) *gormRepository[T]
 // 	return r
 // }
 
-// applyPreloads 应用预加载
-func (r *gormRepository[T]) applyPreloads(db *gorm.DB) *gorm.DB {
-	for _, preload := range r.preloads {
-		db = db.Preload(preload)
+// session 创建绑定上下文的新会话，所有查询都应基于此方法，避免条件在请求间共享
+// 多租户实体追加当前租户条件，上下文中缺少租户时会话携带错误；上下文携带数据权限时追加数据权限条件
+func (r *gormRepository[T]) session(ctx context.Context) *gorm.DB {
+	db := r.db.WithContext(ctx)
+	condition, err := r.tenantCondition(ctx)
+	if err != nil {
+		db.AddError(err)
+		return db
+	}
+	if condition != nil {
+		db = db.Where(condition)
+	}
+	if scope := dataScopeOf(ctx, r.entityType); scope != nil {
+		expr, err := scope.Expression()
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		db = db.Where(expr)
 	}
 	return db
 }
 
+// tenantCondition 当前租户条件，实体不区分租户或跳过租户隔离时返回 nil
+func (r *gormRepository[T]) tenantCondition(ctx context.Context) (clause.Expression, error) {
+	if r.tenant == "" {
+		return nil, nil
+	}
+	tenantID, scoped, err := currentTenant(ctx)
+	if err != nil || !scoped {
+		return nil, err
+	}
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: r.tenant}, Value: tenantID}, nil
+}
+
+// Query 创建链式查询构建器
+func (r *gormRepository[T]) Query() IQuery[T] {
+	return newGormQuery[T](r.session, r.lock)
+}
+
+// LockForUpdate 返回加排他锁（SELECT ... FOR UPDATE）的仓储副本，需在 Transaction 回调中使用
+func (r *gormRepository[T]) LockForUpdate(opts ...*options.LockOptions) IRepository[T] {
+	return r.withLock(clause.LockingStrengthUpdate, opts...)
+}
+
+// SharedLock 返回加共享锁（SELECT ... FOR SHARE）的仓储副本，需在 Transaction 回调中使用
+func (r *gormRepository[T]) SharedLock(opts ...*options.LockOptions) IRepository[T] {
+	return r.withLock(clause.LockingStrengthShare, opts...)
+}
+
+// withLock 创建带锁的仓储副本
+func (r *gormRepository[T]) withLock(strength string, opts ...*options.LockOptions) IRepository[T] {
+	lockOpts := options.NewLockOptions(opts...)
+	lock := &clause.Locking{Strength: strength}
+	if lockOpts.NoWait {
+		lock.Options = clause.LockingOptionsNoWait
+	} else if lockOpts.SkipLocked {
+		lock.Options = clause.LockingOptionsSkipLocked
+	}
+	return &gormRepository[T]{
+		db:         r.db,
+		entityType: r.entityType,
+		lock:       lock,
+		softDelete: r.softDelete,
+		tenant:     r.tenant,
+	}
+}
+
+// gormLocking 为查询添加锁子句
+// sqlite 不支持行级锁（写事务期间锁定整个数据库），sqlserver 需使用表提示，两者均忽略锁子句
+func gormLocking(db *gorm.DB, lock *clause.Locking) *gorm.DB {
+	if lock == nil {
+		return db
+	}
+	switch db.Dialector.Name() {
+	case "sqlite", "sqlserver":
+		return db
+	}
+	return db.Clauses(*lock)
+}
+
 // FindOne 查询单个实体
 func (r *gormRepository[T]) FindOne(ctx context.Context, query interface{}, args ...interface{}) (T, error) {
 	// var entity T
 	entity := NewModel[T]()
-	db := r.applyPreloads(r.db.WithContext(ctx))
+	db := gormLocking(r.session(ctx), r.lock)
 	err := db.Where(query, args...).First(&entity).Error
 	if err != nil {
 		return entity, err
@@ -62,7 +144,7 @@ func (r *gormRepository[T]) FindOne(ctx context.Context, query interface{}, args
 // Find 查询实体列表
 func (r *gormRepository[T]) Find(ctx context.Context, entity T, opts *options.QueryOptions) ([]T, error) {
 	var entities []T
-	db := r.applyPreloads(r.db.WithContext(ctx).Model(&entity))
+	db := gormLocking(r.session(ctx).Model(&entity), r.lock)
 
 	// 应用查询选项
 	db = opts.ApplyQueryOptions(db)
@@ -71,10 +153,69 @@ func (r *gormRepository[T]) Find(ctx context.Context, entity T, opts *options.Qu
 	return entities, err
 }
 
+// FindByCursor 游标分页查询
+func (r *gormRepository[T]) FindByCursor(ctx context.Context, entity T, opts *options.QueryOptions) (*CursorPage[T], error) {
+	keys, err := cursorKeys(r.entityType, DB_TYPE_GORM, opts.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+	var cursor *options.Cursor
+	if opts.Cursor != "" {
+		if cursor, keys, err = decodeCursor(opts.Cursor, keys); err != nil {
+			return nil, err
+		}
+	}
+	backward := cursor != nil && cursor.Backward
+
+	// 排序由游标键决定，其余查询选项照常应用
+	queryOpts := *opts
+	queryOpts.OrderBy = nil
+	db := gormLocking(r.session(ctx).Model(&entity), r.lock)
+	db = queryOpts.ApplyQueryOptions(db)
+
+	if cursor != nil {
+		db = db.Where(gormKeysetCondition(keys, backward))
+	}
+	for _, key := range keys {
+		db = db.Order(clause.OrderByColumn{
+			Column: clause.Column{Table: clause.CurrentTable, Name: key.Name},
+			Desc:   !keysetAscending(key, backward),
+		})
+	}
+
+	var entities []T
+	if err := db.Limit(opts.Limit + 1).Find(&entities).Error; err != nil {
+		return nil, err
+	}
+	return buildCursorPage(entities, keys, opts.Limit, cursor)
+}
+
+// gormKeysetCondition 构建 keyset 条件：(k1 > v1) OR (k1 = v1 AND k2 > v2) ...
+func gormKeysetCondition(keys []cursorKey, backward bool) clause.Expression {
+	ors := make([]clause.Expression, 0, len(keys))
+	for i, key := range keys {
+		ands := make([]clause.Expression, 0, i+1)
+		for _, prev := range keys[:i] {
+			ands = append(ands, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: prev.Name}, Value: prev.value})
+		}
+		column := clause.Column{Table: clause.CurrentTable, Name: key.Name}
+		if keysetAscending(key, backward) {
+			ands = append(ands, clause.Gt{Column: column, Value: key.value})
+		} else {
+			ands = append(ands, clause.Lt{Column: column, Value: key.value})
+		}
+		ors = append(ors, clause.And(ands...))
+	}
+	if len(ors) == 1 {
+		return ors[0]
+	}
+	return clause.Or(ors...)
+}
+
 // FindAll 查询所有符合条件的记录
 func (r *gormRepository[T]) FindAll(ctx context.Context, query interface{}, args ...interface{}) ([]T, error) {
 	var entities []T
-	db := r.applyPreloads(r.db.WithContext(ctx))
+	db := gormLocking(r.session(ctx), r.lock)
 	err := db.Where(query, args...).Find(&entities).Error
 	return entities, err
 }
@@ -83,7 +224,7 @@ func (r *gormRepository[T]) FindAll(ctx context.Context, query interface{}, args
 func (r *gormRepository[T]) FindById(ctx context.Context, id any) (T, error) {
 	// var entity T
 	entity := NewModel[T]()
-	db := r.applyPreloads(r.db.WithContext(ctx))
+	db := gormLocking(r.session(ctx), r.lock)
 	err := db.First(&entity, id).Error
 	if err != nil {
 		return entity, err
@@ -93,7 +234,11 @@ func (r *gormRepository[T]) FindById(ctx context.Context, id any) (T, error) {
 
 // 实现所有接口方法...
 func (r *gormRepository[T]) Create(ctx context.Context, entity T) error {
-	return r.db.WithContext(ctx).Create(entity).Error
+	if err := assignTenant(ctx, entity); err != nil {
+		return err
+	}
+	initVersion(entity)
+	return r.session(ctx).Create(entity).Error
 }
 
 // BatchCreate 批量创建
@@ -102,7 +247,219 @@ func (r *gormRepository[T]) BatchCreate(ctx context.Context, entities []T, opts
 	if len(opts) > 0 && opts[0].BatchSize > 0 {
 		batchSize = opts[0].BatchSize
 	}
-	return r.db.WithContext(ctx).CreateInBatches(entities, batchSize).Error
+	if err := assignTenant(ctx, entities...); err != nil {
+		return err
+	}
+	for _, entity := range entities {
+		initVersion(entity)
+	}
+	return r.session(ctx).CreateInBatches(entities, batchSize).Error
+}
+
+// Upsert 插入实体，conflictColumns 冲突时更新 updateColumns
+// conflictColumns 为空时使用主键；updateColumns 为空时更新除主键、冲突字段、租户字段与创建时间外的所有字段
+// MySQL 使用 ON DUPLICATE KEY UPDATE，忽略 conflictColumns，以表上的唯一索引为准
+// 多租户或携带数据权限时，先在事务中校验主键与唯一索引上冲突的已有记录均在当前范围内，否则拒绝写入
+func (r *gormRepository[T]) Upsert(ctx context.Context, entity T, conflictColumns []string, updateColumns []string) error {
+	onConflict, err := r.scopedOnConflict(ctx, conflictColumns, updateColumns)
+	if err != nil {
+		return err
+	}
+	if err := assignTenant(ctx, entity); err != nil {
+		return err
+	}
+	initVersion(entity)
+	return r.upsert(ctx, []T{entity}, func(db *gorm.DB) error {
+		return db.Clauses(onConflict).Create(entity).Error
+	})
+}
+
+// BatchUpsert 批量插入或更新
+func (r *gormRepository[T]) BatchUpsert(ctx context.Context, entities []T, conflictColumns []string, updateColumns []string, opts ...*options.BatchOptions) error {
+	onConflict, err := r.scopedOnConflict(ctx, conflictColumns, updateColumns)
+	if err != nil {
+		return err
+	}
+	batchSize := 100
+	if len(opts) > 0 && opts[0].BatchSize > 0 {
+		batchSize = opts[0].BatchSize
+	}
+	if err := assignTenant(ctx, entities...); err != nil {
+		return err
+	}
+	for _, entity := range entities {
+		initVersion(entity)
+	}
+	return r.upsert(ctx, entities, func(db *gorm.DB) error {
+		return db.Clauses(onConflict).CreateInBatches(entities, batchSize).Error
+	})
+}
+
+// upsert 执行冲突更新，多租户或携带数据权限时在事务中先校验冲突记录的归属
+// MySQL 的 ON DUPLICATE KEY UPDATE 不支持冲突更新条件，仅靠 OnConflict.Where 会覆盖其他租户的记录
+func (r *gormRepository[T]) upsert(ctx context.Context, entities []T, create func(db *gorm.DB) error) error {
+	if r.tenant == "" && dataScopeOf(ctx, r.entityType) == nil {
+		return create(r.session(ctx))
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repo := &gormRepository[T]{db: tx, entityType: r.entityType, softDelete: r.softDelete, tenant: r.tenant}
+		if err := repo.checkUpsertConflicts(ctx, entities); err != nil {
+			return err
+		}
+		return create(repo.session(ctx))
+	})
+}
+
+// checkUpsertConflicts 校验实体在主键与唯一索引上冲突的已有记录均属于当前租户且在数据权限范围内，并锁定这些记录
+func (r *gormRepository[T]) checkUpsertConflicts(ctx context.Context, entities []T) error {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(NewModel[T]()); err != nil {
+		return err
+	}
+	for _, key := range uniqueKeys(stmt.Schema) {
+		conditions := make([]clause.Expression, 0, len(entities))
+		for _, entity := range entities {
+			value := reflect.ValueOf(entity)
+			match := make([]clause.Expression, 0, len(key))
+			for _, f := range key {
+				v, zero := f.ValueOf(ctx, value)
+				if zero && f.PrimaryKey {
+					match = nil
+					break
+				}
+				match = append(match, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: f.DBName}, Value: v})
+			}
+			if len(match) > 0 {
+				conditions = append(conditions, clause.And(match...))
+			}
+		}
+		if len(conditions) == 0 {
+			continue
+		}
+		var total, owned int64
+		lock := &clause.Locking{Strength: clause.LockingStrengthUpdate}
+		db := gormLocking(r.db.WithContext(ctx).Unscoped().Model(new(T)), lock).Where(clause.Or(conditions...))
+		if err := db.Count(&total).Error; err != nil {
+			return err
+		}
+		if total == 0 {
+			continue
+		}
+		if err := r.session(ctx).Unscoped().Model(new(T)).Where(clause.Or(conditions...)).Count(&owned).Error; err != nil {
+			return err
+		}
+		if owned != total {
+			return errors.New(errors.ErrForbidden, "upsert conflicts with records outside the current scope")
+		}
+	}
+	return nil
+}
+
+// uniqueKeys 返回实体的主键与唯一索引字段组合
+func uniqueKeys(s *schema.Schema) [][]*schema.Field {
+	keys := make([][]*schema.Field, 0)
+	if len(s.PrimaryFields) > 0 {
+		keys = append(keys, s.PrimaryFields)
+	}
+	for _, f := range s.Fields {
+		if f.Unique && !f.PrimaryKey {
+			keys = append(keys, []*schema.Field{f})
+		}
+	}
+	indexes := s.ParseIndexes()
+	names := make([]string, 0, len(indexes))
+	for name, index := range indexes {
+		if index.Class == "UNIQUE" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := make([]*schema.Field, 0, len(indexes[name].Fields))
+		for _, option := range indexes[name].Fields {
+			key = append(key, option.Field)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// scopedOnConflict 构建冲突更新子句，只更新当前租户与数据权限允许的冲突记录
+func (r *gormRepository[T]) scopedOnConflict(ctx context.Context, conflictColumns []string, updateColumns []string) (clause.OnConflict, error) {
+	onConflict, err := r.onConflict(conflictColumns, updateColumns)
+	if err != nil || onConflict.DoNothing {
+		return onConflict, err
+	}
+	condition, err := r.tenantCondition(ctx)
+	if err != nil {
+		return onConflict, err
+	}
+	if condition != nil {
+		onConflict.Where.Exprs = append(onConflict.Where.Exprs, condition)
+	}
+	if scope := dataScopeOf(ctx, r.entityType); scope != nil {
+		expr, err := scope.Expression()
+		if err != nil {
+			return onConflict, err
+		}
+		onConflict.Where.Exprs = append(onConflict.Where.Exprs, expr)
+	}
+	return onConflict, nil
+}
+
+// onConflict 构建冲突更新子句，乐观锁实体冲突时版本号自增
+func (r *gormRepository[T]) onConflict(conflictColumns []string, updateColumns []string) (clause.OnConflict, error) {
+	stmt := &gorm.Statement{DB: r.db}
+	if err := stmt.Parse(NewModel[T]()); err != nil {
+		return clause.OnConflict{}, err
+	}
+	conflicts, err := storageColumns(r.entityType, DB_TYPE_GORM, conflictColumns)
+	if err != nil {
+		return clause.OnConflict{}, err
+	}
+	if len(conflicts) == 0 {
+		for _, f := range stmt.Schema.PrimaryFields {
+			conflicts = append(conflicts, f.DBName)
+		}
+	}
+	updates, err := storageColumns(r.entityType, DB_TYPE_GORM, updateColumns)
+	if err != nil {
+		return clause.OnConflict{}, err
+	}
+	if len(updates) == 0 {
+		for _, name := range stmt.Schema.DBNames {
+			f := stmt.Schema.FieldsByDBName[name]
+			if f.PrimaryKey || f.AutoCreateTime > 0 || containsColumn(conflicts, name) {
+				continue
+			}
+			updates = append(updates, name)
+		}
+	}
+
+	version := ""
+	if _, ok := any(NewModel[T]()).(IVersioned); ok {
+		version = versionColumn(r.entityType, DB_TYPE_GORM)
+	}
+	onConflict := clause.OnConflict{}
+	for _, name := range conflicts {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: name})
+	}
+	for _, name := range updates {
+		// 租户字段不随冲突更新改变，避免将已有记录移至当前租户
+		if name != version && name != r.tenant {
+			onConflict.DoUpdates = append(onConflict.DoUpdates, clause.AssignmentColumns([]string{name})...)
+		}
+	}
+	if version != "" {
+		onConflict.DoUpdates = append(onConflict.DoUpdates, clause.Assignment{
+			Column: clause.Column{Name: version},
+			Value:  gorm.Expr("? + 1", clause.Column{Table: clause.CurrentTable, Name: version}),
+		})
+	}
+	if len(onConflict.DoUpdates) == 0 {
+		onConflict.DoNothing = true
+	}
+	return onConflict, nil
 }
 
 // Page 分页查询
@@ -110,7 +467,7 @@ func (r *gormRepository[T]) Page(ctx context.Context, page int, pageSize int) ([
 	var entities []T
 	var total int64
 
-	db := r.applyPreloads(r.db.WithContext(ctx))
+	db := r.session(ctx)
 	if err := db.Model(new(T)).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
@@ -131,69 +488,350 @@ func (r *gormRepository[T]) Page(ctx context.Context, page int, pageSize int) ([
 
 // BatchDelete 批量删除
 func (r *gormRepository[T]) BatchDelete(ctx context.Context, ids []any, opts ...*options.DeleteOptions) error {
-	return r.db.WithContext(ctx).Delete(new(T), ids).Error
+	db := r.session(ctx).Model(new(T)).Where(clause.IN{Column: clause.PrimaryColumn, Values: ids})
+	return r.delete(db, options.NewDeleteOptions(opts...))
 }
 
 // BatchUpdate 批量更新
+// 多租户实体先校验已有记录均属于当前租户，避免 Save 的冲突更新覆盖其他租户的记录；携带数据权限时拒绝将记录移出范围
 func (r *gormRepository[T]) BatchUpdate(ctx context.Context, entities []T) error {
-	return r.db.WithContext(ctx).Save(entities).Error
+	if err := assignTenant(ctx, entities...); err != nil {
+		return err
+	}
+	if r.tenant != "" || dataScopeOf(ctx, r.entityType) != nil {
+		if err := r.checkScopedRecords(ctx, entities); err != nil {
+			return err
+		}
+	}
+	ids := make([]interface{}, 0, len(entities))
+	for _, entity := range entities {
+		ids = append(ids, entity.GetID())
+	}
+	return r.withinScope(ctx, ids, func(repo *gormRepository[T]) error {
+		return repo.session(ctx).Save(entities).Error
+	})
+}
+
+// checkScopedRecords 校验实体对应的已有记录均属于当前租户且在数据权限范围内
+func (r *gormRepository[T]) checkScopedRecords(ctx context.Context, entities []T) error {
+	ids := make([]interface{}, 0, len(entities))
+	for _, entity := range entities {
+		ids = append(ids, entity.GetID())
+	}
+	ids = nonZeroIDs(ids)
+	if len(ids) == 0 {
+		return nil
+	}
+	var total, owned int64
+	db := r.db.WithContext(ctx).Unscoped().Model(new(T)).Where(clause.IN{Column: clause.PrimaryColumn, Values: ids})
+	if err := db.Count(&total).Error; err != nil {
+		return err
+	}
+	if err := r.session(ctx).Unscoped().Model(new(T)).Where(clause.IN{Column: clause.PrimaryColumn, Values: ids}).Count(&owned).Error; err != nil {
+		return err
+	}
+	if owned != total {
+		return errors.New(errors.ErrForbidden, "access to the records is not allowed")
+	}
+	return nil
 }
 
 // Count 统计记录数
 func (r *gormRepository[T]) Count(ctx context.Context, entity T) (int64, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(entity).Count(&count).Error
+	err := r.session(ctx).Model(entity).Count(&count).Error
 	return count, err
 }
 
 // Exists 检查记录是否存在
 func (r *gormRepository[T]) Exists(ctx context.Context, query interface{}, args ...interface{}) (bool, error) {
 	var count int64
-	err := r.db.WithContext(ctx).Model(new(T)).Where(query, args...).Count(&count).Error
+	err := r.session(ctx).Model(new(T)).Where(query, args...).Count(&count).Error
 	return count > 0, err
 }
 
-// Delete 删除实体
-func (r *gormRepository[T]) Delete(ctx context.Context, entity T, opts ...*options.DeleteOptions) error {
+// Sum 字段求和
+func (r *gormRepository[T]) Sum(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error) {
+	return r.aggregate(ctx, options.AggregateSum, field, opts...)
+}
+
+// CountField 统计字段非空记录数
+func (r *gormRepository[T]) CountField(ctx context.Context, field string, opts ...*options.QueryOptions) (int64, error) {
+	value, err := r.aggregate(ctx, options.AggregateCount, field, opts...)
+	return int64(value), err
+}
+
+// Max 字段最大值
+func (r *gormRepository[T]) Max(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error) {
+	return r.aggregate(ctx, options.AggregateMax, field, opts...)
+}
+
+// Min 字段最小值
+func (r *gormRepository[T]) Min(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error) {
+	return r.aggregate(ctx, options.AggregateMin, field, opts...)
+}
+
+// Avg 字段平均值
+func (r *gormRepository[T]) Avg(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error) {
+	return r.aggregate(ctx, options.AggregateAvg, field, opts...)
+}
+
+// aggregate 执行单值聚合，无匹配记录时返回 0
+func (r *gormRepository[T]) aggregate(ctx context.Context, fn string, field string, opts ...*options.QueryOptions) (float64, error) {
+	aggOpts := options.NewAggregateOptions(fn, field)
 	if len(opts) > 0 {
-		if opts[0].Force {
-			return r.db.WithContext(ctx).Unscoped().Delete(&entity).Error
-		}
-		if !opts[0].DeletedAt.IsZero() {
-			r.db = r.db.Set("deleted_at", opts[0].DeletedAt)
-		}
-		if opts[0].DeletedBy != "" {
-			r.db = r.db.Set("deleted_by", opts[0].DeletedBy)
-		}
+		aggOpts.Query = opts[0]
+	}
+	f, _, err := resolveAggregateFields(r.entityType, aggOpts)
+	if err != nil {
+		return 0, err
 	}
-	return r.db.WithContext(ctx).Delete(entity).Where(entity).Error
+	column := clause.Column{Table: clause.CurrentTable, Name: f.Column}
+
+	var value sql.NullFloat64
+	db := aggOpts.Query.ApplyFilters(r.session(ctx).Model(new(T)))
+	if err := db.Select(gormAggregateExpr(fn), column).Row().Scan(&value); err != nil {
+		return 0, err
+	}
+	return value.Float64, nil
+}
+
+// Aggregate 聚合管道仅支持 MongoDB，关系型数据库请使用 GroupAggregate 或 Query
+func (r *gormRepository[T]) Aggregate(ctx context.Context, pipeline interface{}, out interface{}) error {
+	return errors.New(errors.ErrInternal, "aggregation pipeline is not supported by gorm, use GroupAggregate instead")
+}
+
+// GroupAggregate 分组聚合，每行包含分组字段（以 json 名称为键）与聚合值 value
+func (r *gormRepository[T]) GroupAggregate(ctx context.Context, opts *options.AggregateOptions) ([]map[string]interface{}, error) {
+	f, groups, err := resolveAggregateFields(r.entityType, opts)
+	if err != nil {
+		return nil, err
+	}
+	column := clause.Column{Table: clause.CurrentTable, Name: f.Column}
+	aggExpr := gormAggregateExpr(opts.Func)
+
+	selects := make([]string, 0, len(groups)+1)
+	vars := make([]interface{}, 0, len(groups)*2+2)
+	groupColumns := make([]clause.Column, 0, len(groups))
+	for _, group := range groups {
+		groupColumn := clause.Column{Table: clause.CurrentTable, Name: group.Column}
+		selects = append(selects, "? AS ?")
+		vars = append(vars, groupColumn, clause.Column{Name: group.JSON})
+		groupColumns = append(groupColumns, groupColumn)
+	}
+	selects = append(selects, aggExpr+" AS ?")
+	vars = append(vars, column, clause.Column{Name: "value"})
+
+	db := opts.Query.ApplyFilters(r.session(ctx).Model(new(T)))
+	db = db.Select(strings.Join(selects, ", "), vars...)
+	if len(groupColumns) > 0 {
+		db = db.Clauses(clause.GroupBy{Columns: groupColumns})
+	}
+	if opts.Having != nil {
+		db = db.Having(aggExpr+" "+options.HavingOperators[opts.Having.Op]+" ?", column, opts.Having.Value)
+	}
+	for _, groupColumn := range groupColumns {
+		db = db.Order(clause.OrderByColumn{Column: groupColumn})
+	}
+
+	rows := make([]map[string]interface{}, 0)
+	if err := db.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		normalizeAggregateRow(row)
+	}
+	return rows, nil
+}
+
+// gormAggregateExpr 生成聚合表达式，字段以占位符传入
+func gormAggregateExpr(fn string) string {
+	return strings.ToUpper(fn) + "(?)"
+}
+
+// Delete 删除实体，支持软删除的实体默认软删除
+func (r *gormRepository[T]) Delete(ctx context.Context, entity T, opts ...*options.DeleteOptions) error {
+	return r.delete(r.session(ctx).Model(entity), options.NewDeleteOptions(opts...))
 }
 
 // DeleteById 根据ID删除
 func (r *gormRepository[T]) DeleteById(ctx context.Context, id any, opts ...*options.DeleteOptions) error {
-	// var entity T
-	entity := NewModel[T]()
-	// entity.Init()
-	if err := entity.SetID(id); err != nil {
-		return err
+	db := r.session(ctx).Model(new(T)).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id})
+	return r.delete(db, options.NewDeleteOptions(opts...))
+}
+
+// delete 删除 db 条件匹配的记录
+// 软删除直接更新删除时间与删除人，已删除的记录不会被重复删除
+func (r *gormRepository[T]) delete(db *gorm.DB, opts *options.DeleteOptions) error {
+	if opts.Force || !r.softDelete.Enabled {
+		return db.Unscoped().Delete(new(T)).Error
+	}
+	deletedAt := opts.DeletedAt
+	if deletedAt.IsZero() {
+		deletedAt = db.NowFunc()
+	}
+	columns := map[string]interface{}{r.softDelete.DeletedAt: deletedAt}
+	if r.softDelete.DeletedBy != "" {
+		columns[r.softDelete.DeletedBy] = opts.DeletedBy
 	}
-	return r.Delete(ctx, entity, opts...)
+	return db.UpdateColumns(columns).Error
+}
+
+// Restore 恢复软删除的记录
+func (r *gormRepository[T]) Restore(ctx context.Context, id any) error {
+	if !r.softDelete.Enabled {
+		return errors.New(errors.ErrInvalidParam, "entity does not support soft delete")
+	}
+	columns := map[string]interface{}{r.softDelete.DeletedAt: nil}
+	if r.softDelete.DeletedBy != "" {
+		columns[r.softDelete.DeletedBy] = ""
+	}
+	result := r.session(ctx).Unscoped().Model(new(T)).
+		Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).
+		Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: r.softDelete.DeletedAt}, Value: nil}).
+		UpdateColumns(columns)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "no deleted record of this id was found")
+	}
+	return nil
+}
+
+// ForceDelete 物理删除记录（包括已软删除的记录）
+func (r *gormRepository[T]) ForceDelete(ctx context.Context, id any) error {
+	result := r.session(ctx).Unscoped().Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Delete(new(T))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.ErrNotFound, "no record of this id was found")
+	}
+	return nil
+}
+
+// FindTrashed 查询已软删除的记录，返回当前页记录与总数
+func (r *gormRepository[T]) FindTrashed(ctx context.Context, opts *options.QueryOptions) ([]T, int64, error) {
+	if !r.softDelete.Enabled {
+		return nil, 0, errors.New(errors.ErrInvalidParam, "entity does not support soft delete")
+	}
+	db := r.session(ctx).Unscoped().Model(new(T)).
+		Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: r.softDelete.DeletedAt}, Value: nil}).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := opts.ApplyFilters(db).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	entities := make([]T, 0)
+	if err := opts.ApplyQueryOptions(db).Find(&entities).Error; err != nil {
+		return nil, 0, err
+	}
+	return entities, total, nil
 }
 
 // Update 更新实体
 func (r *gormRepository[T]) Update(ctx context.Context, entity T, updateFields map[string]interface{}) error {
+	if err := assignTenant(ctx, entity); err != nil {
+		return err
+	}
+	if err := checkTenantFields(ctx, r.entityType, DB_TYPE_GORM, r.tenant, updateFields); err != nil {
+		return err
+	}
+	return r.withinScope(ctx, []interface{}{entity.GetID()}, func(repo *gormRepository[T]) error {
+		return repo.update(ctx, entity, updateFields)
+	})
+}
+
+// update 执行更新，带版本号的实体校验版本
+func (r *gormRepository[T]) update(ctx context.Context, entity T, updateFields map[string]interface{}) error {
+	if versioned, ok := any(entity).(IVersioned); ok {
+		return r.updateVersioned(ctx, entity, versioned, updateFields)
+	}
 	// 如果没有提供更新字段，则使用整个实体进行更新
 	if updateFields == nil {
-		return r.db.WithContext(ctx).Updates(entity).Error
+		return r.session(ctx).Updates(entity).Error
 	}
 	// 只更新指定字段
-	return r.db.WithContext(ctx).Model(entity).Updates(updateFields).Error
+	return r.session(ctx).Model(entity).Updates(updateFields).Error
 }
 
-// Preload 添加预加载
-func (r *gormRepository[T]) Preload(query ...string) IRepository[T] {
-	r.preloads = append(r.preloads, query...)
-	return r
+// withinScope 携带数据权限时在事务中执行写入，并校验写入前在范围内的记录写入后仍在范围内，否则回滚
+// 避免通过修改权限字段（如 owner_id）将记录移出可编辑范围
+func (r *gormRepository[T]) withinScope(ctx context.Context, ids []interface{}, write func(repo *gormRepository[T]) error) error {
+	if dataScopeOf(ctx, r.entityType) == nil {
+		return write(r)
+	}
+	ids = nonZeroIDs(ids)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repo := &gormRepository[T]{db: tx, entityType: r.entityType, softDelete: r.softDelete, tenant: r.tenant}
+		before, err := repo.countScoped(ctx, ids)
+		if err != nil {
+			return err
+		}
+		if err := write(repo); err != nil {
+			return err
+		}
+		after, err := repo.countScoped(ctx, ids)
+		if err != nil {
+			return err
+		}
+		if after < before {
+			return errors.New(errors.ErrForbidden, "the updated records are outside the data permission scope")
+		}
+		return nil
+	})
+}
+
+// countScoped 统计指定 ID 中属于当前租户且在数据权限范围内的记录数
+func (r *gormRepository[T]) countScoped(ctx context.Context, ids []interface{}) (int64, error) {
+	var count int64
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	err := r.session(ctx).Unscoped().Model(new(T)).Where(clause.IN{Column: clause.PrimaryColumn, Values: ids}).Count(&count).Error
+	return count, err
+}
+
+// nonZeroIDs 过滤空 ID
+func nonZeroIDs(ids []interface{}) []interface{} {
+	result := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		if id != nil && !reflect.ValueOf(id).IsZero() {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// updateVersioned 带版本校验的更新，仅当数据库中的版本与实体版本一致时更新并自增版本号
+func (r *gormRepository[T]) updateVersioned(ctx context.Context, entity T, versioned IVersioned, updateFields map[string]interface{}) error {
+	version := versioned.GetVersion()
+	column := clause.Column{Table: clause.CurrentTable, Name: versionColumn(r.entityType, DB_TYPE_GORM)}
+	db := r.session(ctx).Model(entity).Where(clause.Eq{Column: column, Value: version})
+
+	var result *gorm.DB
+	if updateFields == nil {
+		versioned.SetVersion(version + 1)
+		result = db.Updates(entity)
+	} else {
+		fields := make(map[string]interface{}, len(updateFields)+1)
+		for k, v := range updateFields {
+			fields[k] = v
+		}
+		fields[column.Name] = version + 1
+		result = db.Updates(fields)
+	}
+	if result.Error != nil || result.RowsAffected == 0 {
+		versioned.SetVersion(version)
+		if result.Error != nil {
+			return result.Error
+		}
+		return errors.New(errors.ErrVersionConflict, "the record has been modified by others")
+	}
+	versioned.SetVersion(version + 1)
+	return nil
 }
 
 // WithTx 使用事务
@@ -201,10 +839,17 @@ func (r *gormRepository[T]) WithTx(tx *gorm.DB) IRepository[T] {
 	return &gormRepository[T]{
 		db:         tx,
 		entityType: r.entityType,
-		preloads:   r.preloads,
+		lock:       r.lock,
+		softDelete: r.softDelete,
+		tenant:     r.tenant,
 	}
 }
 
+// outboxDB 写入发件箱的连接，事务中为当前事务
+func (r *gormRepository[T]) outboxDB() *gorm.DB {
+	return r.db
+}
+
 // Transaction 事务操作
 func (r *gormRepository[T]) Transaction(ctx context.Context, fc func(tx IRepository[T]) error) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
@@ -2,34 +2,125 @@ package crud
 
 import (
 	"context"
-	"errors"
+	stderrors "errors"
+	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
 	"github.com/qiniu/qmgo"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // mongoRepository mongodb仓储实现
 type mongoRepository[T ICrudEntity] struct {
+	client     *qmgo.Client  // 用于开启事务
+	session    mongo.Session // 事务会话，为空表示不在事务中
 	collection *qmgo.Collection
 	entityType reflect.Type
+	forUpdate  bool           // 读取前写入锁字段，模拟 SELECT ... FOR UPDATE
+	softDelete softDeleteMeta // 软删除字段
+	tenant     string         // 租户字段，为空表示实体不区分租户
 }
 
 // newMongoRepository 创建mongodb仓储实例
-func newMongoRepository[T ICrudEntity](db *qmgo.Database, entity T) *mongoRepository[T] {
+func newMongoRepository[T ICrudEntity](client *qmgo.Client, db *qmgo.Database, entity T) *mongoRepository[T] {
 	entityType := reflect.TypeOf(entity)
 	if entityType.Kind() == reflect.Ptr {
 		entityType = entityType.Elem()
 	}
 	return &mongoRepository[T]{
+		client:     client,
 		collection: db.Collection(entity.TableName()), // 不确定是不是使用entity的TableName方法
 		entityType: entityType,
+		softDelete: softDeleteMetaOf(entityType, DB_TYPE_MONGODB),
+		tenant:     tenantColumn(entityType, DB_TYPE_MONGODB),
 	}
 }
 
+// Query 创建链式查询构建器
+func (r *mongoRepository[T]) Query() IQuery[T] {
+	query := newMongoQuery[T](r.collection, r.entityType)
+	query.session = r.session
+	query.scoped = r.scoped
+	if r.forUpdate {
+		query.lock = r.lockDocuments
+	}
+	return query
+}
+
+// mongoLockField 悲观锁写入的字段
+const mongoLockField = "_lock"
+
+// LockForUpdate 返回加排他锁的仓储副本，需在 Transaction 回调中使用
+// mongodb 没有行级锁，读取前会向匹配文档写入 _lock 字段，使并发事务对同一文档的写入立即产生写冲突；
+// 写冲突总是立即返回，因此行为等同于 NOWAIT，SkipLocked 不受支持
+func (r *mongoRepository[T]) LockForUpdate(opts ...*options.LockOptions) IRepository[T] {
+	return &mongoRepository[T]{
+		client:     r.client,
+		session:    r.session,
+		collection: r.collection,
+		entityType: r.entityType,
+		forUpdate:  true,
+		softDelete: r.softDelete,
+		tenant:     r.tenant,
+	}
+}
+
+// SharedLock mongodb 事务内的读取本身基于快照，无需共享锁，返回原仓储
+func (r *mongoRepository[T]) SharedLock(opts ...*options.LockOptions) IRepository[T] {
+	return r
+}
+
+// bind 事务中的仓储将操作绑定到事务会话，调用方上下文中的租户等信息保持不变
+func (r *mongoRepository[T]) bind(ctx context.Context) context.Context {
+	if r.session == nil {
+		return ctx
+	}
+	return mongo.NewSessionContext(ctx, r.session)
+}
+
+// scoped 为过滤条件追加当前租户、数据权限与未删除条件
+func (r *mongoRepository[T]) scoped(ctx context.Context, filter interface{}) (interface{}, error) {
+	filter, err := r.accessScoped(ctx, filter)
+	if err != nil || !r.softDelete.Enabled {
+		return filter, err
+	}
+	return bson.M{"$and": bson.A{filter, bson.M{r.softDelete.DeletedAt: nil}}}, nil
+}
+
+// accessScoped 为过滤条件追加当前租户与数据权限条件，上下文中缺少租户时返回错误
+func (r *mongoRepository[T]) accessScoped(ctx context.Context, filter interface{}) (interface{}, error) {
+	filter = mongoDataScope(ctx, r.entityType, filter)
+	if r.tenant == "" {
+		return filter, nil
+	}
+	tenantID, scoped, err := currentTenant(ctx)
+	if err != nil || !scoped {
+		return filter, err
+	}
+	return bson.M{"$and": bson.A{filter, bson.M{r.tenant: tenantID}}}, nil
+}
+
+// lockDocuments 向匹配文档写入锁字段
+func (r *mongoRepository[T]) lockDocuments(ctx context.Context, filter interface{}) error {
+	if !r.forUpdate {
+		return nil
+	}
+	_, err := r.collection.UpdateAll(ctx, filter, bson.M{"$set": bson.M{mongoLockField: primitive.NewObjectID()}})
+	return err
+}
+
 func (r *mongoRepository[T]) Create(ctx context.Context, entity T) error {
+	ctx = r.bind(ctx)
+	if err := assignTenant(ctx, entity); err != nil {
+		return err
+	}
+	initVersion(entity)
 	res, err := r.collection.InsertOne(ctx, entity)
 	if err == nil {
 		entity.SetID(res.InsertedID)
@@ -37,22 +128,285 @@ func (r *mongoRepository[T]) Create(ctx context.Context, entity T) error {
 	return err
 }
 
+// Update 更新实体，updateFields 为空时替换整个文档，否则只更新指定字段
+// 实体实现 IVersioned 时校验版本号，版本不一致返回 ErrVersionConflict
 func (r *mongoRepository[T]) Update(ctx context.Context, entity T, updateFields map[string]interface{}) error {
+	ctx = r.bind(ctx)
+	if err := assignTenant(ctx, entity); err != nil {
+		return err
+	}
+	if err := checkTenantFields(ctx, r.entityType, DB_TYPE_MONGODB, r.tenant, updateFields); err != nil {
+		return err
+	}
+	objId, err := Id2ObjectId(entity.GetID())
+	if err != nil {
+		return err
+	}
+	filter := bson.M{"_id": objId}
+	versioned, isVersioned := any(entity).(IVersioned)
+	var version int64
+	if isVersioned {
+		version = versioned.GetVersion()
+		filter[versionColumn(r.entityType, DB_TYPE_MONGODB)] = version
+	}
+
+	scopedFilter, err := r.scoped(ctx, filter)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	entity.SetUpdatedAt(now)
 	if len(updateFields) == 0 {
-		return r.collection.UpdateOne(ctx, entity, entity)
+		if isVersioned {
+			versioned.SetVersion(version + 1)
+		}
+		if err = r.checkScopeValues(ctx, entity, nil); err == nil {
+			err = r.collection.ReplaceOne(ctx, scopedFilter, entity)
+		}
+	} else {
+		set := bson.M{}
+		if f, ok := lookupEntityField(r.entityType, "updated_at"); ok {
+			set[f.StorageName(DB_TYPE_MONGODB)] = now
+		}
+		for k, v := range updateFields {
+			if f, ok := lookupEntityField(r.entityType, k); ok {
+				k = f.StorageName(DB_TYPE_MONGODB)
+			}
+			set[k] = v
+		}
+		if isVersioned {
+			set[versionColumn(r.entityType, DB_TYPE_MONGODB)] = version + 1
+		}
+		if err = r.checkScopeValues(ctx, scopedFilter, set); err == nil {
+			err = r.collection.UpdateOne(ctx, scopedFilter, bson.M{"$set": set})
+		}
 	}
-	err := r.collection.UpdateOne(ctx, entity, updateFields) // TODO 不确定是否如此写
-	return err
+
+	if err != nil {
+		if isVersioned {
+			versioned.SetVersion(version)
+			if qmgo.IsErrNoDocuments(err) {
+				return errors.New(errors.ErrVersionConflict, "the record has been modified by others")
+			}
+		}
+		return err
+	}
+	if isVersioned {
+		versioned.SetVersion(version + 1)
+	}
+	return nil
 }
+
+// Delete 删除实体，支持软删除的实体默认软删除
 func (r *mongoRepository[T]) Delete(ctx context.Context, entity T, opts ...*options.DeleteOptions) error {
-	err := r.collection.Remove(ctx, entity)
-	return err
+	return r.delete(ctx, bson.M{"_id": entity.GetID()}, options.NewDeleteOptions(opts...))
 }
+
+// DeleteById 根据ID删除
 func (r *mongoRepository[T]) DeleteById(ctx context.Context, id any, opts ...*options.DeleteOptions) error {
-	err := r.collection.RemoveId(ctx, id)
+	objId, err := Id2ObjectId(id)
+	if err != nil {
+		return err
+	}
+	return r.delete(ctx, bson.M{"_id": objId}, options.NewDeleteOptions(opts...))
+}
+
+// delete 删除 filter 匹配的记录，软删除时写入删除时间与删除人
+func (r *mongoRepository[T]) delete(ctx context.Context, filter bson.M, opts *options.DeleteOptions) error {
+	ctx = r.bind(ctx)
+	if opts.Force || !r.softDelete.Enabled {
+		tenantFilter, err := r.accessScoped(ctx, filter)
+		if err != nil {
+			return err
+		}
+		_, err = r.collection.RemoveAll(ctx, tenantFilter)
+		return err
+	}
+	deletedAt := opts.DeletedAt
+	if deletedAt.IsZero() {
+		deletedAt = time.Now()
+	}
+	set := bson.M{r.softDelete.DeletedAt: deletedAt}
+	if r.softDelete.DeletedBy != "" && opts.DeletedBy != "" {
+		set[r.softDelete.DeletedBy] = opts.DeletedBy
+	}
+	scopedFilter, err := r.scoped(ctx, filter)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection.UpdateAll(ctx, scopedFilter, bson.M{"$set": set})
 	return err
 }
+
+// Restore 恢复软删除的记录
+func (r *mongoRepository[T]) Restore(ctx context.Context, id any) error {
+	ctx = r.bind(ctx)
+	if !r.softDelete.Enabled {
+		return errors.New(errors.ErrInvalidParam, "entity does not support soft delete")
+	}
+	objId, err := Id2ObjectId(id)
+	if err != nil {
+		return err
+	}
+	unset := bson.M{r.softDelete.DeletedAt: ""}
+	if r.softDelete.DeletedBy != "" {
+		unset[r.softDelete.DeletedBy] = ""
+	}
+	filter, err := r.accessScoped(ctx, bson.M{"_id": objId, r.softDelete.DeletedAt: bson.M{"$ne": nil}})
+	if err != nil {
+		return err
+	}
+	err = r.collection.UpdateOne(ctx, filter, bson.M{"$unset": unset})
+	if qmgo.IsErrNoDocuments(err) {
+		return errors.New(errors.ErrNotFound, "no deleted record of this id was found")
+	}
+	return err
+}
+
+// ForceDelete 物理删除记录（包括已软删除的记录）
+func (r *mongoRepository[T]) ForceDelete(ctx context.Context, id any) error {
+	ctx = r.bind(ctx)
+	objId, err := Id2ObjectId(id)
+	if err != nil {
+		return err
+	}
+	filter, err := r.accessScoped(ctx, bson.M{"_id": objId})
+	if err != nil {
+		return err
+	}
+	err = r.collection.Remove(ctx, filter)
+	if qmgo.IsErrNoDocuments(err) {
+		return errors.New(errors.ErrNotFound, "no record of this id was found")
+	}
+	return err
+}
+
+// FindTrashed 查询已软删除的记录，返回当前页记录与总数
+func (r *mongoRepository[T]) FindTrashed(ctx context.Context, opts *options.QueryOptions) ([]T, int64, error) {
+	ctx = r.bind(ctx)
+	if !r.softDelete.Enabled {
+		return nil, 0, errors.New(errors.ErrInvalidParam, "entity does not support soft delete")
+	}
+	main, relation := mongoQueryFilters(opts)
+	filter, err := r.accessScoped(ctx, bson.M{"$and": bson.A{main, bson.M{r.softDelete.DeletedAt: bson.M{"$ne": nil}}}})
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := r.countDocuments(ctx, filter, relation, opts.Joins)
+	if err != nil {
+		return nil, 0, err
+	}
+	findOpts, err := mongoFindOptionsOf(r.entityType, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	entities := make([]T, 0)
+	if err := r.findDocuments(ctx, filter, relation, findOpts, &entities); err != nil {
+		return nil, 0, err
+	}
+	return entities, total, nil
+}
+
+// mongoFindOptions 文档查询参数
+type mongoFindOptions struct {
+	joins      []options.Join   // 关联过滤与排序
+	expands    []options.Expand // 关联展开
+	sorts      []string         // qmgo 排序参数
+	projection bson.M           // 字段投影，为空时返回全部字段
+	textScore  bool             // 按全文检索相关度排序，相关度优先于 sorts
+	skip       int64
+	limit      int64
+}
+
+// mongoFindOptionsOf 将查询选项中的排序、字段选择、分页与关联转换为文档查询参数
+// $text 条件需位于聚合管道的第一个阶段，存在关联连接时不按相关度排序
+func mongoFindOptionsOf(entityType reflect.Type, opts *options.QueryOptions) (mongoFindOptions, error) {
+	sorts, err := mongoOrderSorts(entityType, opts.OrderBy)
+	if err != nil {
+		return mongoFindOptions{}, err
+	}
+	findOpts := mongoFindOptions{
+		joins:      opts.Joins,
+		expands:    opts.Expand,
+		sorts:      sorts,
+		projection: mongoProjection(entityType, opts.Select),
+		textScore:  opts.SearchRank && opts.SearchMode == options.SearchModeFullText && mongoSearchFilter(opts) != nil,
+	}
+	if opts.Page > 0 && opts.PageSize > 0 {
+		findOpts.skip, findOpts.limit = int64((opts.Page-1)*opts.PageSize), int64(opts.PageSize)
+	}
+	return findOpts, nil
+}
+
+// findDocuments 查询文档，存在关联连接、展开或按相关度排序时使用聚合管道
+// filter 为主集合条件，在 $lookup 之前匹配；relation 为引用关联文档的条件，在 $lookup 之后匹配
+func (r *mongoRepository[T]) findDocuments(ctx context.Context, filter interface{}, relation bson.M, opts mongoFindOptions, result interface{}) error {
+	if len(opts.joins) == 0 && len(opts.expands) == 0 && !opts.textScore {
+		query := r.collection.Find(ctx, filter)
+		if len(opts.sorts) > 0 {
+			query = query.Sort(opts.sorts...)
+		}
+		if opts.projection != nil {
+			query = query.Select(opts.projection)
+		}
+		if opts.skip > 0 {
+			query = query.Skip(opts.skip)
+		}
+		if opts.limit > 0 {
+			query = query.Limit(opts.limit)
+		}
+		return query.All(result)
+	}
+	pipeline := mongoMatchStages(filter, relation, opts.joins)
+	sort := mongoSortDocument(opts.sorts)
+	if opts.textScore {
+		sort = append(bson.D{{Key: "_score", Value: bson.M{"$meta": "textScore"}}}, sort...)
+	}
+	if len(sort) > 0 {
+		pipeline = append(pipeline, bson.M{"$sort": sort})
+	}
+	if opts.skip > 0 {
+		pipeline = append(pipeline, bson.M{"$skip": opts.skip})
+	}
+	if opts.limit > 0 {
+		pipeline = append(pipeline, bson.M{"$limit": opts.limit})
+	}
+	if len(opts.joins) > 0 {
+		pipeline = append(pipeline, mongoUnsetLookups(opts.joins))
+	}
+	if opts.projection != nil {
+		pipeline = append(pipeline, bson.M{"$project": opts.projection})
+	}
+	pipeline = append(pipeline, mongoExpandStages(opts.expands, "")...)
+	return r.collection.Aggregate(ctx, pipeline).All(result)
+}
+
+// countDocuments 统计文档数，存在关联连接时使用 $lookup 聚合管道
+func (r *mongoRepository[T]) countDocuments(ctx context.Context, filter interface{}, relation bson.M, joins []options.Join) (int64, error) {
+	if len(joins) == 0 {
+		return r.collection.Find(ctx, filter).Count()
+	}
+	pipeline := append(mongoMatchStages(filter, relation, joins), bson.M{"$count": "total"})
+	var results []struct {
+		Total int64 `bson:"total"`
+	}
+	if err := r.collection.Aggregate(ctx, pipeline).All(&results); err != nil || len(results) == 0 {
+		return 0, err
+	}
+	return results[0].Total, nil
+}
+
+// mongoMatchStages 生成过滤与关联阶段：主集合条件（含 $text）位于首个 $match，关联条件在 $lookup 之后匹配
+func mongoMatchStages(filter interface{}, relation bson.M, joins []options.Join) bson.A {
+	stages := append(bson.A{bson.M{"$match": filter}}, mongoLookupStages(joins)...)
+	if len(relation) > 0 {
+		stages = append(stages, bson.M{"$match": relation})
+	}
+	return stages
+}
+
 func (r *mongoRepository[T]) FindById(ctx context.Context, id any) (T, error) {
+	ctx = r.bind(ctx)
 	entity := NewModel[T]()
 	objId, err := Id2ObjectId(id)
 	if err != nil {
@@ -61,61 +415,524 @@ func (r *mongoRepository[T]) FindById(ctx context.Context, id any) (T, error) {
 	if err := entity.SetID(objId); err != nil {
 		return entity, err
 	}
-	err = r.collection.Find(ctx, bson.D{{Key: "_id", Value: objId}}).One(entity)
+	filter, err := r.scoped(ctx, bson.D{{Key: "_id", Value: objId}})
+	if err != nil {
+		return entity, err
+	}
+	if err := r.lockDocuments(ctx, filter); err != nil {
+		return entity, err
+	}
+	err = r.collection.Find(ctx, filter).One(entity)
 	return entity, err
 }
+
+// Find 按查询选项查询，支持过滤、搜索、排序、字段选择、分页与关联展开
 func (r *mongoRepository[T]) Find(ctx context.Context, entity T, opts *options.QueryOptions) ([]T, error) {
-	var entities []T
-	err := r.collection.Find(ctx, entity).All(entities)
-	return entities, err
+	ctx = r.bind(ctx)
+	if opts == nil {
+		opts = options.NewQueryOptions()
+	}
+	main, relation := mongoQueryFilters(opts)
+	filter, err := r.scoped(ctx, main)
+	if err != nil {
+		return nil, err
+	}
+	findOpts, err := mongoFindOptionsOf(r.entityType, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.lockDocuments(ctx, filter); err != nil {
+		return nil, err
+	}
+	entities := make([]T, 0)
+	if err := r.findDocuments(ctx, filter, relation, findOpts, &entities); err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// FindByCursor 游标分页查询
+func (r *mongoRepository[T]) FindByCursor(ctx context.Context, entity T, opts *options.QueryOptions) (*CursorPage[T], error) {
+	ctx = r.bind(ctx)
+	keys, err := cursorKeys(r.entityType, DB_TYPE_MONGODB, opts.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+	var cursor *options.Cursor
+	if opts.Cursor != "" {
+		if cursor, keys, err = decodeCursor(opts.Cursor, keys); err != nil {
+			return nil, err
+		}
+	}
+	backward := cursor != nil && cursor.Backward
+
+	main, relation := mongoQueryFilters(opts)
+	filter, err := r.scoped(ctx, main)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		filter = bson.M{"$and": bson.A{filter, mongoKeysetFilter(keys, backward)}}
+	}
+
+	entities := make([]T, 0)
+	findOpts := mongoFindOptions{joins: opts.Joins, expands: opts.Expand, sorts: mongoSortFields(keys, backward), limit: int64(opts.Limit + 1)}
+	if findOpts.projection = mongoProjection(r.entityType, opts.Select); findOpts.projection != nil {
+		// 游标由排序键生成，投影需包含排序键
+		for _, key := range keys {
+			findOpts.projection[key.Name] = 1
+		}
+	}
+	if err := r.findDocuments(ctx, filter, relation, findOpts, &entities); err != nil {
+		return nil, err
+	}
+	return buildCursorPage(entities, keys, opts.Limit, cursor)
 }
 
+// Count 统计当前租户与数据权限范围内未删除的记录数
 func (r *mongoRepository[T]) Count(ctx context.Context, entity T) (int64, error) {
-	return r.collection.Find(ctx, entity).Count()
+	ctx = r.bind(ctx)
+	filter, err := r.scoped(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	return r.collection.Find(ctx, filter).Count()
+}
+
+// Sum 字段求和
+func (r *mongoRepository[T]) Sum(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error) {
+	return r.aggregate(ctx, options.AggregateSum, field, opts...)
+}
+
+// CountField 统计字段非空记录数
+func (r *mongoRepository[T]) CountField(ctx context.Context, field string, opts ...*options.QueryOptions) (int64, error) {
+	value, err := r.aggregate(ctx, options.AggregateCount, field, opts...)
+	return int64(value), err
+}
+
+// Max 字段最大值
+func (r *mongoRepository[T]) Max(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error) {
+	return r.aggregate(ctx, options.AggregateMax, field, opts...)
+}
+
+// Min 字段最小值
+func (r *mongoRepository[T]) Min(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error) {
+	return r.aggregate(ctx, options.AggregateMin, field, opts...)
+}
+
+// Avg 字段平均值
+func (r *mongoRepository[T]) Avg(ctx context.Context, field string, opts ...*options.QueryOptions) (float64, error) {
+	return r.aggregate(ctx, options.AggregateAvg, field, opts...)
+}
+
+// aggregate 执行单值聚合，无匹配记录时返回 0
+func (r *mongoRepository[T]) aggregate(ctx context.Context, fn string, field string, opts ...*options.QueryOptions) (float64, error) {
+	aggOpts := options.NewAggregateOptions(fn, field)
+	if len(opts) > 0 {
+		aggOpts.Query = opts[0]
+	}
+	rows, err := r.GroupAggregate(ctx, aggOpts)
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+	value, _ := rows[0]["value"].(float64)
+	return value, nil
+}
+
+// GroupAggregate 分组聚合，每行包含分组字段（以 json 名称为键）与聚合值 value
+func (r *mongoRepository[T]) GroupAggregate(ctx context.Context, opts *options.AggregateOptions) ([]map[string]interface{}, error) {
+	ctx = r.bind(ctx)
+	f, groups, err := resolveAggregateFields(r.entityType, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var groupID interface{}
+	if len(groups) > 0 {
+		id := bson.M{}
+		for _, group := range groups {
+			id[group.JSON] = "$" + group.BSON
+		}
+		groupID = id
+	}
+	main, relation := mongoQueryFilters(opts.Query)
+	filter, err := r.scoped(ctx, main)
+	if err != nil {
+		return nil, err
+	}
+	var joins []options.Join
+	if opts.Query != nil {
+		joins = opts.Query.Joins
+	}
+	pipeline := append(mongoMatchStages(filter, relation, joins),
+		bson.M{"$group": bson.M{"_id": groupID, "value": mongoAccumulator(opts.Func, f.BSON)}},
+	)
+	if opts.Having != nil {
+		op := opts.Having.Op
+		if op == "neq" {
+			op = "ne"
+		}
+		pipeline = append(pipeline, bson.M{"$match": bson.M{"value": bson.M{"$" + op: opts.Having.Value}}})
+	}
+	if len(groups) > 0 {
+		sort := bson.D{}
+		for _, group := range groups {
+			sort = append(sort, bson.E{Key: "_id." + group.JSON, Value: 1})
+		}
+		pipeline = append(pipeline, bson.M{"$sort": sort})
+	}
+
+	var results []bson.M
+	if err := r.collection.Aggregate(ctx, pipeline).All(&results); err != nil {
+		return nil, err
+	}
+	rows := make([]map[string]interface{}, 0, len(results))
+	for _, result := range results {
+		row := map[string]interface{}{"value": result["value"]}
+		if id, ok := result["_id"].(bson.M); ok {
+			for key, value := range id {
+				row[key] = value
+			}
+		}
+		rows = append(rows, normalizeAggregateRow(row))
+	}
+	return rows, nil
+}
+
+// Aggregate 执行聚合管道，租户、数据权限与未删除条件合并到首个 $match 或 $geoNear 阶段，否则追加在管道前
+func (r *mongoRepository[T]) Aggregate(ctx context.Context, pipeline interface{}, out interface{}) error {
+	ctx = r.bind(ctx)
+	stages, err := pipelineStages(pipeline)
+	if err != nil {
+		return err
+	}
+	filter, err := r.scoped(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	if stages, err = scopedPipeline(stages, filter); err != nil {
+		return err
+	}
+	return r.collection.Aggregate(ctx, stages).All(out)
+}
+
+// mongoAccumulator 生成 $group 聚合累加器
+func mongoAccumulator(fn string, field string) bson.M {
+	if fn == options.AggregateCount {
+		// 与 SQL COUNT(field) 一致，仅统计非空值
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$" + field, nil}}, 1, 0}}}
+	}
+	return bson.M{"$" + fn: "$" + field}
 }
 
 func (r *mongoRepository[T]) BatchCreate(ctx context.Context, entities []T, opts ...*options.BatchOptions) error {
+	ctx = r.bind(ctx)
+	if err := assignTenant(ctx, entities...); err != nil {
+		return err
+	}
+	for _, entity := range entities {
+		initVersion(entity)
+	}
 	_, err := r.collection.InsertMany(ctx, entities)
 	return err
 }
 
+// Upsert 插入实体，conflictColumns 匹配到文档时更新 updateColumns
+// conflictColumns 为空时使用 _id；updateColumns 为空时更新除 _id、冲突字段与创建时间外的所有字段
+func (r *mongoRepository[T]) Upsert(ctx context.Context, entity T, conflictColumns []string, updateColumns []string) error {
+	return r.BatchUpsert(ctx, []T{entity}, conflictColumns, updateColumns)
+}
+
+// BatchUpsert 使用 bulk write 批量插入或更新
+func (r *mongoRepository[T]) BatchUpsert(ctx context.Context, entities []T, conflictColumns []string, updateColumns []string, opts ...*options.BatchOptions) error {
+	ctx = r.bind(ctx)
+	if len(entities) == 0 {
+		return nil
+	}
+	conflicts, err := storageColumns(r.entityType, DB_TYPE_MONGODB, conflictColumns)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		conflicts = []string{"_id"}
+	}
+	updates, err := storageColumns(r.entityType, DB_TYPE_MONGODB, updateColumns)
+	if err != nil {
+		return err
+	}
+
+	if err := assignTenant(ctx, entities...); err != nil {
+		return err
+	}
+	filters := make(bson.A, 0, len(entities))
+	bulk := r.collection.Bulk()
+	for _, entity := range entities {
+		filter, update, err := r.upsertDocument(entity, conflicts, updates)
+		if err != nil {
+			return err
+		}
+		// 多租户实体的冲突条件包含租户，其他租户的记录不会被匹配
+		if r.tenant != "" && !containsColumn(conflicts, r.tenant) {
+			filter[r.tenant] = any(entity).(ITenantEntity).GetTenantID()
+		}
+		filters = append(filters, filter)
+		bulk = bulk.UpsertOne(filter, update)
+	}
+	if err := r.checkUpsertConflicts(ctx, bson.M{"$or": filters}); err != nil {
+		return err
+	}
+	res, err := bulk.Run(ctx)
+	if err != nil {
+		return err
+	}
+	for i, id := range res.UpsertedIDs {
+		if err := entities[i].SetID(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkUpsertConflicts 校验冲突条件匹配到的已有文档均在数据权限范围内，避免覆盖范围外的文档
+func (r *mongoRepository[T]) checkUpsertConflicts(ctx context.Context, filter bson.M) error {
+	if dataScopeOf(ctx, r.entityType) == nil {
+		return nil
+	}
+	total, err := r.collection.Find(ctx, filter).Count()
+	if err != nil || total == 0 {
+		return err
+	}
+	owned, err := r.collection.Find(ctx, mongoDataScope(ctx, r.entityType, filter)).Count()
+	if err != nil {
+		return err
+	}
+	if owned != total {
+		return errors.New(errors.ErrForbidden, "upsert conflicts with records outside the current scope")
+	}
+	return nil
+}
+
+// upsertDocument 构建单个实体的 upsert 过滤条件与更新文档，乐观锁实体的版本号使用 $inc 自增
+func (r *mongoRepository[T]) upsertDocument(entity T, conflicts []string, updates []string) (bson.M, bson.M, error) {
+	raw, err := bson.Marshal(entity)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc := bson.M{}
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	filter := bson.M{}
+	for _, name := range conflicts {
+		value, ok := doc[name]
+		if !ok {
+			return nil, nil, errors.New(errors.ErrInvalidParam, fmt.Sprintf("conflict field %s is empty", name))
+		}
+		filter[name] = value
+	}
+
+	version := ""
+	if _, ok := any(entity).(IVersioned); ok {
+		version = versionColumn(r.entityType, DB_TYPE_MONGODB)
+	}
+	set, setOnInsert := bson.M{}, bson.M{}
+	for name, value := range doc {
+		switch {
+		case name == version || containsColumn(conflicts, name):
+		case len(updates) > 0 && containsColumn(updates, name), len(updates) == 0 && name != "_id" && name != "created_at":
+			set[name] = value
+		default:
+			setOnInsert[name] = value
+		}
+	}
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(setOnInsert) > 0 {
+		update["$setOnInsert"] = setOnInsert
+	}
+	if version != "" {
+		update["$inc"] = bson.M{version: 1}
+	}
+	if len(update) == 0 {
+		update["$setOnInsert"] = filter
+	}
+	return filter, update, nil
+}
+
+// BatchUpdate 按 ID 批量替换文档，文档不存在时插入，与 gorm Save 一致
 func (r *mongoRepository[T]) BatchUpdate(ctx context.Context, entities []T) error {
-	_, err := r.collection.UpdateAll(ctx, entities, entities)
+	ctx = r.bind(ctx)
+	if len(entities) == 0 {
+		return nil
+	}
+	if err := assignTenant(ctx, entities...); err != nil {
+		return err
+	}
+	ids := make([]primitive.ObjectID, 0, len(entities))
+	for _, entity := range entities {
+		objId, err := Id2ObjectId(entity.GetID())
+		if err != nil {
+			return err
+		}
+		if objId.IsZero() {
+			return errors.New(errors.ErrInvalidParam, "id is required for batch update")
+		}
+		ids = append(ids, objId)
+	}
+	if err := r.checkScopedRecords(ctx, ids); err != nil {
+		return err
+	}
+	for _, entity := range entities {
+		if err := r.checkScopeValues(ctx, entity, nil); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	bulk := r.collection.Bulk()
+	for i, entity := range entities {
+		entity.SetUpdatedAt(now)
+		bulk = bulk.Upsert(bson.M{"_id": ids[i]}, entity)
+	}
+	_, err := bulk.Run(ctx)
 	return err
 }
+
+// checkScopeValues 校验写入后的文档仍在数据权限范围内，避免通过修改权限字段将文档移出可编辑范围
+// set 为空时 doc 为完整的新文档；否则 doc 为已有文档的过滤条件，合并 set 后校验，文档不存在时不校验
+func (r *mongoRepository[T]) checkScopeValues(ctx context.Context, doc interface{}, set bson.M) error {
+	expr := dataScopeOf(ctx, r.entityType)
+	if expr == nil {
+		return nil
+	}
+	values := bson.M{}
+	if set == nil {
+		data, err := bson.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		if err := bson.Unmarshal(data, &values); err != nil {
+			return err
+		}
+	} else {
+		if err := r.collection.Find(ctx, doc).One(&values); err != nil {
+			if qmgo.IsErrNoDocuments(err) {
+				return nil
+			}
+			return err
+		}
+		for k, v := range set {
+			values[k] = v
+		}
+	}
+	if !dataScopeMatches(*expr, values) {
+		return errors.New(errors.ErrForbidden, "the updated records are outside the data permission scope")
+	}
+	return nil
+}
+
+// checkScopedRecords 校验 ID 对应的已有文档均属于当前租户且在数据权限范围内
+func (r *mongoRepository[T]) checkScopedRecords(ctx context.Context, ids []primitive.ObjectID) error {
+	if r.tenant == "" && dataScopeOf(ctx, r.entityType) == nil {
+		return nil
+	}
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	total, err := r.collection.Find(ctx, filter).Count()
+	if err != nil {
+		return err
+	}
+	scopedFilter, err := r.accessScoped(ctx, filter)
+	if err != nil {
+		return err
+	}
+	owned, err := r.collection.Find(ctx, scopedFilter).Count()
+	if err != nil {
+		return err
+	}
+	if owned != total {
+		return errors.New(errors.ErrForbidden, "access to the records is not allowed")
+	}
+	return nil
+}
 func (r *mongoRepository[T]) BatchDelete(ctx context.Context, ids []any, opts ...*options.DeleteOptions) error {
-	err := r.collection.Remove(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}})
-	return err
+	objIds := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		objId, err := Id2ObjectId(id)
+		if err != nil {
+			return err
+		}
+		objIds = append(objIds, objId)
+	}
+	return r.delete(ctx, bson.M{"_id": bson.M{"$in": objIds}}, options.NewDeleteOptions(opts...))
 }
+
+// FindOne 查询第一条符合条件的记录，条件同 Query().Where，无记录时返回 qmgo.ErrNoSuchDocuments
 func (r *mongoRepository[T]) FindOne(ctx context.Context, query interface{}, args ...interface{}) (T, error) {
-	entity := NewModel[T]()
-	err := r.collection.Find(ctx, query).One(entity)
-	return entity, err
+	return r.Query().Where(query, args...).First(ctx)
 }
+
+// FindAll 查询所有符合条件的记录
 func (r *mongoRepository[T]) FindAll(ctx context.Context, query interface{}, args ...interface{}) ([]T, error) {
-	var entities []T
-	err := r.collection.Find(ctx, query).All(entities)
-	if err != nil {
-		return nil, err
-	}
-	return entities, err
+	return r.Query().Where(query, args...).Find(ctx)
 }
+
+// Exists 检查是否存在符合条件的记录
 func (r *mongoRepository[T]) Exists(ctx context.Context, query interface{}, args ...interface{}) (bool, error) {
-	entity := NewModel[T]()
-	err := r.collection.Find(ctx, query).One(entity)
-	return entity.GetID() != nil, err
+	return r.Query().Where(query, args...).Exists(ctx)
 }
+
+// Transaction 使用 qmgo 会话执行多文档事务，回调中的仓储绑定事务会话，任意上下文的操作均加入事务；
+// 已在事务中时加入当前事务。发生瞬时错误时驱动会重试整个回调，回调需可重复执行。
+// 单节点部署或 4.0 以下版本不支持事务，返回 ErrTransactionNotSupported
 func (r *mongoRepository[T]) Transaction(ctx context.Context, fc func(tx IRepository[T]) error) error {
-	// _, err := r.collection.Aggregate(ctx,fc(),)// TODO 待完善
-	return nil
+	if r.session != nil {
+		return fc(r)
+	}
+	if r.client == nil {
+		return errors.New(errors.ErrTransactionNotSupported, "mongodb client is not configured, transactions are unavailable")
+	}
+	_, err := r.client.DoTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+		return nil, fc(r.withSession(mongo.SessionFromContext(sessCtx)))
+	})
+	if mongoTransactionUnsupported(err) {
+		return errors.Wrap(err, errors.ErrTransactionNotSupported, "mongodb deployment does not support transactions, a replica set or sharded cluster (4.0+) is required")
+	}
+	return err
+}
+
+// withSession 返回绑定事务会话的仓储副本
+func (r *mongoRepository[T]) withSession(session mongo.Session) *mongoRepository[T] {
+	tx := *r
+	tx.session = session
+	return &tx
+}
+
+// mongoTransactionUnsupported 是否为部署不支持事务的错误：版本低于 4.0，或单节点返回 IllegalOperation(20)
+func mongoTransactionUnsupported(err error) bool {
+	if err == nil {
+		return false
+	}
+	if stderrors.Is(err, qmgo.ErrTransactionNotSupported) {
+		return true
+	}
+	var cmdErr mongo.CommandError
+	if stderrors.As(err, &cmdErr) && cmdErr.Code == 20 {
+		return strings.Contains(cmdErr.Message, "Transaction numbers")
+	}
+	return false
 }
 
 func Id2ObjectId(id any) (primitive.ObjectID, error) {
 	if id == nil {
 		return primitive.NilObjectID, nil
 	}
+	if objId, ok := id.(primitive.ObjectID); ok {
+		return objId, nil
+	}
 	if idStr, ok := id.(string); ok {
 		return primitive.ObjectIDFromHex(idStr)
 	}
-	return primitive.NilObjectID, errors.New("id must be string")
+	return primitive.NilObjectID, errors.New(errors.ErrIDType, "id must be string")
 }
@@ -0,0 +1,128 @@
+package crud
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/core/crud/types"
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/qiniu/qmgo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongooptions "go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/gorm"
+)
+
+// searchTag 全文检索字段标签，如 `search:"true"`，仅对字符串字段生效
+const searchTag = "search"
+
+// searchColumns 返回声明了 search 标签的字符串字段存储名
+func searchColumns(entityType reflect.Type, dbType string) []string {
+	columns := make([]string, 0)
+	for _, f := range entityFields(entityType) {
+		if f.Tag.Get(searchTag) != "true" || f.Type.Kind() != reflect.String {
+			continue
+		}
+		columns = append(columns, f.StorageName(dbType))
+	}
+	return columns
+}
+
+// searchOptions 根据 search、search_mode、search_fields 参数设置搜索选项
+// 实体声明了 search 字段时默认使用全文检索，否则使用 LIKE；全文检索未指定 order_by 时按相关度排序
+func searchOptions(ctx *gin.Context, entityType reflect.Type, dbType string, opts *options.QueryOptions) error {
+	search := ctx.Query("search")
+	if search == "" {
+		return nil
+	}
+	columns := searchColumns(entityType, dbType)
+	mode := options.SearchModeLike
+	if len(columns) > 0 {
+		mode = options.SearchModeFullText
+	}
+	switch ctx.DefaultQuery("search_mode", mode) {
+	case options.SearchModeFullText:
+		if len(columns) == 0 {
+			return errors.New(errors.ErrInvalidParam, "entity has no full-text searchable fields")
+		}
+		opts.SearchMode = options.SearchModeFullText
+		opts.SearchRank = ctx.Query("order_by") == ""
+	case options.SearchModeLike:
+		fields := strings.Join(columns, ",")
+		if fields == "" {
+			fields = "id"
+		}
+		var err error
+		if columns, err = storageColumns(entityType, dbType, strings.Split(ctx.DefaultQuery("search_fields", fields), ",")); err != nil {
+			return err
+		}
+		opts.SearchMode = options.SearchModeLike
+	default:
+		return errors.New(errors.ErrInvalidParam, "invalid search_mode: "+ctx.Query("search_mode"))
+	}
+	opts.Search = search
+	opts.SearchFields = columns
+	return nil
+}
+
+// searchModeParam search_mode 查询参数文档
+func searchModeParam() types.Parameter {
+	return types.Parameter{
+		Name:        "search_mode",
+		In:          "query",
+		Description: "Search mode: fulltext uses the database full-text index on fields tagged with search (default when declared, ranked by relevance unless order_by is set), like matches search_fields with LIKE",
+		Schema:      types.Schema{Type: "string"},
+	}
+}
+
+// migrateSearchIndex 为声明了 search 字段的实体创建当前方言的全文索引，方言不支持时跳过
+func migrateSearchIndex(db *gorm.DB, entity ICrudEntity) error {
+	columns := searchColumns(reflect.TypeOf(entity), DB_TYPE_GORM)
+	if len(columns) == 0 {
+		return nil
+	}
+	strategy, ok := options.GetSearchStrategy(db.Dialector.Name())
+	if !ok {
+		return nil
+	}
+	return strategy.Migrate(db, entity.TableName(), columns)
+}
+
+// migrateMongoTextIndex 为声明了 search 字段的实体创建 text 索引
+func migrateMongoTextIndex(ctx context.Context, mdb *qmgo.Database, entity ICrudEntity) error {
+	columns := searchColumns(reflect.TypeOf(entity), DB_TYPE_MONGODB)
+	if len(columns) == 0 {
+		return nil
+	}
+	collection, err := mdb.Collection(entity.TableName()).CloneCollection()
+	if err != nil {
+		return err
+	}
+	keys := bson.D{}
+	for _, column := range columns {
+		keys = append(keys, bson.E{Key: column, Value: "text"})
+	}
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    keys,
+		Options: mongooptions.Index().SetName("idx_" + entity.TableName() + "_text"),
+	})
+	return err
+}
+
+// mongoSearchFilter 将搜索选项转换为 bson 条件，全文检索使用 $text，否则各字段正则匹配以 $or 组合
+func mongoSearchFilter(opts *options.QueryOptions) bson.M {
+	if opts.Search == "" || len(opts.SearchFields) == 0 {
+		return nil
+	}
+	if opts.SearchMode == options.SearchModeFullText {
+		return bson.M{"$text": bson.M{"$search": opts.Search}}
+	}
+	ors := make(bson.A, 0, len(opts.SearchFields))
+	for _, field := range opts.SearchFields {
+		ors = append(ors, mongoCondition(options.Condition{Field: field, Op: options.OpLike, Value: opts.Search}))
+	}
+	return bson.M{"$or": ors}
+}
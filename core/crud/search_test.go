@@ -0,0 +1,100 @@
+package crud
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// testPost 声明全文检索字段的测试实体
+type testPost struct {
+	*BaseEntity
+	Title string `json:"title" search:"true"`
+	Body  string `json:"body" search:"true"`
+	Views int    `json:"views" search:"true"` // 非字符串字段被忽略
+}
+
+func (*testPost) TableName() string {
+	return "test_posts"
+}
+
+func (p *testPost) Init() {
+	if p.BaseEntity == nil {
+		p.BaseEntity = &BaseEntity{}
+	}
+}
+
+func TestFullTextSearchSQL(t *testing.T) {
+	require.Equal(t, []string{"title", "body"}, searchColumns(reflect.TypeOf(&testPost{}), DB_TYPE_GORM))
+
+	opts := options.NewQueryOptions(
+		options.WithOrderBy("id desc"),
+		options.WithSearch("go generics"),
+		options.WithSearchFields(searchColumns(reflect.TypeOf(&testPost{}), DB_TYPE_GORM)),
+		options.WithSearchMode(options.SearchModeFullText),
+		options.WithSearchRank(true),
+	)
+	toSQL := func(db *gorm.DB) string {
+		return db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			var posts []*testPost
+			return opts.ApplyQueryOptions(tx.Model(&testPost{})).Find(&posts)
+		})
+	}
+
+	pg, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DisableAutomaticPing: true})
+	require.NoError(t, err)
+	sql := toSQL(pg)
+	require.Contains(t, sql, `to_tsvector('simple', coalesce("test_posts"."title", '') || ' ' || coalesce("test_posts"."body", '')) @@ websearch_to_tsquery('simple', 'go generics')`)
+	require.Contains(t, sql, `ORDER BY ts_rank(`)
+	require.True(t, strings.HasSuffix(sql, "DESC, id desc"), sql)
+
+	my, err := gorm.Open(mysql.New(mysql.Config{DSN: "root@/test", SkipInitializeWithVersion: true}), &gorm.Config{DisableAutomaticPing: true})
+	require.NoError(t, err)
+	require.Contains(t, toSQL(my), "MATCH(`test_posts`.`title`,`test_posts`.`body`) AGAINST ('go generics' IN NATURAL LANGUAGE MODE)")
+}
+
+func TestSQLiteFullTextSearch(t *testing.T) {
+	db, repo := setupTestRepository(t, &testPost{})
+	if err := migrateSearchIndex(db, &testPost{}); err != nil {
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			t.Skip("sqlite is built without FTS5, run with -tags sqlite_fts5")
+		}
+		require.NoError(t, err)
+	}
+	// 重复迁移不报错
+	require.NoError(t, migrateSearchIndex(db, &testPost{}))
+
+	ctx := context.Background()
+	for _, post := range []*testPost{
+		{BaseEntity: &BaseEntity{}, Title: "Go generics", Body: "type parameters in go"},
+		{BaseEntity: &BaseEntity{}, Title: "Rust traits", Body: "generics and traits"},
+		{BaseEntity: &BaseEntity{}, Title: "Cooking", Body: "pasta"},
+	} {
+		require.NoError(t, repo.Create(ctx, post))
+	}
+	require.NoError(t, repo.Update(ctx, &testPost{BaseEntity: &BaseEntity{ID: 3}}, map[string]interface{}{"body": "go pasta"}))
+
+	search := func(keyword string) []*testPost {
+		items, err := repo.Find(ctx, &testPost{}, options.NewQueryOptions(
+			options.WithSearch(keyword),
+			options.WithSearchFields(searchColumns(reflect.TypeOf(&testPost{}), DB_TYPE_GORM)),
+			options.WithSearchMode(options.SearchModeFullText),
+			options.WithSearchRank(true),
+		))
+		require.NoError(t, err)
+		return items
+	}
+
+	require.Len(t, search("generics"), 2)
+	items := search("go")
+	require.Len(t, items, 2)
+	require.Equal(t, "Go generics", items[0].Title) // 标题与正文均命中，相关度更高
+	require.Len(t, search(`"pasta`), 1)             // 引号不会导致语法错误
+}
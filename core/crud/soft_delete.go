@@ -0,0 +1,45 @@
+package crud
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// ISoftDelete 支持记录删除人的软删除实体
+type ISoftDelete interface {
+	// GetDeletedBy 获取删除人
+	GetDeletedBy() string
+	// SetDeletedBy 设置删除人
+	SetDeletedBy(deletedBy string)
+}
+
+// softDeleteMeta 实体软删除字段信息
+type softDeleteMeta struct {
+	Enabled   bool   // 是否支持软删除
+	DeletedAt string // 删除时间存储字段
+	DeletedBy string // 删除人存储字段，为空表示不记录删除人
+}
+
+var deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+
+// softDeleteMetaOf 解析实体的软删除字段
+// gorm 实体需使用 gorm.DeletedAt 类型的删除时间字段；mongodb 实体需包含 bson 名为 deleted_at 的字段
+func softDeleteMetaOf(entityType reflect.Type, dbType string) softDeleteMeta {
+	meta := softDeleteMeta{}
+	for _, f := range entityFields(entityType) {
+		switch {
+		case dbType == DB_TYPE_MONGODB && f.BSON == "deleted_at":
+			meta.Enabled, meta.DeletedAt = true, f.BSON
+		case dbType != DB_TYPE_MONGODB && f.Type == deletedAtType:
+			meta.Enabled, meta.DeletedAt = true, f.Column
+		}
+	}
+	if !meta.Enabled {
+		return meta
+	}
+	if f, ok := lookupEntityField(entityType, "deleted_by"); ok {
+		meta.DeletedBy = f.StorageName(dbType)
+	}
+	return meta
+}
@@ -0,0 +1,102 @@
+package crud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/kruily/gofastcrud/tenant"
+)
+
+// ITenantEntity 按租户隔离的实体
+type ITenantEntity interface {
+	// GetTenantID 获取租户ID
+	GetTenantID() string
+	// SetTenantID 设置租户ID
+	SetTenantID(tenantID string)
+}
+
+// TenantEntity 租户字段，实体嵌入后仓储自动按上下文中的租户过滤读写，创建时写入租户并拒绝跨租户访问
+type TenantEntity struct {
+	TenantID string `gorm:"column:tenant_id;size:64;index" json:"tenant_id" bson:"tenant_id" description:"租户ID"`
+}
+
+// GetTenantID 获取租户ID
+func (e *TenantEntity) GetTenantID() string {
+	return e.TenantID
+}
+
+// SetTenantID 设置租户ID
+func (e *TenantEntity) SetTenantID(tenantID string) {
+	e.TenantID = tenantID
+}
+
+var tenantEntityType = reflect.TypeOf((*ITenantEntity)(nil)).Elem()
+
+// tenantColumn 实体租户字段的存储名称，实体未实现 ITenantEntity 时返回空
+func tenantColumn(entityType reflect.Type, dbType string) string {
+	if entityType.Kind() != reflect.Ptr {
+		entityType = reflect.PointerTo(entityType)
+	}
+	if !entityType.Implements(tenantEntityType) {
+		return ""
+	}
+	if f, ok := lookupEntityField(entityType, "tenant_id"); ok {
+		return f.StorageName(dbType)
+	}
+	return ""
+}
+
+// currentTenant 获取上下文中的租户，scoped 为 false 表示跳过租户隔离（超级管理员）
+// 上下文中既没有租户也没有跳过标记时拒绝访问多租户实体
+func currentTenant(ctx context.Context) (tenantID string, scoped bool, err error) {
+	if tenantID, ok := tenant.FromContext(ctx); ok {
+		return tenantID, true, nil
+	}
+	if tenant.IsUnscoped(ctx) {
+		return "", false, nil
+	}
+	return "", false, errors.New(errors.ErrUnauthorized, "tenant is required")
+}
+
+// assignTenant 为实体写入当前租户，实体已属于其他租户时拒绝
+func assignTenant[T ICrudEntity](ctx context.Context, entities ...T) error {
+	for _, entity := range entities {
+		e, ok := any(entity).(ITenantEntity)
+		if !ok {
+			return nil
+		}
+		tenantID, scoped, err := currentTenant(ctx)
+		if err != nil || !scoped {
+			return err
+		}
+		switch e.GetTenantID() {
+		case "":
+			e.SetTenantID(tenantID)
+		case tenantID:
+		default:
+			return errors.New(errors.ErrForbidden, "cross-tenant access is not allowed")
+		}
+	}
+	return nil
+}
+
+// checkTenantFields 拒绝通过更新字段将记录移至其他租户
+func checkTenantFields(ctx context.Context, entityType reflect.Type, dbType string, column string, fields map[string]interface{}) error {
+	if column == "" {
+		return nil
+	}
+	tenantID, scoped, err := currentTenant(ctx)
+	if err != nil || !scoped {
+		return err
+	}
+	for name, value := range fields {
+		if f, ok := lookupEntityField(entityType, name); (ok && f.StorageName(dbType) == column) || name == column {
+			if fmt.Sprint(value) != tenantID {
+				return errors.New(errors.ErrForbidden, "cross-tenant access is not allowed")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package crud
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/kruily/gofastcrud/core/crud/options"
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/kruily/gofastcrud/tenant"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"gorm.io/gorm"
+)
+
+// testNote 多租户测试实体
+type testNote struct {
+	*BaseEntity
+	TenantEntity
+	Title string  `json:"title"`
+	Code  *string `json:"code" gorm:"uniqueIndex"`
+}
+
+func (*testNote) TableName() string {
+	return "test_notes"
+}
+
+func (n *testNote) Init() {
+	if n.BaseEntity == nil {
+		n.BaseEntity = &BaseEntity{}
+	}
+}
+
+func TestTenantScope(t *testing.T) {
+	_, repo := setupTestRepository(t, &testNote{})
+
+	acme := tenant.WithTenant(context.Background(), "acme")
+	globex := tenant.WithTenant(context.Background(), "globex")
+
+	// 缺少租户时拒绝读写
+	err := repo.Create(context.Background(), &testNote{BaseEntity: &BaseEntity{}, Title: "none"})
+	require.True(t, errors.Is(err, errors.ErrUnauthorized))
+	_, err = repo.Count(context.Background(), &testNote{})
+	require.True(t, errors.Is(err, errors.ErrUnauthorized))
+
+	require.NoError(t, repo.Create(acme, &testNote{BaseEntity: &BaseEntity{}, Title: "a1"}))
+	require.NoError(t, repo.Create(acme, &testNote{BaseEntity: &BaseEntity{}, Title: "a2"}))
+	other := &testNote{BaseEntity: &BaseEntity{}, Title: "g1"}
+	require.NoError(t, repo.Create(globex, other))
+	require.Equal(t, "globex", other.TenantID)
+	err = repo.Create(acme, &testNote{BaseEntity: &BaseEntity{}, TenantEntity: TenantEntity{TenantID: "globex"}})
+	require.True(t, errors.Is(err, errors.ErrForbidden))
+
+	items, err := repo.Find(acme, &testNote{}, options.NewQueryOptions())
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	count, err := repo.Query().Count(globex)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+	sum, err := repo.CountField(acme, "id")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), sum)
+
+	// 跨租户的 ID 不可读写
+	_, err = repo.FindById(acme, other.ID)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	require.NoError(t, repo.DeleteById(acme, other.ID))
+	require.True(t, errors.Is(repo.ForceDelete(acme, other.ID), errors.ErrNotFound))
+	err = repo.Update(acme, &testNote{BaseEntity: &BaseEntity{ID: 1}}, map[string]interface{}{"tenant_id": "globex"})
+	require.True(t, errors.Is(err, errors.ErrForbidden))
+	err = repo.BatchUpdate(acme, []*testNote{{BaseEntity: &BaseEntity{ID: other.ID}, Title: "stolen"}})
+	require.True(t, errors.Is(err, errors.ErrForbidden))
+	err = repo.Upsert(acme, &testNote{BaseEntity: &BaseEntity{ID: other.ID}, Title: "stolen"}, nil, nil)
+	require.True(t, errors.Is(err, errors.ErrForbidden))
+
+	// 超级管理员跳过租户隔离
+	all, err := repo.FindAll(tenant.WithoutScope(context.Background()), "1 = 1")
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	require.Equal(t, "g1", all[2].Title)
+	require.Equal(t, "globex", all[2].TenantID)
+
+	// 唯一索引冲突的其他租户记录不会被覆盖或移至当前租户（MySQL 忽略冲突更新条件）
+	onConflict, err := repo.onConflict(nil, nil)
+	require.NoError(t, err)
+	for _, assignment := range onConflict.DoUpdates {
+		require.NotEqual(t, "tenant_id", assignment.Column.Name)
+	}
+	code := "shared"
+	require.NoError(t, repo.Create(globex, &testNote{BaseEntity: &BaseEntity{}, Title: "g2", Code: &code}))
+	err = repo.Upsert(acme, &testNote{BaseEntity: &BaseEntity{}, Title: "stolen", Code: &code}, []string{"code"}, nil)
+	require.True(t, errors.Is(err, errors.ErrForbidden))
+	err = repo.BatchUpsert(acme, []*testNote{{BaseEntity: &BaseEntity{}, Title: "stolen", Code: &code}}, []string{"code"}, nil)
+	require.True(t, errors.Is(err, errors.ErrForbidden))
+	owned, err := repo.FindOne(globex, "code = ?", code)
+	require.NoError(t, err)
+	require.Equal(t, "g2", owned.Title)
+	require.Equal(t, "globex", owned.TenantID)
+
+	// 当前租户的冲突记录正常更新
+	require.NoError(t, repo.Upsert(globex, &testNote{BaseEntity: &BaseEntity{}, Title: "g2-updated", Code: &code}, []string{"code"}, nil))
+	owned, err = repo.FindOne(globex, "code = ?", code)
+	require.NoError(t, err)
+	require.Equal(t, "g2-updated", owned.Title)
+}
+
+func TestMongoTenantScope(t *testing.T) {
+	repo := &mongoRepository[*testNote]{tenant: tenantColumn(reflect.TypeOf(testNote{}), DB_TYPE_MONGODB)}
+	filter, err := repo.scoped(tenant.WithTenant(context.Background(), "acme"), bson.M{"title": "a"})
+	require.NoError(t, err)
+	require.Equal(t, bson.M{"$and": bson.A{bson.M{"title": "a"}, bson.M{"tenant_id": "acme"}}}, filter)
+	_, err = repo.scoped(context.Background(), bson.M{})
+	require.True(t, errors.Is(err, errors.ErrUnauthorized))
+}
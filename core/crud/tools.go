@@ -109,7 +109,7 @@ func WrapHandler(handler types.HandlerFunc, response module.ICrudResponse) gin.H
 			ctx.JSON(appErr.HTTPStatus(), response.Error(appErr))
 			return
 		}
-		// 日志记录请求返回结果
-		ctx.JSON(200, result)
+		// 日志记录请求返回结果，处理函数可通过 ctx.Status 指定成功状态码（如 202）
+		ctx.JSON(ctx.Writer.Status(), result)
 	}
 }
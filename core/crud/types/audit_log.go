@@ -0,0 +1,36 @@
+package types
+
+import "time"
+
+// 审计操作类型
+const (
+	AuditActionCreate      = "create"
+	AuditActionUpdate      = "update"
+	AuditActionUpsert      = "upsert"
+	AuditActionDelete      = "delete"
+	AuditActionRestore     = "restore"
+	AuditActionForceDelete = "force_delete"
+)
+
+// AuditChange 字段变更前后的值，新建时 Before 为空，删除时 After 为空
+type AuditChange struct {
+	Before interface{} `json:"before" bson:"before"`
+	After  interface{} `json:"after" bson:"after"`
+}
+
+// AuditLog 实体变更审计记录
+type AuditLog struct {
+	ID        string                 `gorm:"primarykey;size:36" bson:"_id" json:"id"`
+	Entity    string                 `gorm:"size:128;index:idx_audit_logs_entity,priority:1" bson:"entity" json:"entity"`      // 实体名称
+	EntityID  string                 `gorm:"size:64;index:idx_audit_logs_entity,priority:2" bson:"entity_id" json:"entity_id"` // 实体ID
+	Action    string                 `gorm:"size:32" bson:"action" json:"action"`                                              // create, update, upsert, delete, restore, force_delete
+	Actor     string                 `gorm:"size:64;index" bson:"actor" json:"actor"`                                          // 操作人，来自上下文中的 user_id
+	RequestID string                 `gorm:"size:64" bson:"request_id" json:"request_id"`                                      // 请求ID
+	Changes   map[string]AuditChange `gorm:"type:text;serializer:json" bson:"changes" json:"changes"`                          // 变更字段，键为 json 字段名
+	CreatedAt time.Time              `gorm:"index:idx_audit_logs_entity,priority:3" bson:"created_at" json:"created_at"`       // 操作时间
+}
+
+// TableName 表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
@@ -0,0 +1,52 @@
+package types
+
+import "time"
+
+// BatchJobStatus 批量任务状态
+type BatchJobStatus string
+
+const (
+	BatchJobPending   BatchJobStatus = "pending"   // 等待执行
+	BatchJobRunning   BatchJobStatus = "running"   // 执行中
+	BatchJobSucceeded BatchJobStatus = "succeeded" // 全部成功
+	BatchJobPartial   BatchJobStatus = "partial"   // 部分失败
+	BatchJobFailed    BatchJobStatus = "failed"    // 全部失败
+)
+
+// 批量任务操作类型
+const (
+	BatchOperationCreate = "create"
+	BatchOperationUpdate = "update"
+	BatchOperationUpsert = "upsert"
+	BatchOperationDelete = "delete"
+)
+
+// BatchItemError 批量任务中单条记录的失败信息
+type BatchItemError struct {
+	Index int    `json:"index"` // 记录在请求中的下标
+	Error string `json:"error"` // 失败原因
+}
+
+// BatchJob 异步批量任务
+type BatchJob struct {
+	ID         string           `gorm:"primarykey;size:36" json:"id"`
+	Entity     string           `gorm:"size:128;index" json:"entity"`            // 实体名称
+	TenantID   string           `gorm:"size:64;index" json:"-"`                  // 创建任务的租户，只有同一租户可查询
+	Actor      string           `gorm:"size:64" json:"-"`                        // 创建任务的操作人，只有本人可查询
+	Operation  string           `gorm:"size:32" json:"operation"`                // create, update, upsert, delete
+	Status     BatchJobStatus   `gorm:"size:16" json:"status"`                   // 任务状态
+	Total      int              `json:"total"`                                   // 记录总数
+	Processed  int              `json:"processed"`                               // 已处理数
+	Succeeded  int              `json:"succeeded"`                               // 成功数
+	Failed     int              `json:"failed"`                                  // 失败数
+	Errors     []BatchItemError `gorm:"type:text;serializer:json" json:"errors"` // 失败明细
+	Result     []interface{}    `gorm:"type:text;serializer:json" json:"result"` // 执行结果，为成功记录的ID
+	CreatedAt  time.Time        `json:"created_at"`                              // 创建时间
+	UpdatedAt  time.Time        `json:"updated_at"`                              // 更新时间
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`                   // 完成时间
+}
+
+// TableName 表名
+func (BatchJob) TableName() string {
+	return "batch_jobs"
+}
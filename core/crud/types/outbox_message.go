@@ -0,0 +1,20 @@
+package types
+
+import "time"
+
+// OutboxMessage 发件箱中待投递的事件，与实体变更在同一事务中写入
+type OutboxMessage struct {
+	ID          string     `gorm:"primarykey;size:36" json:"id"`
+	EventName   string     `gorm:"size:255;index" json:"event_name"`                                 // 事件名称
+	Payload     string     `gorm:"type:text" json:"payload"`                                         // 事件内容，json 格式
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`                               // 已投递次数
+	LastError   string     `gorm:"type:text" json:"last_error"`                                      // 最近一次投递失败的原因
+	CreatedAt   time.Time  `gorm:"index:idx_outbox_messages_pending,priority:2" json:"created_at"`   // 写入时间
+	DeliveredAt *time.Time `gorm:"index:idx_outbox_messages_pending,priority:1" json:"delivered_at"` // 投递成功时间，未投递时为空
+	DeadAt      *time.Time `gorm:"index" json:"dead_at"`                                             // 超过最大投递次数进入死信的时间，死信不再投递
+}
+
+// TableName 表名
+func (OutboxMessage) TableName() string {
+	return "outbox_messages"
+}
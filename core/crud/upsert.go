@@ -0,0 +1,44 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/kruily/gofastcrud/errors"
+)
+
+// storageColumns 将字段名（json/列名/bson/Go 字段名）转换为存储字段名，未知字段返回 ErrInvalidParam
+func storageColumns(entityType reflect.Type, dbType string, names []string) ([]string, error) {
+	columns := make([]string, 0, len(names))
+	for _, name := range names {
+		f, ok := lookupEntityField(entityType, name)
+		if !ok {
+			return nil, errors.New(errors.ErrInvalidParam, fmt.Sprintf("unknown field %s", name))
+		}
+		columns = append(columns, f.StorageName(dbType))
+	}
+	return columns, nil
+}
+
+// containsColumn 判断字段是否在列表中
+func containsColumn(columns []string, column string) bool {
+	for _, c := range columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// storageFields 将更新字段的键转换为存储字段名，未知字段返回 ErrInvalidParam
+func storageFields(entityType reflect.Type, dbType string, fields map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		f, ok := lookupEntityField(entityType, name)
+		if !ok {
+			return nil, errors.New(errors.ErrInvalidParam, fmt.Sprintf("unknown field %s", name))
+		}
+		result[f.StorageName(dbType)] = value
+	}
+	return result, nil
+}
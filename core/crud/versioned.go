@@ -0,0 +1,96 @@
+package crud
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kruily/gofastcrud/errors"
+)
+
+// IVersioned 支持乐观锁的实体
+type IVersioned interface {
+	// GetVersion 获取版本号
+	GetVersion() int64
+	// SetVersion 设置版本号
+	SetVersion(version int64)
+}
+
+// Versioned 乐观锁版本字段，嵌入实体即可启用版本检查
+// 更新时仓储会校验版本号并自增，版本不一致时返回 ErrVersionConflict
+type Versioned struct {
+	Version int64 `gorm:"column:version;not null;default:1" bson:"version" json:"version" example:"1" description:"版本号"`
+}
+
+// GetVersion 获取版本号
+func (v *Versioned) GetVersion() int64 {
+	return v.Version
+}
+
+// SetVersion 设置版本号
+func (v *Versioned) SetVersion(version int64) {
+	v.Version = version
+}
+
+// versionField 版本字段名
+const versionField = "version"
+
+// versionColumn 版本字段的存储名
+func versionColumn(entityType reflect.Type, dbType string) string {
+	if f, ok := lookupEntityField(entityType, versionField); ok {
+		return f.StorageName(dbType)
+	}
+	return versionField
+}
+
+// initVersion 新建实体时初始化版本号
+func initVersion(entity any) {
+	if versioned, ok := entity.(IVersioned); ok && versioned.GetVersion() == 0 {
+		versioned.SetVersion(1)
+	}
+}
+
+// formatETag 根据版本号生成 ETag
+func formatETag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// parseETag 解析 If-Match 中的版本号，支持弱校验前缀 W/
+func parseETag(etag string) (int64, bool) {
+	etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+	version, err := strconv.ParseInt(strings.Trim(etag, `"`), 10, 64)
+	return version, err == nil
+}
+
+// checkVersion 校验请求中的版本号，并从更新字段中移除 version
+// If-Match 与当前版本不一致返回 ErrPreconditionFailed，请求体 version 不一致返回 ErrVersionConflict
+func checkVersion(ctx *gin.Context, entity any, updateFields map[string]interface{}) error {
+	versioned, ok := entity.(IVersioned)
+	if !ok {
+		return nil
+	}
+	current := versioned.GetVersion()
+	if ifMatch := ctx.GetHeader("If-Match"); ifMatch != "" && strings.TrimSpace(ifMatch) != "*" {
+		version, ok := parseETag(ifMatch)
+		if !ok || version != current {
+			return errors.New(errors.ErrPreconditionFailed, "If-Match does not match the current version")
+		}
+	}
+	if value, exists := updateFields[versionField]; exists {
+		delete(updateFields, versionField)
+		version, ok := toFloat64(value)
+		if value == nil || !ok || int64(version) != current {
+			return errors.New(errors.ErrVersionConflict, "the record has been modified by others")
+		}
+	}
+	return nil
+}
+
+// setETag 为支持乐观锁的实体设置 ETag 响应头
+func setETag(ctx *gin.Context, entity any) {
+	if versioned, ok := entity.(IVersioned); ok {
+		ctx.Header("ETag", formatETag(versioned.GetVersion()))
+	}
+}
@@ -17,72 +17,160 @@ import (
 	"github.com/qiniu/qmgo"
 )
 
+// DefaultDatasource 默认数据源名称，未配置 name 的连接属于默认数据源
+const DefaultDatasource = "default"
+
 // Database 数据库管理器
+// 每个数据源可包含一个 gorm 连接与一个 MongoDB 连接，根实例的 DB、MDB 与 MClient 返回默认数据源的连接，
+// 未配置默认数据源时分别使用第一个 gorm 连接与第一个 MongoDB 连接
 type Database struct {
+	name    string
 	db      *gorm.DB
 	mdb     *qmgo.Database
 	mClient *qmgo.Client
 	config  []config.DatabaseConfig
+	root    *Database            // 根实例，用于从任意数据源查找其他数据源
+	sources map[string]*Database // 命名数据源，仅根实例持有
+	names   []string             // 数据源按配置顺序排列的名称
 }
 
-// New 创建数据库管理器实例
+// New 创建数据库管理器实例，按 name 连接多个数据源，同一数据源重复配置同类连接时 panic
 func New(cfg []config.DatabaseConfig) *Database {
-	obj := &Database{}
-	var err error
+	obj := &Database{name: DefaultDatasource, sources: make(map[string]*Database)}
+	obj.root = obj
 	obj.config = cfg
 
 	for _, c := range cfg {
-		switch c.Driver {
-		case "mysql":
-			if c.Charset == "" {
-				c.Charset = "utf8mb4"
-			}
-			dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local",
-				c.Username, c.Password, c.Host, c.Port, c.Database, c.Charset)
-			obj.db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Info)})
-		case "postgres":
-			dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable",
-				c.Host, c.Username, c.Password, c.Database, c.Port)
-			obj.db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-		case "sqlite":
-			obj.db, err = gorm.Open(sqlite.Open(c.Database), &gorm.Config{})
-		case "mongo":
-			dsn := fmt.Sprintf("mongodb://%s:%d", c.Host, c.Port)
-			// 连接 MongoDB
-
-			client, err1 := qmgo.NewClient(context.Background(), &qmgo.Config{
-				Uri: dsn,
-				Auth: &qmgo.Credential{
-					Username: c.Username,
-					Password: c.Password,
-				},
-			})
-			if err1 != nil {
-				panic(fmt.Errorf("连接 MongoDB 失败: %v", err1))
-			}
-			// 选择数据库
-			obj.mClient = client
-			obj.mdb = client.Database(c.Database)
-		default:
-			panic(fmt.Errorf("不支持的数据库类型: %s", c.Driver))
+		name := c.Name
+		if name == "" {
+			name = DefaultDatasource
+		}
+		source, ok := obj.sources[name]
+		if !ok {
+			source = &Database{name: name, root: obj}
+			obj.sources[name] = source
+			obj.names = append(obj.names, name)
 		}
+		source.config = append(source.config, c)
+		source.connect(c)
+	}
 
-		if err != nil {
-			panic(fmt.Errorf("连接数据库失败: %v", err))
+	// 默认数据源
+	for _, name := range obj.names {
+		source := obj.sources[name]
+		if obj.db == nil || name == DefaultDatasource && source.db != nil {
+			obj.db = source.db
+		}
+		if obj.mdb == nil || name == DefaultDatasource && source.mdb != nil {
+			obj.mdb, obj.mClient = source.mdb, source.mClient
 		}
+	}
+	return obj
+}
 
-		if c.Driver == "mongo" {
-			continue
+// connect 连接数据源的 gorm 或 MongoDB 数据库
+func (d *Database) connect(c config.DatabaseConfig) {
+	if c.Driver == "mongo" && d.mdb != nil || c.Driver != "mongo" && d.db != nil {
+		panic(fmt.Errorf("数据源 %s 重复配置 %s 连接", d.name, c.Driver))
+	}
+	var err error
+	switch c.Driver {
+	case "mysql":
+		d.db, err = gorm.Open(gormDialector(c), &gorm.Config{Logger: logger.Default.LogMode(logger.Info)})
+	case "postgres", "sqlite":
+		d.db, err = gorm.Open(gormDialector(c), &gorm.Config{})
+	case "mongo":
+		if len(c.Replicas) > 0 {
+			panic(fmt.Errorf("数据源 %s 的 MongoDB 连接不支持 replicas 配置，请使用副本集读偏好", d.name))
 		}
-		// gorm 配置连接池
-		if err := obj.ConfigurePool(&c); err != nil {
+		dsn := fmt.Sprintf("mongodb://%s:%d", c.Host, c.Port)
+		// 连接 MongoDB
+
+		client, err1 := qmgo.NewClient(context.Background(), &qmgo.Config{
+			Uri: dsn,
+			Auth: &qmgo.Credential{
+				Username: c.Username,
+				Password: c.Password,
+			},
+		})
+		if err1 != nil {
+			panic(fmt.Errorf("连接 MongoDB 失败: %v", err1))
+		}
+		// 选择数据库
+		d.mClient = client
+		d.mdb = client.Database(c.Database)
+		return
+	default:
+		panic(fmt.Errorf("不支持的数据库类型: %s", c.Driver))
+	}
+
+	if err != nil {
+		panic(fmt.Errorf("连接数据库失败: %v", err))
+	}
+
+	// gorm 配置连接池
+	if err := d.ConfigurePool(&c); err != nil {
+		panic(err)
+	}
+	if len(c.Replicas) > 0 {
+		if err := d.useReplicas(c); err != nil {
 			panic(err)
 		}
-		log.Printf("Database connected successfully with pool configuration: (MaxIdleConns: %d, MaxOpenConns: %d, ConnMaxLifetime: %ds)",
-			c.MaxIdleConns, c.MaxOpenConns, c.ConnMaxLifetime)
 	}
+	log.Printf("Database %s connected successfully with pool configuration: (MaxIdleConns: %d, MaxOpenConns: %d, ConnMaxLifetime: %ds)",
+		d.name, c.MaxIdleConns, c.MaxOpenConns, c.ConnMaxLifetime)
+}
 
-	return obj
+// gormDialector 根据配置创建 gorm 驱动
+func gormDialector(c config.DatabaseConfig) gorm.Dialector {
+	switch c.Driver {
+	case "mysql":
+		if c.Charset == "" {
+			c.Charset = "utf8mb4"
+		}
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=True&loc=Local",
+			c.Username, c.Password, c.Host, c.Port, c.Database, c.Charset)
+		return mysql.Open(dsn)
+	case "postgres":
+		dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable",
+			c.Host, c.Username, c.Password, c.Database, c.Port)
+		return postgres.Open(dsn)
+	case "sqlite":
+		return sqlite.Open(c.Database)
+	}
+	panic(fmt.Errorf("不支持的数据库类型: %s", c.Driver))
+}
+
+// Datasource 获取命名数据源，name 为空或 default 时返回默认数据源
+func (d *Database) Datasource(name string) (*Database, error) {
+	root := d
+	if d.root != nil {
+		root = d.root
+	}
+	if name == "" || name == DefaultDatasource {
+		return root, nil
+	}
+	if source, ok := root.sources[name]; ok {
+		return source, nil
+	}
+	return nil, fmt.Errorf("数据源 %s 未配置", name)
+}
+
+// Datasources 按配置顺序返回全部数据源名称
+func (d *Database) Datasources() []string {
+	root := d
+	if d.root != nil {
+		root = d.root
+	}
+	return append([]string(nil), root.names...)
+}
+
+// Name 数据源名称
+func (d *Database) Name() string {
+	if d.name == "" {
+		return DefaultDatasource
+	}
+	return d.name
 }
 
 // ConfigurePool 配置连接池
@@ -138,17 +226,37 @@ func (d *Database) MDB() *qmgo.Database {
 	return d.mdb
 }
 
-// Close 关闭数据库连接
+// MClient 获取 qmgo 客户端，用于开启会话与事务
+func (d *Database) MClient() *qmgo.Client {
+	return d.mClient
+}
+
+// Close 关闭数据库连接，根实例关闭全部数据源
 func (d *Database) Close() (err error) {
+	if len(d.sources) == 0 {
+		return d.close()
+	}
+	for _, name := range d.names {
+		if e := d.sources[name].close(); e != nil {
+			err = e
+		}
+	}
+	return
+}
+
+// close 关闭当前数据源的连接
+func (d *Database) close() (err error) {
 	if d.db != nil {
-		sqlDB, err := d.db.DB()
-		if err != nil {
-			return fmt.Errorf("获取数据库实例失败: %v", err)
+		sqlDB, dbErr := d.db.DB()
+		if dbErr != nil {
+			return fmt.Errorf("获取数据库实例失败: %v", dbErr)
 		}
 		err = sqlDB.Close()
 	}
 	if d.mdb != nil {
-		err = d.mClient.Close(context.Background())
+		if mErr := d.mClient.Close(context.Background()); mErr != nil {
+			err = mErr
+		}
 	}
 	return
 }
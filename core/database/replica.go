@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kruily/gofastcrud/config"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// PrimaryKey 上下文中强制读主库的键，gin 请求中可通过 ctx.Set(database.PrimaryKey, true) 设置
+const PrimaryKey = "db_primary"
+
+// primaryKey WithPrimary 使用的上下文键
+type primaryKey struct{}
+
+// WithPrimary 返回强制读主库的上下文，用于写入后立即读取（read-your-writes）
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// UsePrimary 上下文是否要求读主库
+func UsePrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	if primary, _ := ctx.Value(primaryKey{}).(bool); primary {
+		return true
+	}
+	primary, _ := ctx.Value(PrimaryKey).(bool)
+	return primary
+}
+
+// useReplicas 注册只读副本：查询走副本，写操作、事务与加锁查询走主库，上下文要求读主库时查询同样走主库
+func (d *Database) useReplicas(primary config.DatabaseConfig) error {
+	replicas := make([]gorm.Dialector, 0, len(primary.Replicas))
+	for _, replica := range primary.Replicas {
+		replicas = append(replicas, gormDialector(replicaConfig(primary, replica)))
+	}
+	resolver := dbresolver.Register(dbresolver.Config{Replicas: replicas, Policy: dbresolver.RandomPolicy{}})
+	if primary.MaxIdleConns > 0 {
+		resolver.SetMaxIdleConns(primary.MaxIdleConns)
+	}
+	if primary.MaxOpenConns > 0 {
+		resolver.SetMaxOpenConns(primary.MaxOpenConns)
+	}
+	if primary.ConnMaxLifetime > 0 {
+		resolver.SetConnMaxLifetime(time.Duration(primary.ConnMaxLifetime) * time.Second)
+	}
+	if primary.ConnMaxIdleTime > 0 {
+		resolver.SetConnMaxIdleTime(time.Duration(primary.ConnMaxIdleTime) * time.Second)
+	}
+	if err := d.db.Use(resolver); err != nil {
+		return fmt.Errorf("注册数据源 %s 的只读副本失败: %v", d.name, err)
+	}
+
+	callback := d.db.Callback()
+	if err := callback.Query().Before("gorm:query").Register("gofastcrud:primary", forcePrimary); err != nil {
+		return err
+	}
+	if err := callback.Row().Before("gorm:row").Register("gofastcrud:primary", forcePrimary); err != nil {
+		return err
+	}
+	return callback.Raw().Before("gorm:raw").Register("gofastcrud:primary", forcePrimary)
+}
+
+// forcePrimary 上下文要求读主库时将查询切换到主库
+func forcePrimary(db *gorm.DB) {
+	if UsePrimary(db.Statement.Context) {
+		dbresolver.Write.ModifyStatement(db.Statement)
+	}
+}
+
+// replicaConfig 副本未配置的连接字段沿用主库配置
+func replicaConfig(primary, replica config.DatabaseConfig) config.DatabaseConfig {
+	replica.Driver = primary.Driver
+	if replica.Host == "" {
+		replica.Host = primary.Host
+	}
+	if replica.Port == 0 {
+		replica.Port = primary.Port
+	}
+	if replica.Username == "" {
+		replica.Username = primary.Username
+	}
+	if replica.Password == "" {
+		replica.Password = primary.Password
+	}
+	if replica.Database == "" {
+		replica.Database = primary.Database
+	}
+	if replica.Charset == "" {
+		replica.Charset = primary.Charset
+	}
+	return replica
+}
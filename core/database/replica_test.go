@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kruily/gofastcrud/config"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestReplicas(t *testing.T) {
+	dir := t.TempDir()
+	replicaPath := filepath.Join(dir, "replica.db")
+	replica, err := gorm.Open(sqlite.Open(replicaPath), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, replica.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)").Error)
+
+	db := New([]config.DatabaseConfig{{
+		Driver:   "sqlite",
+		Database: filepath.Join(dir, "primary.db"),
+		Replicas: []config.DatabaseConfig{{Database: replicaPath}},
+	}})
+	defer db.Close()
+	require.NoError(t, db.DB().Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT)").Error)
+
+	// 写入主库，查询走副本
+	ctx := context.Background()
+	require.NoError(t, db.DB().WithContext(ctx).Table("items").Create(map[string]interface{}{"name": "primary"}).Error)
+	var count int64
+	require.NoError(t, db.DB().WithContext(ctx).Table("items").Count(&count).Error)
+	require.Equal(t, int64(0), count)
+
+	// 上下文要求读主库
+	require.True(t, UsePrimary(WithPrimary(ctx)))
+	require.NoError(t, db.DB().WithContext(WithPrimary(ctx)).Table("items").Count(&count).Error)
+	require.Equal(t, int64(1), count)
+	var name string
+	require.NoError(t, db.DB().WithContext(WithPrimary(ctx)).Raw("SELECT name FROM items").Scan(&name).Error)
+	require.Equal(t, "primary", name)
+
+	// 事务使用主库
+	require.NoError(t, db.DB().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Table("items").Count(&count).Error
+	}))
+	require.Equal(t, int64(1), count)
+}
@@ -104,6 +104,15 @@ func (c *Container) BindWithOptions(name string, obj any, objType reflect.Type,
 
 	return nil
 }
+
+// Unbind 移除名称对应的单例与绑定
+func (c *Container) Unbind(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.instances, name)
+	delete(c.bindings, name)
+}
+
 func (c *Container) ResolveSingleton(name string) (interface{}, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
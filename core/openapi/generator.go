@@ -71,6 +71,8 @@ func (g *Generator) RegisterEntityWithVersion(entityType reflect.Type, basePath
 				pathItem.Post = operation
 			case "PUT":
 				pathItem.Put = operation
+			case "PATCH":
+				pathItem.Patch = operation
 			case "DELETE":
 				pathItem.Delete = operation
 			}
@@ -471,7 +473,10 @@ func (g *Generator) generateOperation(route *types.APIRoute, entityName string)
 	}
 
 	// 添加请求体 Body 参数
-	if route.Method == "POST" || route.Method == "PUT" {
+	if route.Method == "PATCH" {
+		operation.Consumes = []string{"application/merge-patch+json", "application/json-patch+json", "application/json"}
+	}
+	if route.Method == "POST" || route.Method == "PUT" || route.Method == "PATCH" {
 		var schema *spec.Schema
 		if route.Request != nil {
 			t := reflect.TypeOf(route.Request)
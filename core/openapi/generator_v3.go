@@ -71,6 +71,8 @@ func (g *GeneratorV3) RegisterEntityWithVersion(entityType reflect.Type, basePat
 				pathItem.Post = operation
 			case "PUT":
 				pathItem.Put = operation
+			case "PATCH":
+				pathItem.Patch = operation
 			case "DELETE":
 				pathItem.Delete = operation
 			}
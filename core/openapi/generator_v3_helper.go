@@ -215,7 +215,7 @@ func (g *GeneratorV3) generateOperation(route *types.APIRoute, entityName string
 	}
 
 	// 添加请求体
-	if route.Method == "POST" || route.Method == "PUT" {
+	if route.Method == "POST" || route.Method == "PUT" || route.Method == "PATCH" {
 		var schema *openapi3.SchemaRef
 		if route.Request != nil {
 			t := reflect.TypeOf(route.Request)
@@ -238,6 +238,13 @@ func (g *GeneratorV3) generateOperation(route *types.APIRoute, entityName string
 				},
 			},
 		}
+		if route.Method == "PATCH" {
+			content := operation.RequestBody.Value.Content
+			content["application/merge-patch+json"] = &openapi3.MediaType{Schema: schema}
+			content["application/json-patch+json"] = &openapi3.MediaType{
+				Schema: openapi3.NewArraySchema().WithItems(openapi3.NewObjectSchema()).NewRef(),
+			}
+		}
 	}
 
 	// 添加响应体
@@ -15,6 +15,8 @@ const (
 	ErrValidation   ErrorCode = 1004
 	ErrTimeout      ErrorCode = 1005
 	ErrIDType       ErrorCode = 1006
+	// 请求前置条件（如 If-Match）不满足
+	ErrPreconditionFailed ErrorCode = 1007
 
 	// 业务级错误码 (2000-2999)
 	ErrUserNotFound    ErrorCode = 2000
@@ -26,6 +28,10 @@ const (
 	ErrDatabase       ErrorCode = 3000
 	ErrDuplicateKey   ErrorCode = 3001
 	ErrNoRowsAffected ErrorCode = 3002
+	// 乐观锁版本冲突
+	ErrVersionConflict ErrorCode = 3003
+	// 数据库部署不支持事务
+	ErrTransactionNotSupported ErrorCode = 3004
 
 	// 第三方服务错误码 (4000-4999)
 	ErrThirdParty ErrorCode = 4000
@@ -34,20 +40,24 @@ const (
 
 // 错误码与HTTP状态码的映射
 var httpStatusMap = map[ErrorCode]int{
-	ErrInternal:        http.StatusInternalServerError,
-	ErrUnauthorized:    http.StatusUnauthorized,
-	ErrForbidden:       http.StatusForbidden,
-	ErrNotFound:        http.StatusNotFound,
-	ErrValidation:      http.StatusBadRequest,
-	ErrTimeout:         http.StatusGatewayTimeout,
-	ErrUserNotFound:    http.StatusNotFound,
-	ErrUserExists:      http.StatusConflict,
-	ErrInvalidPassword: http.StatusBadRequest,
-	ErrDatabase:        http.StatusInternalServerError,
-	ErrDuplicateKey:    http.StatusConflict,
-	ErrNoRowsAffected:  http.StatusNotFound,
-	ErrThirdParty:      http.StatusBadGateway,
-	ErrRateLimit:       http.StatusTooManyRequests,
+	ErrInternal:                http.StatusInternalServerError,
+	ErrUnauthorized:            http.StatusUnauthorized,
+	ErrForbidden:               http.StatusForbidden,
+	ErrNotFound:                http.StatusNotFound,
+	ErrValidation:              http.StatusBadRequest,
+	ErrTimeout:                 http.StatusGatewayTimeout,
+	ErrUserNotFound:            http.StatusNotFound,
+	ErrUserExists:              http.StatusConflict,
+	ErrInvalidPassword:         http.StatusBadRequest,
+	ErrInvalidParam:            http.StatusBadRequest,
+	ErrDatabase:                http.StatusInternalServerError,
+	ErrDuplicateKey:            http.StatusConflict,
+	ErrNoRowsAffected:          http.StatusNotFound,
+	ErrVersionConflict:         http.StatusConflict,
+	ErrTransactionNotSupported: http.StatusInternalServerError,
+	ErrPreconditionFailed:      http.StatusPreconditionFailed,
+	ErrThirdParty:              http.StatusBadGateway,
+	ErrRateLimit:               http.StatusTooManyRequests,
 }
 
 func RegisterErrorCode(code ErrorCode, message string, httpStatus int) error {
@@ -7,9 +7,9 @@ import (
 // Book 书籍模型
 type Book struct {
 	*crud.BaseUUIDEntity
-	Title      string    `json:"title" binding:"required"`
-	CategoryID string    `json:"category_id" gorm:"type:text;index:idx_category_id(255)"`
-	Category   *Category `json:"category" gorm:"foreignKey:CategoryID;references:ID"`
+	Title      string    `json:"title" binding:"required" filter:"eq,like" search:"true"`
+	CategoryID string    `json:"category_id" gorm:"type:text;index:idx_category_id(255)" filter:"eq,in"`
+	Category   *Category `json:"category" gorm:"foreignKey:CategoryID;references:ID" relation:"name" expand:"true"`
 }
 
 func (*Book) TableName() string {
@@ -7,8 +7,8 @@ import (
 // Category 分类模型
 type Category struct {
 	crud.BaseUUIDEntity
-	Name  string `json:"name" binding:"required" gorm:"unique"`
-	Books []Book `json:"books" gorm:"foreignKey:CategoryID;references:ID"`
+	Name  string `json:"name" binding:"required" gorm:"unique" filter:"eq,like"`
+	Books []Book `json:"books" gorm:"foreignKey:CategoryID;references:ID" expand:"true"`
 }
 
 func (Category) TableName() string {
@@ -8,8 +8,8 @@ import (
 // @Description 用户信息
 type User struct {
 	*crud.BaseMongoEntity `bson:",inline"`
-	Username              string `json:"username" binding:"required" description:"用户名"`
-	Email                 string `json:"email" binding:"required" gorm:"unique;" description:"邮箱"`
+	Username              string `json:"username" binding:"required" description:"用户名" filter:"eq,like"`
+	Email                 string `json:"email" binding:"required" gorm:"unique;" description:"邮箱" filter:"eq"`
 }
 
 func (*User) TableName() string {
@@ -132,6 +132,64 @@ func (rm *CasbinMaker) RemoveRoleForUser(user, role string) error {
 	return rm.enforcer.SavePolicy()
 }
 
+// AddDataPolicy 添加数据权限策略，rule 为行过滤规则，如 owner_id = $user_id
+// 模型需定义 p2 = sub, obj, act, rule；obj 与 act 可使用 * 匹配全部
+func (rm *CasbinMaker) AddDataPolicy(sub, obj, act, rule string) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	_, err := rm.enforcer.AddNamedPolicy("p2", sub, obj, act, rule)
+	if err != nil {
+		return err
+	}
+	return rm.enforcer.SavePolicy()
+}
+
+// RemoveDataPolicy 删除数据权限策略
+func (rm *CasbinMaker) RemoveDataPolicy(sub, obj, act, rule string) error {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	_, err := rm.enforcer.RemoveNamedPolicy("p2", sub, obj, act, rule)
+	if err != nil {
+		return err
+	}
+	return rm.enforcer.SavePolicy()
+}
+
+// DataRules 获取用户及其继承角色在 obj、act 上的数据权限规则
+func (rm *CasbinMaker) DataRules(user, obj, act string) ([]string, error) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	if rm.enforcer == nil {
+		return nil, ErrNotInitialized
+	}
+	roles, err := rm.enforcer.GetImplicitRolesForUser(user)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]string, 0)
+	for _, sub := range append([]string{user}, roles...) {
+		policies, err := rm.enforcer.GetFilteredNamedPolicy("p2", 0, sub)
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range policies {
+			if len(policy) < 4 || !matchPolicyField(policy[1], obj) || !matchPolicyField(policy[2], act) {
+				continue
+			}
+			rules = append(rules, policy[3])
+		}
+	}
+	return rules, nil
+}
+
+// matchPolicyField 策略字段匹配，* 匹配全部
+func matchPolicyField(policy, value string) bool {
+	return policy == "*" || policy == value
+}
+
 // Enforce 检查权限
 func (rm *CasbinMaker) Enforce(sub, obj, act string) (bool, error) {
 	rm.mu.RLock()
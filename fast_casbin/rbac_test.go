@@ -1,6 +1,8 @@
 package fast_casbin
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -100,3 +102,26 @@ func TestCasbinMaker(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, hasRole)
 }
+
+func TestDataRules(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "casbin.db")), &gorm.Config{})
+	require.NoError(t, err)
+
+	model := strings.Replace(testModel, "p = sub, obj, act\n", "p = sub, obj, act\np2 = sub, obj, act, rule\n", 1)
+	maker, err := NewCasbinMaker(Config{ModelText: model, DB: db})
+	require.NoError(t, err)
+	require.NoError(t, maker.AddDataPolicy("editor", "books", "update", "owner_id = $user_id"))
+	require.NoError(t, maker.AddDataPolicy("editor", "books", "*", "status = published"))
+	require.NoError(t, maker.AddDataPolicy("alice", "*", "*", "*"))
+	require.NoError(t, maker.AddRoleForUser("bob", "editor"))
+
+	rules, err := maker.DataRules("bob", "books", "update")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"owner_id = $user_id", "status = published"}, rules)
+	rules, err = maker.DataRules("bob", "authors", "update")
+	require.NoError(t, err)
+	require.Empty(t, rules)
+	rules, err = maker.DataRules("alice", "books", "delete")
+	require.NoError(t, err)
+	require.Equal(t, []string{"*"}, rules)
+}
@@ -22,6 +22,7 @@ type Claims struct {
 	jwt.RegisteredClaims
 	UserID   any    `json:"user_id"`
 	Username string `json:"username"`
+	TenantID string `json:"tenant_id,omitempty"`
 }
 
 // NewJWTMaker 创建一个新的JWT maker
@@ -41,6 +42,11 @@ func NewJWTMaker(secretKey string, expire string) (*JWTMaker, error) {
 
 // CreateToken 创建一个新的token
 func (maker *JWTMaker) CreateToken(userID any, username string) (string, error) {
+	return maker.CreateTenantToken(userID, username, "")
+}
+
+// CreateTenantToken 创建携带租户ID的token
+func (maker *JWTMaker) CreateTenantToken(userID any, username string, tenantID string) (string, error) {
 	claims := Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(maker.duration)),
@@ -48,6 +54,7 @@ func (maker *JWTMaker) CreateToken(userID any, username string) (string, error)
 		},
 		UserID:   userID,
 		Username: username,
+		TenantID: tenantID,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
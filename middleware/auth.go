@@ -6,6 +6,7 @@ import (
 
 	"github.com/kruily/gofastcrud/fast_casbin"
 	"github.com/kruily/gofastcrud/fast_jwt"
+	"github.com/kruily/gofastcrud/tenant"
 
 	"github.com/gin-gonic/gin"
 )
@@ -46,6 +47,9 @@ func (m *AuthMiddleware) JWT() gin.HandlerFunc {
 		// 将用户信息存储到上下文中
 		c.Set("user_id", cla.UserID)
 		c.Set("username", cla.Username)
+		if cla.TenantID != "" {
+			c.Set(tenant.ClaimsKey, cla.TenantID)
+		}
 
 		c.Next()
 	}
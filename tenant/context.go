@@ -0,0 +1,57 @@
+package tenant
+
+import "context"
+
+const (
+	// ContextKey gin 上下文中存储租户ID的键
+	ContextKey = "tenant_id"
+	// ClaimsKey gin 上下文中存储 JWT 声明中租户ID的键，由认证中间件写入
+	ClaimsKey = "claims_tenant_id"
+	// UnscopedKey gin 上下文中标记跳过租户隔离的键
+	UnscopedKey = "tenant_unscoped"
+)
+
+// contextKey context.Context 中的键类型，避免与其他包冲突
+type contextKey struct{ name string }
+
+var (
+	tenantKey   = contextKey{"tenant"}
+	unscopedKey = contextKey{"unscoped"}
+)
+
+// WithTenant 返回携带租户ID的上下文
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenantID)
+}
+
+// WithoutScope 返回跳过租户隔离的上下文，仅用于超级管理员或后台任务
+// 上下文中同时存在租户ID时仍按该租户隔离
+func WithoutScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unscopedKey, true)
+}
+
+// FromContext 获取上下文中的租户ID，兼容 gin.Context 中以 ContextKey 存储的值
+func FromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	for _, key := range []any{tenantKey, ContextKey} {
+		if id, ok := ctx.Value(key).(string); ok && id != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// IsUnscoped 上下文是否跳过租户隔离
+func IsUnscoped(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	for _, key := range []any{unscopedKey, UnscopedKey} {
+		if unscoped, ok := ctx.Value(key).(bool); ok && unscoped {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package tenant
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kruily/gofastcrud/errors"
+)
+
+// DefaultHeader 默认的租户请求头
+const DefaultHeader = "X-Tenant-ID"
+
+// Resolver 从请求中解析租户ID，未解析到时返回空字符串
+type Resolver func(c *gin.Context) string
+
+// ClaimsResolver 从认证中间件写入的 JWT 声明中解析租户
+func ClaimsResolver() Resolver {
+	return func(c *gin.Context) string {
+		return c.GetString(ClaimsKey)
+	}
+}
+
+// HeaderResolver 从请求头解析租户
+func HeaderResolver(header string) Resolver {
+	return func(c *gin.Context) string {
+		return strings.TrimSpace(c.GetHeader(header))
+	}
+}
+
+// SubdomainResolver 从子域名解析租户，如 baseDomain 为 example.com 时 acme.example.com 解析为 acme
+func SubdomainResolver(baseDomain string) Resolver {
+	suffix := "." + strings.ToLower(strings.Trim(baseDomain, "."))
+	return func(c *gin.Context) string {
+		host := c.Request.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		sub, ok := strings.CutSuffix(strings.ToLower(host), suffix)
+		if !ok || sub == "" || strings.Contains(sub, ".") {
+			return ""
+		}
+		return sub
+	}
+}
+
+// Option 租户中间件选项
+type Option func(*middlewareOptions)
+
+// middlewareOptions 租户中间件配置
+type middlewareOptions struct {
+	resolvers  []Resolver
+	superAdmin func(c *gin.Context) bool
+	optional   bool
+}
+
+// WithResolvers 设置租户解析器，按顺序使用第一个非空结果
+func WithResolvers(resolvers ...Resolver) Option {
+	return func(o *middlewareOptions) {
+		o.resolvers = resolvers
+	}
+}
+
+// WithSuperAdmin 设置超级管理员判断，超级管理员可访问任意租户，未指定租户时跳过租户隔离
+func WithSuperAdmin(isSuperAdmin func(c *gin.Context) bool) Option {
+	return func(o *middlewareOptions) {
+		o.superAdmin = isSuperAdmin
+	}
+}
+
+// WithOptional 未解析到租户时放行请求，多租户实体的仓储操作仍会被拒绝
+func WithOptional() Option {
+	return func(o *middlewareOptions) {
+		o.optional = true
+	}
+}
+
+// Middleware 解析租户并写入请求上下文，默认依次使用 JWT 声明与 X-Tenant-ID 请求头
+// JWT 声明中存在租户时，非超级管理员不能通过请求头或子域名访问其他租户
+func Middleware(opts ...Option) gin.HandlerFunc {
+	o := &middlewareOptions{resolvers: []Resolver{ClaimsResolver(), HeaderResolver(DefaultHeader)}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(c *gin.Context) {
+		superAdmin := o.superAdmin != nil && o.superAdmin(c)
+		claimed := ""
+		if !superAdmin {
+			claimed = c.GetString(ClaimsKey)
+		}
+		tenantID := claimed
+		for _, resolve := range o.resolvers {
+			resolved := resolve(c)
+			if resolved == "" {
+				continue
+			}
+			if claimed != "" && resolved != claimed {
+				c.Error(errors.New(errors.ErrForbidden, "cross-tenant access is not allowed"))
+				c.Abort()
+				return
+			}
+			if tenantID == "" {
+				tenantID = resolved
+			}
+		}
+
+		ctx := c.Request.Context()
+		switch {
+		case tenantID != "":
+			c.Set(ContextKey, tenantID)
+			ctx = WithTenant(ctx, tenantID)
+		case superAdmin:
+			c.Set(UnscopedKey, true)
+			ctx = WithoutScope(ctx)
+		case !o.optional:
+			c.Error(errors.New(errors.ErrUnauthorized, "tenant is required"))
+			c.Abort()
+			return
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+package tenant
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kruily/gofastcrud/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// runMiddleware 执行中间件，返回处理后的上下文
+func runMiddleware(handler gin.HandlerFunc, host string, header string, claimed string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Host = host
+	if header != "" {
+		c.Request.Header.Set(DefaultHeader, header)
+	}
+	if claimed != "" {
+		c.Set(ClaimsKey, claimed)
+	}
+	handler(c)
+	return c
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware()
+	c := runMiddleware(handler, "api.example.com", "acme", "")
+	tenantID, ok := FromContext(c.Request.Context())
+	require.True(t, ok)
+	require.Equal(t, "acme", tenantID)
+	tenantID, _ = FromContext(c)
+	require.Equal(t, "acme", tenantID)
+
+	// JWT 声明优先，请求头不能切换到其他租户
+	c = runMiddleware(handler, "", "", "globex")
+	tenantID, _ = FromContext(c.Request.Context())
+	require.Equal(t, "globex", tenantID)
+	c = runMiddleware(handler, "", "acme", "globex")
+	require.True(t, c.IsAborted())
+	require.True(t, errors.Is(c.Errors.Last().Err, errors.ErrForbidden))
+
+	c = runMiddleware(handler, "", "", "")
+	require.True(t, c.IsAborted())
+	require.True(t, errors.Is(c.Errors.Last().Err, errors.ErrUnauthorized))
+
+	subdomain := Middleware(WithResolvers(SubdomainResolver("example.com")))
+	c = runMiddleware(subdomain, "acme.example.com:8080", "", "")
+	tenantID, _ = FromContext(c.Request.Context())
+	require.Equal(t, "acme", tenantID)
+
+	// 超级管理员可访问任意租户，未指定租户时跳过隔离
+	admin := Middleware(WithSuperAdmin(func(c *gin.Context) bool { return true }))
+	c = runMiddleware(admin, "", "acme", "")
+	tenantID, _ = FromContext(c.Request.Context())
+	require.Equal(t, "acme", tenantID)
+	c = runMiddleware(admin, "", "", "")
+	require.False(t, c.IsAborted())
+	require.True(t, IsUnscoped(c.Request.Context()))
+}
@@ -17,6 +17,14 @@ type PagenationResponse struct {
 	Size  int         `json:"size"`
 }
 
+// CursorPagenationResponse 游标分页响应结构
+type CursorPagenationResponse struct {
+	List       interface{} `json:"list"`
+	NextCursor string      `json:"next_cursor"`
+	PrevCursor string      `json:"prev_cursor"`
+	Limit      int         `json:"limit"`
+}
+
 // DefaultResponseHandler 默认响应处理器
 type DefaultResponseHandler struct{}
 
@@ -55,3 +63,17 @@ func (h *DefaultResponseHandler) Pagenation(items interface{}, total int64, page
 		},
 	}
 }
+
+// CursorPagenation 处理游标分页列表响应
+func (h *DefaultResponseHandler) CursorPagenation(items interface{}, nextCursor string, prevCursor string, limit int) interface{} {
+	return Response{
+		Code:    0,
+		Message: "success",
+		Data: CursorPagenationResponse{
+			List:       items,
+			NextCursor: nextCursor,
+			PrevCursor: prevCursor,
+			Limit:      limit,
+		},
+	}
+}
@@ -2,6 +2,7 @@ package validator
 
 import (
 	"reflect"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/kruily/gofastcrud/errors"
@@ -29,8 +30,11 @@ func ValidateMap(fields map[string]interface{}, entity any) error {
 
 	// 验证字段
 	for fieldName, value := range fields {
-		// 检查字段是否存在
-		field, exists := entityType.FieldByName(fieldName)
+		// 检查字段是否存在，优先按 json 名称查找
+		field, exists := fieldByJSONName(entityType, fieldName)
+		if !exists {
+			field, exists = entityType.FieldByName(fieldName)
+		}
 		if !exists {
 			return errors.New(errors.ErrInvalidParam, "invalid field: "+fieldName)
 		}
@@ -46,6 +50,32 @@ func ValidateMap(fields map[string]interface{}, entity any) error {
 	return nil
 }
 
+// fieldByJSONName 根据 json 名称查找字段，包括嵌入结构体中的字段
+func fieldByJSONName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if f, ok := fieldByJSONName(embedded, name); ok {
+					return f, true
+				}
+				continue
+			}
+		}
+		if jsonName := strings.Split(field.Tag.Get("json"), ",")[0]; jsonName == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
 // ValidateVar 验证单个变量
 func ValidateVar(field interface{}, tag string) error {
 	var validate = validator.New()